@@ -80,7 +80,7 @@ func completionScript(shell string) (string, error) {
 _slurm_monitor_completion() {
   local cur prev words cword
   _init_completion || return
-  local commands="doctor dry-run completion monitor help"
+  local commands="doctor dry-run completion monitor serve replay help"
   if [[ ${cword} -eq 1 ]]; then
     COMPREPLY=( $(compgen -W "${commands}" -- "${cur}") )
     return
@@ -89,8 +89,11 @@ _slurm_monitor_completion() {
     completion)
       COMPREPLY=( $(compgen -W "bash zsh" -- "${cur}") )
       ;;
-    doctor|dry-run|monitor)
-      COMPREPLY=( $(compgen -W "--refresh --connect-timeout --command-timeout --ssh-config --identity-file --port --no-color --compact --once --duration" -- "${cur}") )
+    replay)
+      COMPREPLY=( $(compgen -f -W "--no-color --compact --sort --duration --history" -- "${cur}") )
+      ;;
+    doctor|dry-run|monitor|serve)
+      COMPREPLY=( $(compgen -W "--refresh --source-refresh --adaptive-refresh --min-refresh --max-refresh --connect-timeout --command-timeout --ssh-config --identity-file --port --no-color --compact --once --format --sort --listen --duration --history --record --record-transport --token-file --rest-version --config" -- "${cur}") )
       ;;
     *)
       COMPREPLY=( $(compgen -W "${commands}" -- "${cur}") )
@@ -107,6 +110,8 @@ _slurm_monitor() {
     'monitor:start live monitoring (default)'
     'doctor:run non-mutating preflight checks'
     'dry-run:print planned execution order'
+    'serve:expose Prometheus metrics over HTTP'
+    'replay:play back a recorded session'
     'completion:print shell completion script'
     'help:show help text'
   )
@@ -118,8 +123,11 @@ _slurm_monitor() {
     completion)
       _values 'shell' bash zsh
       ;;
-    doctor|dry-run|monitor)
-      _values 'flag' --refresh --connect-timeout --command-timeout --ssh-config --identity-file --port --no-color --compact --once --duration
+    replay)
+      _files
+      ;;
+    doctor|dry-run|monitor|serve)
+      _values 'flag' --refresh --source-refresh --adaptive-refresh --min-refresh --max-refresh --connect-timeout --command-timeout --ssh-config --identity-file --port --no-color --compact --once --format --sort --listen --duration --history --record --record-transport --token-file --rest-version --config
       ;;
     *)
       _message 'optional ssh target'
@@ -4,14 +4,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"slurm_monitor/internal/config"
+	"slurm_monitor/internal/export"
+	"slurm_monitor/internal/metrics"
 	"slurm_monitor/internal/monitor"
+	"slurm_monitor/internal/record"
+	"slurm_monitor/internal/replay"
+	"slurm_monitor/internal/sim"
 	"slurm_monitor/internal/slurm"
 	"slurm_monitor/internal/transport"
 	"slurm_monitor/internal/tui"
@@ -29,23 +38,20 @@ func (e *missingSlurmCommandsError) Error() string {
 	return fmt.Sprintf("missing required Slurm commands on %s: %s", e.source, e.missing)
 }
 
-func Run(cfg config.Config) error {
+func Run(cfg config.Config) (err error) {
 	switch cfg.Command {
 	case config.CommandDoctor:
 		return RunDoctor(cfg, os.Stdout)
 	case config.CommandDryRun:
 		return RunDryRun(cfg, os.Stdout)
-	case config.CommandMonitor:
+	case config.CommandReplay:
+		return runReplay(cfg)
+	case config.CommandMonitor, config.CommandServe:
 		// Continue into monitor execution.
 	default:
 		return fmt.Errorf("unsupported command: %s", cfg.Command)
 	}
 
-	tr, err := buildTransport(cfg)
-	if err != nil {
-		return err
-	}
-
 	rootCtx := context.Background()
 	ctx, cancel := context.WithCancel(rootCtx)
 	if cfg.Duration > 0 {
@@ -53,40 +59,223 @@ func Run(cfg config.Config) error {
 	}
 	defer cancel()
 
-	if err := awaitSlurmAvailability(ctx, tr, cfg.CommandTimeout); err != nil {
+	collector, source, closeBackend, err := openBackend(ctx, cfg)
+	if err != nil {
 		return err
 	}
+	defer func() {
+		if closeErr := closeBackend(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	rec, err := openRecording(cfg, source)
+	if err != nil {
+		return err
+	}
+	if rec != nil {
+		defer func() {
+			if closeErr := rec.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}()
+	}
 
-	collector := slurm.NewCollector(tr, cfg.CommandTimeout)
 	if cfg.Once {
-		return runOnce(ctx, collector, tr.Describe())
+		return runOnce(ctx, collector, source, cfg, rec)
+	}
+	if cfg.Command == config.CommandServe {
+		return runServe(ctx, cfg, collector, source, rec)
 	}
 
 	updates := make(chan monitor.Update, 8)
+	waitLoop := startLoop(ctx, cfg, collector, rec, updates)
+	// Registered after the recording's close, so it runs first: the loop must
+	// be stopped before the file it writes to is closed.
+	defer func() {
+		cancel()
+		if recordErr := waitLoop(); recordErr != nil && err == nil {
+			err = recordErr
+		}
+	}()
+
+	model := tui.NewModel(tui.Options{
+		Source:         source,
+		Compact:        cfg.Compact,
+		NoColor:        cfg.NoColor,
+		Refresh:        cfg.Refresh,
+		MaxDuration:    cfg.Duration,
+		NodeSort:       cfg.NodeSort,
+		UserSort:       cfg.UserSort,
+		Updates:        updates,
+		History:        cfg.History,
+		JobDetails:     collector,
+		SampleInterval: sampleInterval(cfg),
+	})
+
+	prog := tea.NewProgram(model, tea.WithAltScreen())
+	if _, err := prog.Run(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// backend is what monitoring needs from a data source: snapshots for the
+// poller and job records for the detail pane.
+type backend interface {
+	monitor.Collector
+	tui.JobDetailSource
+}
+
+// openBackend builds the collector for cfg and waits until its preflight
+// check passes. The returned close function flushes a --record-transport
+// fixture and reports its first write error.
+func openBackend(ctx context.Context, cfg config.Config) (backend, string, func() error, error) {
+	noop := func() error { return nil }
+	if cfg.Mode == config.ModeREST {
+		rest := newRESTCollector(cfg)
+		if err := awaitAvailability(ctx, rest.Describe(), rest.Ping, 1*time.Second, 30*time.Second); err != nil {
+			return nil, "", nil, err
+		}
+		return rest, rest.Describe(), noop, nil
+	}
+
+	tr, err := buildTransport(cfg)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	closeFn := noop
+	if cfg.RecordTransport != "" {
+		recorder, err := transport.NewRecordingTransport(tr, cfg.RecordTransport)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		tr, closeFn = recorder, recorder.Close
+	}
+	if err := awaitSlurmAvailability(ctx, tr, cfg.CommandTimeout); err != nil {
+		_ = closeFn()
+		return nil, "", nil, err
+	}
+	return slurm.NewCollector(tr, cfg.CommandTimeout), tr.Describe(), closeFn, nil
+}
+
+func newRESTCollector(cfg config.Config) *slurm.RESTCollector {
+	return slurm.NewRESTCollector(slurm.RESTOptions{
+		BaseURL:   cfg.Target,
+		Version:   cfg.RESTVersion,
+		TokenFile: cfg.TokenFile,
+		Timeout:   cfg.CommandTimeout,
+	})
+}
+
+// openRecording opens the --record file, or returns nil when recording is off.
+func openRecording(cfg config.Config, source string) (*record.Writer, error) {
+	if cfg.Record == "" {
+		return nil, nil
+	}
+	return record.Create(cfg.Record, source)
+}
+
+// startLoop polls collector in the background with cfg's refresh settings,
+// recording each snapshot to rec when it is set. The returned function blocks
+// until the loop exits, which it does once ctx is done, and then reports the
+// first recording write error. A failed write does not interrupt monitoring.
+func startLoop(ctx context.Context, cfg config.Config, collector monitor.Collector, rec *record.Writer, updates chan<- monitor.Update) func() error {
 	loop := monitor.NewLoop(collector, cfg.Refresh)
-	go loop.Run(ctx, updates)
+	loop.SourceRefresh = cfg.SourceRefresh
+	loop.AdaptiveRefresh, loop.MinRefresh, loop.MaxRefresh = cfg.AdaptiveRefresh, cfg.MinRefresh, cfg.MaxRefresh
+
+	// recordErr is only touched by the loop goroutine until done is closed.
+	var recordErr error
+	if rec != nil {
+		loop.Record = func(snap slurm.Snapshot) {
+			if err := rec.Record(snap); err != nil && recordErr == nil {
+				recordErr = err
+			}
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		loop.Run(ctx, updates)
+	}()
+	return func() error {
+		<-done
+		return recordErr
+	}
+}
+
+// sampleInterval is the shortest interval at which the loop can publish a
+// snapshot: Refresh, or less under --min-refresh or --source-refresh.
+func sampleInterval(cfg config.Config) time.Duration {
+	d := cfg.Refresh
+	if cfg.AdaptiveRefresh && cfg.MinRefresh > 0 {
+		d = min(d, cfg.MinRefresh)
+	}
+	for _, r := range cfg.SourceRefresh {
+		if r > 0 {
+			d = min(d, r)
+		}
+	}
+	return d
+}
 
+// runReplay plays a recording through the same TUI as live monitoring.
+func runReplay(cfg config.Config) error {
+	frames, err := record.Load(cfg.ReplayPath)
+	if len(frames) == 0 {
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("recording %s has no snapshots", cfg.ReplayPath)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "slurm-monitor: replaying the readable part of %s: %v\n", cfg.ReplayPath, err)
+	}
+
+	rootCtx := context.Background()
+	ctx, cancel := context.WithCancel(rootCtx)
+	if cfg.Duration > 0 {
+		ctx, cancel = context.WithTimeout(rootCtx, cfg.Duration)
+	}
+	defer cancel()
+
+	player := replay.NewPlayer(frames)
+	updates := make(chan monitor.Update, 8)
+	go player.Run(ctx, updates)
+
+	source := "replay " + cfg.ReplayPath
+	if frames[0].Source != "" {
+		source += " (" + frames[0].Source + ")"
+	}
 	model := tui.NewModel(tui.Options{
-		Source:      tr.Describe(),
+		Source:      source,
 		Compact:     cfg.Compact,
 		NoColor:     cfg.NoColor,
 		Refresh:     cfg.Refresh,
 		MaxDuration: cfg.Duration,
+		NodeSort:    cfg.NodeSort,
+		UserSort:    cfg.UserSort,
 		Updates:     updates,
+		History:     cfg.History,
+		Playback:    player,
 	})
 
 	prog := tea.NewProgram(model, tea.WithAltScreen())
-	if _, err := prog.Run(); err != nil {
-		return err
-	}
-
-	return nil
+	_, err = prog.Run()
+	return err
 }
 
 func buildTransport(cfg config.Config) (transport.Transport, error) {
 	switch cfg.Mode {
 	case config.ModeLocal:
 		return transport.NewLocalTransport(), nil
+	case config.ModeFixture:
+		return transport.LoadFixture(strings.TrimPrefix(cfg.Target, transport.FixtureScheme))
+	case config.ModeSim:
+		return sim.NewTransport(cfg.Target)
 	case config.ModeRemote:
 		return transport.NewSSHTransport(transport.SSHOptions{
 			Target:         cfg.Target,
@@ -133,6 +322,21 @@ func awaitSlurmAvailabilityWithBackoff(
 	timeout time.Duration,
 	baseDelay time.Duration,
 	maxDelay time.Duration,
+) error {
+	check := func(ctx context.Context) error {
+		return checkSlurmAvailability(ctx, tr, timeout)
+	}
+	return awaitAvailability(ctx, tr.Describe(), check, baseDelay, maxDelay)
+}
+
+// awaitAvailability runs check until it passes, backing off between
+// transient failures and giving up on permanent ones.
+func awaitAvailability(
+	ctx context.Context,
+	source string,
+	check func(context.Context) error,
+	baseDelay time.Duration,
+	maxDelay time.Duration,
 ) error {
 	if baseDelay <= 0 {
 		baseDelay = 1 * time.Second
@@ -143,7 +347,7 @@ func awaitSlurmAvailabilityWithBackoff(
 
 	delay := baseDelay
 	for {
-		err := checkSlurmAvailability(ctx, tr, timeout)
+		err := check(ctx)
 		if err == nil {
 			return nil
 		}
@@ -160,7 +364,7 @@ func awaitSlurmAvailabilityWithBackoff(
 		fmt.Fprintf(
 			os.Stderr,
 			"slurm-monitor: transient preflight failure on %s: %v; retrying in %s (Ctrl+C to stop)\n",
-			tr.Describe(),
+			source,
 			err,
 			delay,
 		)
@@ -188,7 +392,63 @@ func isMissingSlurmCommandError(err error) bool {
 	return errors.As(err, &missingErr)
 }
 
-func runOnce(ctx context.Context, collector *slurm.Collector, source string) error {
+// runServe polls like the TUI does but publishes each update as Prometheus
+// metrics instead of rendering it.
+func runServe(ctx context.Context, cfg config.Config, collector monitor.Collector, source string, rec *record.Writer) (err error) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	listener, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", cfg.Listen, err)
+	}
+
+	exporter := metrics.NewExporter(source)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", exporter)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, "slurm-monitor exporter: metrics are served at /metrics")
+	})
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	updates := make(chan monitor.Update, 8)
+	waitLoop := startLoop(ctx, cfg, collector, rec, updates)
+	defer func() {
+		stop()
+		if recordErr := waitLoop(); recordErr != nil && err == nil {
+			err = recordErr
+		}
+	}()
+	go exporter.Consume(ctx, updates)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	fmt.Fprintf(os.Stderr, "slurm-monitor: serving metrics for %s on http://%s/metrics\n", source, listener.Addr())
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("metrics server: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shut down metrics server: %w", err)
+	}
+	return nil
+}
+
+func runOnce(ctx context.Context, collector monitor.Collector, source string, cfg config.Config, rec *record.Writer) error {
 	collectCtx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 	defer cancel()
 
@@ -196,10 +456,59 @@ func runOnce(ctx context.Context, collector *slurm.Collector, source string) err
 	if err != nil {
 		return err
 	}
+	if rec != nil {
+		if err := rec.Record(snapshot); err != nil {
+			return err
+		}
+	}
+	slurm.SortNodes(snapshot.Nodes, cfg.NodeSort)
+	slurm.SortUsers(snapshot.Users, cfg.UserSort)
 
+	if cfg.Format != "" && cfg.Format != config.FormatText {
+		return export.Write(os.Stdout, export.Format(cfg.Format), export.FromSnapshot(source, snapshot))
+	}
+	printTextSummary(source, snapshot, cfg.NodeSort)
+	return nil
+}
+
+// printTextSummary prints the snapshot in its current node and user order.
+// Node rows are only listed when a non-default node sort was requested, since
+// a name-ordered top slice of a large cluster says little.
+func printTextSummary(source string, snapshot slurm.Snapshot, nodeSort slurm.NodeSort) {
 	fmt.Fprintf(os.Stdout, "source: %s\n", source)
+	if snapshot.SlurmVersion != "" {
+		fmt.Fprintf(os.Stdout, "slurm_version: %s\n", snapshot.SlurmVersion)
+	}
 	fmt.Fprintf(os.Stdout, "collected_at: %s\n", snapshot.CollectedAt.Format(time.RFC3339))
+	if snapshot.Partial() {
+		fmt.Fprintln(os.Stdout, "sections:")
+		for _, st := range snapshot.Sections {
+			if st.State == slurm.SectionFresh {
+				continue
+			}
+			fmt.Fprintf(os.Stdout, "  - %s state=%s error=%q\n", st.Section, st.State, st.Error)
+		}
+	}
 	fmt.Fprintf(os.Stdout, "nodes: %d\n", len(snapshot.Nodes))
+	if nodeSort.Key != "" && nodeSort != slurm.DefaultNodeSort {
+		nodes := snapshot.Nodes
+		if len(nodes) > 10 {
+			nodes = nodes[:10]
+		}
+		fmt.Fprintf(os.Stdout, "top_nodes (sort %s):\n", nodeSort)
+		for _, n := range nodes {
+			fmt.Fprintf(
+				os.Stdout,
+				"  - %s state=%s partition=%s cpu=%s mem=%s gpu=%s\n",
+				n.Name,
+				n.State,
+				n.Partition,
+				uifmt.Ratio(n.CPUAlloc, n.CPUTotal),
+				uifmt.MemPair(n.MemAllocMB, n.MemTotalMB),
+				uifmt.Ratio(n.GPUAlloc, n.GPUTotal),
+			)
+		}
+	}
 	fmt.Fprintf(
 		os.Stdout,
 		"queue_jobs: running_cpu=%d running_gpu=%d pending_cpu=%d pending_gpu=%d other=%d total=%d\n",
@@ -219,6 +528,8 @@ func runOnce(ctx context.Context, collector *slurm.Collector, source string) err
 		snapshot.Queue.ResourceLoad.PendingGPU,
 	)
 
+	printQueueBreakdowns(snapshot.Queue)
+
 	totals := snapshot.Totals()
 	fmt.Fprintf(
 		os.Stdout,
@@ -227,9 +538,22 @@ func runOnce(ctx context.Context, collector *slurm.Collector, source string) err
 		uifmt.MemPair(totals.MemAllocMB, totals.MemTotalMB),
 		uifmt.Ratio(totals.GPUAlloc, totals.GPUTotal),
 	)
+	if stats := snapshot.GPUTypeStats(); len(stats) > 0 {
+		fmt.Fprintln(os.Stdout, "gpu_types:")
+		for _, st := range stats {
+			name := st.Type
+			if name == "" {
+				name = "untyped"
+			}
+			fmt.Fprintf(
+				os.Stdout,
+				"  - %s gpu=%s running_gpu=%d pending_gpu=%d pending_jobs=%d\n",
+				name, uifmt.Ratio(st.Alloc, st.Total), st.RunningGPU, st.PendingGPU, st.PendingJobs,
+			)
+		}
+	}
 
-	users := append([]slurm.UserSummary(nil), snapshot.Users...)
-	slurm.SortUsersForDisplay(users)
+	users := snapshot.Users
 	if len(users) > 10 {
 		users = users[:10]
 	}
@@ -250,6 +574,34 @@ func runOnce(ctx context.Context, collector *slurm.Collector, source string) err
 			uifmt.MemMB(user.PendingMemMB),
 		)
 	}
+}
 
-	return nil
+// printQueueBreakdowns prints the per-cause, per-partition, per-state and
+// per-name queue counts. Lists arrive sorted by count from the parser; job
+// names are capped like the user list since they are unbounded.
+func printQueueBreakdowns(q slurm.QueueSummary) {
+	fmt.Fprintln(os.Stdout, "pending_causes:")
+	for _, c := range q.PendingCause {
+		fmt.Fprintf(os.Stdout, "  - %s count=%d\n", c.Name, c.Count)
+	}
+	fmt.Fprintln(os.Stdout, "partitions:")
+	for _, p := range q.ByPartition {
+		fmt.Fprintf(
+			os.Stdout,
+			"  - %s running=%d pending=%d other=%d total=%d\n",
+			p.Partition, p.Running, p.Pending, p.Other, p.Running+p.Pending+p.Other,
+		)
+	}
+	fmt.Fprintln(os.Stdout, "job_states:")
+	for _, st := range q.ByState {
+		fmt.Fprintf(os.Stdout, "  - %s count=%d\n", st.State, st.Count)
+	}
+	names := q.ByJobName
+	if len(names) > 10 {
+		names = names[:10]
+	}
+	fmt.Fprintln(os.Stdout, "top_job_names:")
+	for _, n := range names {
+		fmt.Fprintf(os.Stdout, "  - %s count=%d\n", n.Name, n.Count)
+	}
 }
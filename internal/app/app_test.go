@@ -9,6 +9,9 @@ import (
 	"testing"
 	"time"
 
+	"slurm_monitor/internal/config"
+	"slurm_monitor/internal/monitor"
+	"slurm_monitor/internal/record"
 	"slurm_monitor/internal/slurm"
 	"slurm_monitor/internal/transport"
 )
@@ -26,6 +29,30 @@ func (f fakeTransport) Describe() string {
 	return "fake"
 }
 
+// sourceTransport answers the collector's node and queue commands; other
+// commands, such as the capability probe, print nothing. queueErr makes the
+// queue command time out.
+type sourceTransport struct {
+	nodes, queue string
+	queueErr     bool
+}
+
+func (s sourceTransport) Run(_ context.Context, command string) (transport.RunResult, error) {
+	switch {
+	case strings.HasPrefix(command, "scontrol show node"):
+		return transport.RunResult{Stdout: s.nodes}, nil
+	case strings.HasPrefix(command, "squeue -h") && s.queueErr:
+		return transport.RunResult{}, &transport.RunError{Command: command, Timeout: true}
+	case strings.HasPrefix(command, "squeue -h"):
+		return transport.RunResult{Stdout: s.queue}, nil
+	}
+	return transport.RunResult{}, nil
+}
+
+func (s sourceTransport) Describe() string {
+	return "fake"
+}
+
 type scriptedTransport struct {
 	calls     int
 	responses []transportResponse
@@ -176,18 +203,18 @@ func TestAwaitSlurmAvailabilityStopsOnPermanentTransportFailure(t *testing.T) {
 }
 
 func TestRunOncePrintsQueueAndUserCPUAndGPUSplit(t *testing.T) {
-	raw := strings.Join([]string{
-		"NodeName=node001 State=IDLE CPUTot=64 CPUAlloc=32 CPULoad=16.00 RealMemory=256000 AllocMem=128000 FreeMem=96000 Partitions=main CfgTRES=cpu=64,mem=256000M,billing=64,gres/gpu=4 AllocTRES=cpu=32,mem=128000M,billing=32,gres/gpu=2",
-		"__SLURM_MONITOR_SPLIT__",
-		"1001|RUNNING|alice|8|20G|cpu=8,mem=20G,gres/gpu=1|train|jobA|None",
-		"1002|PENDING|alice|4|10G|N/A|train|jobB|Priority",
-	}, "\n")
-	collector := slurm.NewCollector(fakeTransport{
-		result: transport.RunResult{Stdout: raw},
+	collector := slurm.NewCollector(sourceTransport{
+		nodes: strings.Join([]string{
+			"NodeName=node001 State=IDLE CPUTot=64 CPUAlloc=32 CPULoad=16.00 RealMemory=256000 AllocMem=128000 FreeMem=96000 Partitions=main CfgTRES=cpu=64,mem=256000M,billing=64,gres/gpu=4 AllocTRES=cpu=32,mem=128000M,billing=32,gres/gpu=2",
+		}, "\n"),
+		queue: strings.Join([]string{
+			"1001|RUNNING|alice|8|20G|cpu=8,mem=20G,gres/gpu=1|train|jobA|None",
+			"1002|PENDING|alice|4|10G|N/A|train|jobB|Priority",
+		}, "\n"),
 	}, 2*time.Second)
 
 	out := captureStdout(t, func() {
-		if err := runOnce(context.Background(), collector, "fake"); err != nil {
+		if err := runOnce(context.Background(), collector, "fake", config.Config{Format: config.FormatText}, nil); err != nil {
 			t.Fatalf("expected nil error, got %v", err)
 		}
 	})
@@ -203,6 +230,81 @@ func TestRunOncePrintsQueueAndUserCPUAndGPUSplit(t *testing.T) {
 	}
 }
 
+func TestRunOncePrintsQueueBreakdowns(t *testing.T) {
+	collector := slurm.NewCollector(sourceTransport{
+		nodes: strings.Join([]string{
+			"NodeName=node001 State=IDLE CPUTot=64 CPUAlloc=32 CPULoad=16.00 RealMemory=256000 AllocMem=128000 FreeMem=96000 Partitions=main CfgTRES=cpu=64,mem=256000M,billing=64,gres/gpu=4 AllocTRES=cpu=32,mem=128000M,billing=32,gres/gpu=2",
+		}, "\n"),
+		queue: strings.Join([]string{
+			"1001|RUNNING|alice|8|20G|cpu=8,mem=20G,gres/gpu=1|gpu|jobA|None",
+			"1002|PENDING|alice|4|10G|N/A|gpu|jobA|Priority",
+			"1003|PENDING|bob|4|10G|N/A|cpu|jobB|QOSMaxGRESPerUser",
+			"1004|PENDING|bob|4|10G|N/A|cpu|jobB|Priority",
+		}, "\n"),
+	}, 2*time.Second)
+
+	out := captureStdout(t, func() {
+		if err := runOnce(context.Background(), collector, "fake", config.Config{Format: config.FormatText}, nil); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+	})
+
+	for _, want := range []string{
+		"pending_causes:\n  - Priority count=2\n  - QOSMaxGRESPerUser count=1\n",
+		"  - gpu running=1 pending=1 other=0 total=2\n",
+		"  - cpu running=0 pending=2 other=0 total=2\n",
+		"job_states:\n  - PENDING count=3\n  - RUNNING count=1\n",
+		"top_job_names:\n  - jobA count=2\n  - jobB count=2\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output, got: %q", want, out)
+		}
+	}
+}
+
+func TestRunOnceListsFailedSections(t *testing.T) {
+	collector := slurm.NewCollector(sourceTransport{
+		nodes:    "NodeName=node001 State=IDLE CPUTot=64 CPUAlloc=0 RealMemory=256000 AllocMem=0 Partitions=main",
+		queueErr: true,
+	}, 2*time.Second)
+
+	out := captureStdout(t, func() {
+		if err := runOnce(context.Background(), collector, "fake", config.Config{Format: config.FormatText}, nil); err != nil {
+			t.Fatalf("expected the node section to publish, got %v", err)
+		}
+	})
+
+	if !strings.Contains(out, "sections:\n  - queue state=failed error=") {
+		t.Fatalf("expected the failed queue section in output, got: %q", out)
+	}
+	if !strings.Contains(out, "nodes: 1\n") {
+		t.Fatalf("expected nodes despite the queue failure, got: %q", out)
+	}
+}
+
+func TestRunOnceWritesJSONDocument(t *testing.T) {
+	collector := slurm.NewCollector(sourceTransport{
+		nodes: strings.Join([]string{
+			"NodeName=node001 State=IDLE CPUTot=64 CPUAlloc=32 CPULoad=16.00 RealMemory=256000 AllocMem=128000 FreeMem=96000 Partitions=main CfgTRES=cpu=64,mem=256000M,billing=64,gres/gpu=4 AllocTRES=cpu=32,mem=128000M,billing=32,gres/gpu=2",
+		}, "\n"),
+		queue: strings.Join([]string{
+			"1002|PENDING|alice|4|10G|N/A|train|jobB|Priority",
+		}, "\n"),
+	}, 2*time.Second)
+
+	out := captureStdout(t, func() {
+		if err := runOnce(context.Background(), collector, "fake", config.Config{Format: config.FormatJSON}, nil); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+	})
+
+	for _, want := range []string{`"schema_version": 1`, `"source": "fake"`, `"pending_cause": [`, `"name": "Priority"`} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in json output, got: %s", want, out)
+		}
+	}
+}
+
 func captureStdout(t *testing.T, fn func()) string {
 	t.Helper()
 	orig := os.Stdout
@@ -229,3 +331,115 @@ func captureStdout(t *testing.T, fn func()) string {
 	}
 	return string(out)
 }
+
+func TestRunOnceAppliesSort(t *testing.T) {
+	collector := slurm.NewCollector(sourceTransport{
+		nodes: strings.Join([]string{
+			"NodeName=node001 State=IDLE CPUTot=64 CPUAlloc=8 RealMemory=256000 AllocMem=0 Partitions=main CfgTRES=cpu=64,mem=256000M,gres/gpu=4 AllocTRES=cpu=8",
+			"NodeName=node002 State=MIXED CPUTot=64 CPUAlloc=32 RealMemory=256000 AllocMem=0 Partitions=main CfgTRES=cpu=64,mem=256000M,gres/gpu=4 AllocTRES=cpu=32,gres/gpu=3",
+		}, "\n"),
+		queue: strings.Join([]string{
+			"1001|RUNNING|alice|8|20G|cpu=8,mem=20G,gres/gpu=3|main|jobA|None",
+			"1002|PENDING|bob|4|10G|N/A|main|jobB|Priority",
+		}, "\n"),
+	}, 2*time.Second)
+
+	cfg := config.Config{
+		Format:   config.FormatText,
+		NodeSort: slurm.NodeSort{Key: slurm.NodeSortGPU, Desc: true},
+		UserSort: slurm.UserSort{Key: slurm.UserSortName, Desc: true},
+	}
+	out := captureStdout(t, func() {
+		if err := runOnce(context.Background(), collector, "fake", cfg, nil); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+	})
+
+	nodeIdx := strings.Index(out, "top_nodes (sort gpu↓):\n  - node002")
+	if nodeIdx < 0 {
+		t.Fatalf("expected gpu-sorted node list, got: %q", out)
+	}
+	bob := strings.Index(out, "  - bob held_cpu")
+	alice := strings.Index(out, "  - alice held_cpu")
+	if bob < 0 || alice < 0 || bob > alice {
+		t.Fatalf("expected users sorted by name descending, got: %q", out)
+	}
+
+	defaultOut := captureStdout(t, func() {
+		if err := runOnce(context.Background(), collector, "fake", config.Config{Format: config.FormatText}, nil); err != nil {
+			t.Fatalf("expected nil error, got %v", err)
+		}
+	})
+	if strings.Contains(defaultOut, "top_nodes") {
+		t.Fatalf("expected no node list without a node sort, got: %q", defaultOut)
+	}
+}
+
+type staticCollector struct{}
+
+func (staticCollector) Collect(context.Context) (slurm.Snapshot, error) {
+	return slurm.Snapshot{CollectedAt: time.Now()}, nil
+}
+
+func TestStartLoopStopsBeforeRecordingIsClosed(t *testing.T) {
+	path := t.TempDir() + "/session.jsonl.gz"
+	rec, err := record.Create(path, "fake")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := make(chan monitor.Update, 8)
+	waitLoop := startLoop(ctx, config.Config{Refresh: time.Millisecond}, staticCollector{}, rec, updates)
+
+	for i := 0; i < 3; i++ {
+		<-updates
+	}
+	cancel()
+	if err := waitLoop(); err != nil {
+		t.Fatalf("expected no recording error, got %v", err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("expected clean close, got %v", err)
+	}
+	frames, err := record.Load(path)
+	if err != nil || len(frames) < 3 {
+		t.Fatalf("expected at least 3 readable frames, got %d (%v)", len(frames), err)
+	}
+}
+
+func TestStartLoopReportsFirstRecordingError(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("/dev/full not available")
+	}
+	rec, err := record.Create("/dev/full", "fake")
+	if err != nil {
+		t.Skipf("cannot open /dev/full: %v", err)
+	}
+	defer rec.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := make(chan monitor.Update, 8)
+	waitLoop := startLoop(ctx, config.Config{Refresh: time.Millisecond}, staticCollector{}, rec, updates)
+
+	if u := <-updates; u.State != monitor.StateConnected {
+		t.Fatalf("expected monitoring to continue despite the write error, got %+v", u)
+	}
+	cancel()
+	if err := waitLoop(); err == nil || !strings.Contains(err.Error(), "recording") {
+		t.Fatalf("expected recording write error, got %v", err)
+	}
+}
+
+func TestSampleIntervalIsShortestPollInterval(t *testing.T) {
+	cfg := config.Config{Refresh: 10 * time.Second}
+	if got := sampleInterval(cfg); got != 10*time.Second {
+		t.Fatalf("expected refresh without overrides, got %v", got)
+	}
+	cfg.SourceRefresh = map[slurm.Section]time.Duration{slurm.SectionQueue: 2 * time.Second, slurm.SectionNodes: time.Minute}
+	if got := sampleInterval(cfg); got != 2*time.Second {
+		t.Fatalf("expected the fastest source interval, got %v", got)
+	}
+	cfg.AdaptiveRefresh, cfg.MinRefresh = true, time.Second
+	if got := sampleInterval(cfg); got != time.Second {
+		t.Fatalf("expected --min-refresh, got %v", got)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"slurm_monitor/internal/config"
+	"slurm_monitor/internal/slurm"
 	"slurm_monitor/internal/transport"
 )
 
@@ -22,10 +23,12 @@ type doctorCheck struct {
 }
 
 type doctorDeps struct {
-	lookPath          func(string) (string, error)
-	stat              func(string) (os.FileInfo, error)
-	buildTransport    func(config.Config) (transport.Transport, error)
-	checkAvailability func(context.Context, transport.Transport, time.Duration) error
+	lookPath           func(string) (string, error)
+	stat               func(string) (os.FileInfo, error)
+	buildTransport     func(config.Config) (transport.Transport, error)
+	checkAvailability  func(context.Context, transport.Transport, time.Duration) error
+	detectCapabilities func(context.Context, transport.Transport, time.Duration) (slurm.Capabilities, error)
+	pingREST           func(context.Context, config.Config) (slurm.Capabilities, error)
 }
 
 func defaultDoctorDeps() doctorDeps {
@@ -34,6 +37,12 @@ func defaultDoctorDeps() doctorDeps {
 		stat:              os.Stat,
 		buildTransport:    buildTransport,
 		checkAvailability: checkSlurmAvailability,
+		detectCapabilities: func(ctx context.Context, tr transport.Transport, timeout time.Duration) (slurm.Capabilities, error) {
+			return slurm.NewCollector(tr, timeout).DetectCapabilities(ctx)
+		},
+		pingREST: func(ctx context.Context, cfg config.Config) (slurm.Capabilities, error) {
+			return newRESTCollector(cfg).DetectCapabilities(ctx)
+		},
 	}
 }
 
@@ -43,7 +52,7 @@ func RunDoctor(cfg config.Config, out io.Writer) error {
 
 func runDoctorWithDeps(cfg config.Config, out io.Writer, deps doctorDeps) error {
 	target := "local"
-	if cfg.Mode == config.ModeRemote {
+	if cfg.Mode != config.ModeLocal {
 		target = cfg.Target
 	}
 
@@ -118,10 +127,25 @@ func buildDoctorChecks(cfg config.Config, deps doctorDeps) []doctorCheck {
 		for _, tool := range []string{"sh", "sinfo", "squeue", "scontrol"} {
 			appendToolCheck("local", tool)
 		}
-	} else {
+	} else if cfg.Mode == config.ModeRemote {
 		appendToolCheck("local", "ssh")
 		appendFileCheck("ssh config file", cfg.SSHConfig)
 		appendFileCheck("ssh identity file", cfg.IdentityFile)
+	} else if cfg.Mode == config.ModeREST {
+		appendFileCheck("slurmrestd token file", cfg.TokenFile)
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.CommandTimeout)
+		defer cancel()
+		caps, err := deps.pingREST(ctx, cfg)
+		if err != nil {
+			checks = append(checks, doctorCheck{name: "slurmrestd ping", err: err})
+			return checks
+		}
+		checks = append(checks, doctorCheck{
+			name:   "slurmrestd ping",
+			detail: "slurmrestd " + cfg.RESTVersion + " accepted the token at " + cfg.Target,
+		})
+		checks = append(checks, doctorCheck{name: "slurm version", detail: describeCapabilities(caps)})
+		return checks
 	}
 
 	tr, err := deps.buildTransport(cfg)
@@ -141,19 +165,46 @@ func buildDoctorChecks(cfg config.Config, deps doctorDeps) []doctorCheck {
 			name: "slurm preflight",
 			err:  err,
 		})
+		return checks
+	}
+	checks = append(checks, doctorCheck{
+		name:   "slurm preflight",
+		detail: "required Slurm commands are reachable on " + tr.Describe(),
+	})
+
+	caps, err := deps.detectCapabilities(ctx, tr, cfg.CommandTimeout)
+	if err != nil {
+		checks = append(checks, doctorCheck{
+			name: "slurm version",
+			err:  err,
+		})
 	} else {
 		checks = append(checks, doctorCheck{
-			name:   "slurm preflight",
-			detail: "required Slurm commands are reachable on " + tr.Describe(),
+			name:   "slurm version",
+			detail: describeCapabilities(caps),
 		})
 	}
 
 	return checks
 }
 
+// describeCapabilities renders the detected release and optional features,
+// such as "23.02.7 (json, tres-alloc)".
+func describeCapabilities(caps slurm.Capabilities) string {
+	version := caps.Version.String()
+	if version == "" {
+		version = "unknown"
+	}
+	features := caps.Features()
+	if len(features) == 0 {
+		return version + " (text output, gres column)"
+	}
+	return version + " (" + strings.Join(features, ", ") + ")"
+}
+
 func RunDryRun(cfg config.Config, out io.Writer) error {
 	target := "local"
-	if cfg.Mode == config.ModeRemote {
+	if cfg.Mode != config.ModeLocal {
 		target = cfg.Target
 	}
 
@@ -162,25 +213,54 @@ func RunDryRun(cfg config.Config, out io.Writer) error {
 		duration = cfg.Duration.String()
 	}
 
+	format := cfg.Format
+	if format == "" {
+		format = config.FormatText
+	}
+
 	fmt.Fprintln(out, "slurm-monitor dry-run")
 	fmt.Fprintf(out, "mode: %s\n", cfg.Mode)
 	fmt.Fprintf(out, "target: %s\n", target)
 	fmt.Fprintf(out, "refresh: %s\n", cfg.Refresh)
+	if len(cfg.SourceRefresh) > 0 {
+		var parts []string
+		for _, section := range slurm.SourceSections() {
+			if d, ok := cfg.SourceRefresh[section]; ok {
+				parts = append(parts, fmt.Sprintf("%s=%s", section, d))
+			}
+		}
+		fmt.Fprintf(out, "source-refresh: %s\n", strings.Join(parts, ","))
+	}
+	if cfg.AdaptiveRefresh {
+		fmt.Fprintf(out, "adaptive-refresh: %s-%s\n", cfg.MinRefresh, cfg.MaxRefresh)
+	}
 	fmt.Fprintf(out, "connect-timeout: %s\n", cfg.ConnectTimeout)
 	fmt.Fprintf(out, "command-timeout: %s\n", cfg.CommandTimeout)
 	fmt.Fprintf(out, "duration: %s\n", duration)
 	fmt.Fprintf(out, "once: %t\n", cfg.Once)
+	fmt.Fprintf(out, "format: %s\n", format)
 	fmt.Fprintf(out, "compact: %t\n", cfg.Compact)
 	fmt.Fprintf(out, "no-color: %t\n\n", cfg.NoColor)
 
 	fmt.Fprintln(out, "planned sequence:")
 	fmt.Fprintln(out, "1. Parse flags and build the configured transport.")
-	if cfg.Mode == config.ModeLocal {
+	switch cfg.Mode {
+	case config.ModeLocal:
 		fmt.Fprintln(out, "2. Run a local preflight check for sh, sinfo, squeue, and scontrol.")
-	} else {
+	case config.ModeFixture:
+		fmt.Fprintln(out, "2. Load the transport fixture and replay its recorded preflight check.")
+	case config.ModeSim:
+		fmt.Fprintln(out, "2. Build the simulated cluster; no Slurm installation is needed.")
+	case config.ModeREST:
+		fmt.Fprintf(out, "2. Ping slurmrestd %s at the target URL with the JWT from --token-file or SLURM_JWT.\n", cfg.RESTVersion)
+	default:
 		fmt.Fprintln(out, "2. Connect over OpenSSH to the target and validate sinfo, squeue, and scontrol remotely.")
 	}
-	if cfg.Once {
+	if cfg.Command == config.CommandServe {
+		fmt.Fprintf(out, "3. Start the polling loop and serve Prometheus metrics on %s/metrics until interrupted or duration is reached.\n", cfg.Listen)
+	} else if cfg.Once && format != config.FormatText {
+		fmt.Fprintf(out, "3. Collect one snapshot, print it as %s, and exit.\n", format)
+	} else if cfg.Once {
 		fmt.Fprintln(out, "3. Collect one snapshot, print summary metrics, and exit.")
 	} else {
 		fmt.Fprintln(out, "3. Start the polling loop and render the live TUI until interrupted or duration is reached.")
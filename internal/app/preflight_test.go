@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"slurm_monitor/internal/config"
+	"slurm_monitor/internal/slurm"
 	"slurm_monitor/internal/transport"
 )
 
@@ -29,6 +30,9 @@ func TestRunDoctorWithDepsLocalPass(t *testing.T) {
 		checkAvailability: func(context.Context, transport.Transport, time.Duration) error {
 			return nil
 		},
+		detectCapabilities: func(context.Context, transport.Transport, time.Duration) (slurm.Capabilities, error) {
+			return slurm.Capabilities{Version: slurm.Version{Major: 23, Minor: 2, Patch: 7}, JSON: true, TRESAlloc: true}, nil
+		},
 	}
 
 	var out strings.Builder
@@ -44,6 +48,7 @@ func TestRunDoctorWithDepsLocalPass(t *testing.T) {
 		"[ok] local tool sinfo",
 		"[ok] local tool squeue",
 		"[ok] local tool scontrol",
+		"[ok] slurm version: 23.02.7 (json, tres-alloc)",
 		"doctor result: PASS",
 	}
 	for _, item := range required {
@@ -95,6 +100,41 @@ func TestRunDoctorWithDepsRemoteFailure(t *testing.T) {
 	}
 }
 
+func TestRunDoctorWithDepsRESTSkipsCommandChecks(t *testing.T) {
+	cfg := config.Config{
+		Mode:           config.ModeREST,
+		Target:         "https://slurm.example.org:6820",
+		RESTVersion:    "v0.0.40",
+		CommandTimeout: 2 * time.Second,
+	}
+
+	deps := doctorDeps{
+		lookPath: func(name string) (string, error) {
+			t.Fatalf("unexpected tool lookup for %s", name)
+			return "", nil
+		},
+		stat: os.Stat,
+		buildTransport: func(config.Config) (transport.Transport, error) {
+			t.Fatalf("slurmrestd mode must not build a command transport")
+			return nil, nil
+		},
+		pingREST: func(context.Context, config.Config) (slurm.Capabilities, error) {
+			return slurm.Capabilities{}, errors.New("slurmrestd /slurm/v0.0.40/ping [http 401]")
+		},
+	}
+
+	var out strings.Builder
+	if err := runDoctorWithDeps(cfg, &out, deps); err == nil {
+		t.Fatalf("expected failure")
+	}
+	text := out.String()
+	for _, item := range []string{"mode: rest", "[fail] slurmrestd ping", "http 401", "doctor result: FAIL"} {
+		if !strings.Contains(text, item) {
+			t.Fatalf("doctor output missing %q", item)
+		}
+	}
+}
+
 func TestRunDryRunLocal(t *testing.T) {
 	cfg := config.Config{
 		Mode:           config.ModeLocal,
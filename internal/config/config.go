@@ -5,8 +5,15 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"os"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
+
+	"slurm_monitor/internal/sim"
+	"slurm_monitor/internal/slurm"
+	"slurm_monitor/internal/transport"
 )
 
 type Mode string
@@ -14,6 +21,12 @@ type Mode string
 const (
 	ModeLocal  Mode = "local"
 	ModeRemote Mode = "remote"
+	// ModeFixture replays a transport fixture given as fixture://<path>.
+	ModeFixture Mode = "fixture"
+	// ModeSim runs against a simulated cluster given as sim://<profile>.
+	ModeSim Mode = "sim"
+	// ModeREST reads from slurmrestd given as an http:// or https:// URL.
+	ModeREST Mode = "rest"
 )
 
 type Command string
@@ -22,32 +35,80 @@ const (
 	CommandMonitor Command = "monitor"
 	CommandDoctor  Command = "doctor"
 	CommandDryRun  Command = "dry-run"
+	CommandServe   Command = "serve"
+	CommandReplay  Command = "replay"
+)
+
+type OutputFormat string
+
+const (
+	FormatText OutputFormat = "text"
+	FormatJSON OutputFormat = "json"
+	FormatCSV  OutputFormat = "csv"
+	FormatYAML OutputFormat = "yaml"
 )
 
 type Config struct {
-	Command        Command
-	Mode           Mode
-	Target         string
-	Refresh        time.Duration
-	ConnectTimeout time.Duration
-	CommandTimeout time.Duration
-	SSHConfig      string
-	IdentityFile   string
-	Port           int
-	NoColor        bool
-	Compact        bool
-	Once           bool
-	Duration       time.Duration
+	Command Command
+	Mode    Mode
+	Target  string
+	Refresh time.Duration
+	// SourceRefresh overrides Refresh for individual sources (nodes, queue,
+	// partitions) in the monitor and serve loops.
+	SourceRefresh map[slurm.Section]time.Duration
+	// AdaptiveRefresh lets the poll interval follow collection cost between
+	// MinRefresh and MaxRefresh, starting from Refresh.
+	AdaptiveRefresh bool
+	MinRefresh      time.Duration
+	MaxRefresh      time.Duration
+	ConnectTimeout  time.Duration
+	CommandTimeout  time.Duration
+	SSHConfig       string
+	IdentityFile    string
+	Port            int
+	NoColor         bool
+	Compact         bool
+	Once            bool
+	Format          OutputFormat
+	NodeSort        slurm.NodeSort
+	UserSort        slurm.UserSort
+	Listen          string
+	Duration        time.Duration
+	History         time.Duration
+	// Record is a file that each successful snapshot is appended to.
+	Record string
+	// ReplayPath is the recording the replay command plays back.
+	ReplayPath string
+	// RecordTransport is a fixture file that every transport command and its
+	// result are appended to.
+	RecordTransport string
+	// TokenFile holds the slurmrestd JWT; SLURM_JWT is used when empty.
+	TokenFile string
+	// RESTVersion is the slurmrestd API version, such as v0.0.40.
+	RESTVersion string
+	// ConfigFile holds flag settings applied before the command line.
+	ConfigFile string
 }
 
 var ErrHelpRequested = errors.New("help requested")
 
+// defaultMaxRefresh bounds --adaptive-refresh when --max-refresh is not set.
+const defaultMaxRefresh = time.Minute
+
+var restVersionRe = regexp.MustCompile(`^v0\.0\.[0-9]+$`)
+
 func defaultConfig() Config {
 	return Config{
 		Command:        CommandMonitor,
 		Refresh:        2 * time.Second,
 		ConnectTimeout: 10 * time.Second,
 		CommandTimeout: 15 * time.Second,
+		Format:         FormatText,
+		NodeSort:       slurm.DefaultNodeSort,
+		UserSort:       slurm.DefaultUserSort,
+		Listen:         ":9341",
+		History:        time.Hour,
+		RESTVersion:    slurm.DefaultRESTVersion,
 	}
 }
 
@@ -56,6 +117,12 @@ func newFlagSet(cfg *Config) *flag.FlagSet {
 	fs.SetOutput(io.Discard)
 
 	fs.DurationVar(&cfg.Refresh, "refresh", cfg.Refresh, "poll interval for collecting new Slurm snapshots")
+	fs.Func("source-refresh", "per-source poll interval as source=duration[,...], such as nodes=10s,partitions=1m; sources: "+sectionNames()+"; unlisted sources use --refresh", func(v string) error {
+		return parseSourceRefresh(v, cfg)
+	})
+	fs.BoolVar(&cfg.AdaptiveRefresh, "adaptive-refresh", false, "lengthen the poll interval when collections are slow or large and shorten it again when they are cheap")
+	fs.DurationVar(&cfg.MinRefresh, "min-refresh", 0, "shortest adaptive poll interval (default --refresh)")
+	fs.DurationVar(&cfg.MaxRefresh, "max-refresh", 0, "longest adaptive poll interval (default 1m)")
 	fs.DurationVar(&cfg.ConnectTimeout, "connect-timeout", cfg.ConnectTimeout, "max SSH connection setup time per command (remote mode)")
 	fs.DurationVar(&cfg.CommandTimeout, "command-timeout", cfg.CommandTimeout, "max runtime for each Slurm command before retry")
 	fs.StringVar(&cfg.SSHConfig, "ssh-config", "", "alternate OpenSSH config path (remote mode, supports Host aliases/ProxyJump)")
@@ -64,7 +131,25 @@ func newFlagSet(cfg *Config) *flag.FlagSet {
 	fs.BoolVar(&cfg.NoColor, "no-color", false, "disable ANSI color styling")
 	fs.BoolVar(&cfg.Compact, "compact", false, "force compact TUI layout for smaller terminals")
 	fs.BoolVar(&cfg.Once, "once", false, "collect one snapshot, print summary, and exit")
+	fs.Func("format", "output format for --once: text, json, csv, or yaml (default text)", func(v string) error {
+		format, err := parseOutputFormat(v)
+		if err != nil {
+			return err
+		}
+		cfg.Format = format
+		return nil
+	})
+	fs.Func("sort", "initial node or user sort as key[:asc|desc]; repeat to set both (node keys: name, state, partition, cpu, cpu%, mem, mem%, gpu, gpu%; user keys: default, held-gpu, held-cpu, pending-gpu, pending-cpu, pending-mem, running, pending, user)", func(v string) error {
+		return parseSort(v, cfg)
+	})
+	fs.StringVar(&cfg.Listen, "listen", cfg.Listen, "HTTP listen address for the Prometheus metrics endpoint (serve command)")
 	fs.DurationVar(&cfg.Duration, "duration", 0, "optional total runtime limit; 0 means run until interrupted")
+	fs.DurationVar(&cfg.History, "history", cfg.History, "how far back TUI utilization sparklines reach; 0 disables history")
+	fs.StringVar(&cfg.Record, "record", "", "append each successful snapshot to this gzip-compressed JSONL file (monitor and serve)")
+	fs.StringVar(&cfg.RecordTransport, "record-transport", "", "append every Slurm command and its raw output to this JSONL fixture; replay it with a fixture://<file> target")
+	fs.StringVar(&cfg.TokenFile, "token-file", "", "file holding the slurmrestd JWT; defaults to the SLURM_JWT environment variable (slurmrestd mode)")
+	fs.StringVar(&cfg.RESTVersion, "rest-version", cfg.RESTVersion, "slurmrestd API version in request paths, such as v0.0.41 (slurmrestd mode)")
+	fs.StringVar(&cfg.ConfigFile, "config", "", "file of flag settings, one name = value per line; command-line flags take precedence")
 
 	return fs
 }
@@ -77,18 +162,26 @@ func HelpText() string {
 	b.WriteString("slurm-monitor: resilient, read-only Slurm queue/node monitor\n\n")
 	b.WriteString("Usage:\n")
 	b.WriteString("  slurm-monitor [flags] [ssh-target]\n")
+	b.WriteString("  slurm-monitor [flags] <slurmrestd-url>\n")
 	b.WriteString("  slurm-monitor doctor [flags] [ssh-target]\n")
 	b.WriteString("  slurm-monitor dry-run [flags] [ssh-target]\n")
+	b.WriteString("  slurm-monitor serve [--listen addr] [flags] [ssh-target]\n")
+	b.WriteString("  slurm-monitor replay [flags] <recording>\n")
 	b.WriteString("  slurm-monitor completion [bash|zsh]\n\n")
 	b.WriteString("Commands:\n")
 	b.WriteString("  monitor  Start live monitoring (default when no command is given).\n")
 	b.WriteString("  doctor   Run non-mutating preflight checks and exit.\n")
 	b.WriteString("  dry-run  Print planned execution order and exit.\n")
+	b.WriteString("  serve    Poll continuously and expose Prometheus metrics over HTTP.\n")
+	b.WriteString("  replay   Play back a --record file in the TUI (space pause, ,/. step, [/] seek, -/+ speed).\n")
 	b.WriteString("  completion Print shell completion script output and exit.\n\n")
 	b.WriteString("Positional target:\n")
 	b.WriteString("  ssh-target is optional.\n")
 	b.WriteString("  - omitted: run locally (requires local sinfo/squeue/scontrol)\n")
-	b.WriteString("  - provided: run remotely through OpenSSH using alias or user@host\n\n")
+	b.WriteString("  - provided: run remotely through OpenSSH using alias or user@host\n")
+	b.WriteString("  - http(s)://<host>:<port>: read from slurmrestd with a JWT from SLURM_JWT or --token-file\n")
+	b.WriteString("  - fixture://<file>: replay a --record-transport capture instead of running commands\n")
+	b.WriteString("  - sim://<profile>[?option=value&...]: simulate a cluster (profiles: " + strings.Join(sim.ProfileNames(), ", ") + ")\n\n")
 	b.WriteString("Behavior:\n")
 	b.WriteString("  - monitor is read-only and never mutates Slurm state\n")
 	b.WriteString("  - doctor and dry-run are non-mutating helpers for setup and validation\n")
@@ -107,9 +200,22 @@ func HelpText() string {
 	b.WriteString("  slurm-monitor cluster_alias\n")
 	b.WriteString("  slurm-monitor user@cluster.example.org --refresh 1s\n")
 	b.WriteString("  slurm-monitor --once cluster_alias\n")
+	b.WriteString("  slurm-monitor --once --format json cluster_alias\n")
+	b.WriteString("  slurm-monitor --once --sort gpu --sort pending-gpu cluster_alias\n")
+	b.WriteString("  slurm-monitor --source-refresh nodes=10s,partitions=1m cluster_alias\n")
+	b.WriteString("  slurm-monitor --config ~/.config/slurm-monitor.conf cluster_alias\n")
+	b.WriteString("  slurm-monitor --adaptive-refresh --min-refresh 2s --max-refresh 30s cluster_alias\n")
 	b.WriteString("  slurm-monitor --duration 30m cluster_alias\n")
 	b.WriteString("  slurm-monitor doctor cluster_alias\n")
 	b.WriteString("  slurm-monitor dry-run --once cluster_alias\n")
+	b.WriteString("  slurm-monitor serve --listen :9341 cluster_alias\n")
+	b.WriteString("  slurm-monitor --record cluster.jsonl.gz cluster_alias\n")
+	b.WriteString("  slurm-monitor replay cluster.jsonl.gz\n")
+	b.WriteString("  slurm-monitor --once --record-transport capture.jsonl cluster_alias\n")
+	b.WriteString("  slurm-monitor --once fixture://capture.jsonl\n")
+	b.WriteString("  SLURM_JWT=$(scontrol token) slurm-monitor https://slurm.example.org:6820\n")
+	b.WriteString("  slurm-monitor 'sim://small?speed=60'\n")
+	b.WriteString("  slurm-monitor --once sim://large\n")
 	b.WriteString("  slurm-monitor completion bash\n")
 
 	return b.String()
@@ -125,6 +231,10 @@ func splitCommand(args []string) (Command, []string) {
 		return CommandDoctor, args[1:]
 	case string(CommandDryRun):
 		return CommandDryRun, args[1:]
+	case string(CommandServe):
+		return CommandServe, args[1:]
+	case string(CommandReplay):
+		return CommandReplay, args[1:]
 	case string(CommandMonitor):
 		return CommandMonitor, args[1:]
 	default:
@@ -132,35 +242,185 @@ func splitCommand(args []string) (Command, []string) {
 	}
 }
 
+func parseOutputFormat(v string) (OutputFormat, error) {
+	switch format := OutputFormat(strings.ToLower(strings.TrimSpace(v))); format {
+	case FormatText, FormatJSON, FormatCSV, FormatYAML:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q (expected text, json, csv, or yaml)", v)
+	}
+}
+
+// parseSort applies a key[:asc|desc] spec to the node or user sort, whichever
+// owns the key. Node and user key names do not overlap.
+func parseSort(v string, cfg *Config) error {
+	keyText, dirText, hasDir := strings.Cut(strings.TrimSpace(v), ":")
+	dirText = strings.ToLower(strings.TrimSpace(dirText))
+	if hasDir && dirText != "asc" && dirText != "desc" {
+		return fmt.Errorf("unsupported sort direction %q (expected asc or desc)", dirText)
+	}
+	direction := func(defaultDesc bool) bool {
+		if !hasDir {
+			return defaultDesc
+		}
+		return dirText == "desc"
+	}
+
+	if key, err := slurm.ParseNodeSortKey(keyText); err == nil {
+		cfg.NodeSort = slurm.NodeSort{Key: key, Desc: direction(key.DefaultDesc())}
+		return nil
+	}
+	if key, err := slurm.ParseUserSortKey(keyText); err == nil {
+		cfg.UserSort = slurm.UserSort{Key: key, Desc: direction(key.DefaultDesc())}
+		return nil
+	}
+	return fmt.Errorf("unknown sort key %q", keyText)
+}
+
+func sectionNames() string {
+	names := make([]string, 0, 3)
+	for _, s := range slurm.SourceSections() {
+		names = append(names, string(s))
+	}
+	return strings.Join(names, ", ")
+}
+
+// parseSourceRefresh adds source=duration pairs to cfg.SourceRefresh; the flag
+// can be repeated.
+func parseSourceRefresh(v string, cfg *Config) error {
+	for _, item := range strings.Split(v, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			return fmt.Errorf("expected source=duration, got %q", item)
+		}
+		section := slurm.Section(strings.ToLower(strings.TrimSpace(name)))
+		if !slices.Contains(slurm.SourceSections(), section) {
+			return fmt.Errorf("unknown source %q (expected %s)", name, sectionNames())
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("source %s: %w", section, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("source %s: interval must be > 0", section)
+		}
+		if cfg.SourceRefresh == nil {
+			cfg.SourceRefresh = make(map[slurm.Section]time.Duration)
+		}
+		cfg.SourceRefresh[section] = d
+	}
+	return nil
+}
+
+// applyConfigFile sets flags from a file of name = value lines, such as
+// "refresh = 5s". Blank lines and lines starting with # are skipped.
+func applyConfigFile(fs *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" {
+			return fmt.Errorf("%s:%d: expected name = value", path, i+1)
+		}
+		if name == "config" || fs.Lookup(name) == nil {
+			return fmt.Errorf("%s:%d: unknown setting %q", path, i+1, name)
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("%s:%d: %s: %w", path, i+1, name, err)
+		}
+	}
+	return nil
+}
+
 func ParseArgs(args []string) (Config, error) {
 	cfg := defaultConfig()
 	cfg.Command, args = splitCommand(args)
 	fs := newFlagSet(&cfg)
 
-	if err := fs.Parse(args); err != nil {
+	parse := func() error {
+		err := fs.Parse(args)
 		if errors.Is(err, flag.ErrHelp) {
-			return Config{}, ErrHelpRequested
+			return ErrHelpRequested
 		}
+		return err
+	}
+	if err := parse(); err != nil {
 		return Config{}, err
 	}
+	if path := cfg.ConfigFile; path != "" {
+		// Start over with the file applied first so that flags given on the
+		// command line win over it.
+		command := cfg.Command
+		cfg = defaultConfig()
+		cfg.Command = command
+		fs = newFlagSet(&cfg)
+		if err := applyConfigFile(fs, path); err != nil {
+			return Config{}, err
+		}
+		if err := parse(); err != nil {
+			return Config{}, err
+		}
+	}
 
 	pos := fs.Args()
 	if len(pos) > 1 {
 		return Config{}, fmt.Errorf("expected zero or one positional target, got %d", len(pos))
 	}
+	if cfg.Command == CommandReplay {
+		// The positional argument is the recording, not an SSH target.
+		if len(pos) != 1 || strings.TrimSpace(pos[0]) == "" {
+			return Config{}, fmt.Errorf("replay expects one recording file")
+		}
+		cfg.ReplayPath = strings.TrimSpace(pos[0])
+		pos = nil
+	}
 	if len(pos) == 1 {
 		cfg.Target = strings.TrimSpace(pos[0])
 	}
 
-	if cfg.Target == "" {
+	switch {
+	case cfg.Target == "":
 		cfg.Mode = ModeLocal
-	} else {
+	case strings.HasPrefix(cfg.Target, transport.FixtureScheme):
+		cfg.Mode = ModeFixture
+		if strings.TrimPrefix(cfg.Target, transport.FixtureScheme) == "" {
+			return Config{}, fmt.Errorf("fixture target needs a file path (fixture://<file>)")
+		}
+	case strings.HasPrefix(cfg.Target, "http://") || strings.HasPrefix(cfg.Target, "https://"):
+		cfg.Mode = ModeREST
+	case strings.HasPrefix(cfg.Target, sim.Scheme):
+		cfg.Mode = ModeSim
+		if _, err := sim.ParseTarget(cfg.Target); err != nil {
+			return Config{}, err
+		}
+	default:
 		cfg.Mode = ModeRemote
 	}
 
 	if cfg.Refresh <= 0 {
 		return Config{}, fmt.Errorf("--refresh must be > 0")
 	}
+	if !cfg.AdaptiveRefresh && (cfg.MinRefresh != 0 || cfg.MaxRefresh != 0) {
+		return Config{}, fmt.Errorf("--min-refresh and --max-refresh require --adaptive-refresh")
+	}
+	if cfg.AdaptiveRefresh {
+		if cfg.MinRefresh == 0 {
+			cfg.MinRefresh = cfg.Refresh
+		}
+		if cfg.MaxRefresh == 0 {
+			cfg.MaxRefresh = max(defaultMaxRefresh, cfg.MinRefresh)
+		}
+		if cfg.MinRefresh < 0 || cfg.MaxRefresh < cfg.MinRefresh {
+			return Config{}, fmt.Errorf("--min-refresh must be > 0 and at most --max-refresh")
+		}
+	}
 	if cfg.ConnectTimeout <= 0 {
 		return Config{}, fmt.Errorf("--connect-timeout must be > 0")
 	}
@@ -170,15 +430,40 @@ func ParseArgs(args []string) (Config, error) {
 	if cfg.Duration < 0 {
 		return Config{}, fmt.Errorf("--duration must be >= 0")
 	}
+	if cfg.History < 0 {
+		return Config{}, fmt.Errorf("--history must be >= 0")
+	}
 	if cfg.Port < 0 {
 		return Config{}, fmt.Errorf("--port must be >= 0")
 	}
+	if cfg.Command == CommandServe && cfg.Once {
+		return Config{}, fmt.Errorf("--once cannot be combined with serve")
+	}
+	if cfg.Command == CommandServe && strings.TrimSpace(cfg.Listen) == "" {
+		return Config{}, fmt.Errorf("--listen must not be empty")
+	}
+	if cfg.Command == CommandReplay && (cfg.Once || cfg.Record != "") {
+		return Config{}, fmt.Errorf("--once and --record cannot be combined with replay")
+	}
+	if cfg.Format != FormatText && !cfg.Once {
+		return Config{}, fmt.Errorf("--format %s requires --once", cfg.Format)
+	}
 
-	if cfg.Mode == ModeLocal {
+	if cfg.Mode != ModeRemote {
 		if cfg.SSHConfig != "" || cfg.IdentityFile != "" || cfg.Port != 0 {
 			return Config{}, fmt.Errorf("ssh-specific flags require a remote target")
 		}
 	}
+	if cfg.Mode == ModeREST {
+		if !restVersionRe.MatchString(cfg.RESTVersion) {
+			return Config{}, fmt.Errorf("--rest-version must look like v0.0.40, got %q", cfg.RESTVersion)
+		}
+		if cfg.RecordTransport != "" {
+			return Config{}, fmt.Errorf("--record-transport needs a command-based target; slurmrestd does not run commands")
+		}
+	} else if cfg.TokenFile != "" || cfg.RESTVersion != slurm.DefaultRESTVersion {
+		return Config{}, fmt.Errorf("--token-file and --rest-version require a slurmrestd URL target")
+	}
 
 	return cfg, nil
 }
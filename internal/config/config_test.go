@@ -2,8 +2,13 @@ package config
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"slurm_monitor/internal/slurm"
 )
 
 func TestParseArgsLocalDefault(t *testing.T) {
@@ -123,3 +128,224 @@ func TestHelpTextIncludesUsageAndExamples(t *testing.T) {
 		}
 	}
 }
+
+func TestParseArgsFormatRequiresOnce(t *testing.T) {
+	cfg, err := ParseArgs([]string{"--once", "--format", "JSON"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if cfg.Format != FormatJSON {
+		t.Fatalf("expected json format, got %s", cfg.Format)
+	}
+	if _, err := ParseArgs([]string{"--format", "csv"}); err == nil {
+		t.Fatalf("expected --format without --once to fail")
+	}
+	if _, err := ParseArgs([]string{"--once", "--format", "xml"}); err == nil {
+		t.Fatalf("expected unsupported format error")
+	}
+}
+
+func TestParseArgsServeCommand(t *testing.T) {
+	cfg, err := ParseArgs([]string{"serve", "--listen", "127.0.0.1:9400", "cluster_alias"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if cfg.Command != CommandServe {
+		t.Fatalf("expected serve command, got %s", cfg.Command)
+	}
+	if cfg.Listen != "127.0.0.1:9400" {
+		t.Fatalf("unexpected listen address %q", cfg.Listen)
+	}
+	if _, err := ParseArgs([]string{"serve", "--once"}); err == nil {
+		t.Fatalf("expected serve with --once to fail")
+	}
+}
+
+func TestParseArgsSort(t *testing.T) {
+	cfg, err := ParseArgs([]string{"--once", "--sort", "gpu", "--sort", "user:desc"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if cfg.NodeSort != (slurm.NodeSort{Key: slurm.NodeSortGPU, Desc: true}) {
+		t.Fatalf("expected gpu descending by default, got %+v", cfg.NodeSort)
+	}
+	if cfg.UserSort != (slurm.UserSort{Key: slurm.UserSortName, Desc: true}) {
+		t.Fatalf("expected explicit user:desc, got %+v", cfg.UserSort)
+	}
+
+	defaults, err := ParseArgs(nil)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if defaults.NodeSort != slurm.DefaultNodeSort || defaults.UserSort != slurm.DefaultUserSort {
+		t.Fatalf("expected default sorts, got %+v %+v", defaults.NodeSort, defaults.UserSort)
+	}
+
+	for _, bad := range []string{"bogus", "gpu:sideways"} {
+		if _, err := ParseArgs([]string{"--sort", bad}); err == nil {
+			t.Fatalf("expected --sort %s to fail", bad)
+		}
+	}
+}
+
+func TestParseArgsReplayCommand(t *testing.T) {
+	cfg, err := ParseArgs([]string{"replay", "--no-color", "night.jsonl.gz"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if cfg.Command != CommandReplay || cfg.ReplayPath != "night.jsonl.gz" || cfg.Target != "" {
+		t.Fatalf("expected replay of night.jsonl.gz, got %+v", cfg)
+	}
+	if _, err := ParseArgs([]string{"replay"}); err == nil {
+		t.Fatalf("expected replay without a file to fail")
+	}
+	if _, err := ParseArgs([]string{"replay", "--record", "out.gz", "night.jsonl.gz"}); err == nil {
+		t.Fatalf("expected replay with --record to fail")
+	}
+
+	cfg, err = ParseArgs([]string{"--record", "night.jsonl.gz", "cluster_alias"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if cfg.Record != "night.jsonl.gz" || cfg.Target != "cluster_alias" {
+		t.Fatalf("unexpected record config %+v", cfg)
+	}
+}
+
+func TestParseArgsFixtureTarget(t *testing.T) {
+	cfg, err := ParseArgs([]string{"--once", "fixture://capture.jsonl"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if cfg.Mode != ModeFixture || cfg.Target != "fixture://capture.jsonl" {
+		t.Fatalf("expected fixture mode, got mode=%s target=%s", cfg.Mode, cfg.Target)
+	}
+	if _, err := ParseArgs([]string{"fixture://"}); err == nil {
+		t.Fatalf("expected fixture target without a path to fail")
+	}
+	if _, err := ParseArgs([]string{"--port", "22", "fixture://capture.jsonl"}); err == nil {
+		t.Fatalf("expected ssh flags with a fixture target to fail")
+	}
+
+	cfg, err = ParseArgs([]string{"--record-transport", "capture.jsonl", "cluster_alias"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if cfg.RecordTransport != "capture.jsonl" || cfg.Mode != ModeRemote {
+		t.Fatalf("unexpected record-transport config %+v", cfg)
+	}
+}
+
+func TestParseArgsSimTarget(t *testing.T) {
+	cfg, err := ParseArgs([]string{"--once", "sim://large?nodes=2000"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if cfg.Mode != ModeSim || cfg.Target != "sim://large?nodes=2000" {
+		t.Fatalf("expected sim mode, got mode=%s target=%s", cfg.Mode, cfg.Target)
+	}
+	if _, err := ParseArgs([]string{"sim://nonexistent"}); err == nil {
+		t.Fatalf("expected unknown sim profile to fail")
+	}
+	if _, err := ParseArgs([]string{"--identity-file", "key", "sim://small"}); err == nil {
+		t.Fatalf("expected ssh flags with a sim target to fail")
+	}
+}
+
+func TestParseArgsRESTTarget(t *testing.T) {
+	cfg, err := ParseArgs([]string{"--token-file", "jwt", "--rest-version", "v0.0.41", "https://slurm.example.org:6820"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if cfg.Mode != ModeREST || cfg.TokenFile != "jwt" || cfg.RESTVersion != "v0.0.41" {
+		t.Fatalf("unexpected rest config %+v", cfg)
+	}
+	for _, args := range [][]string{
+		{"--rest-version", "41", "https://slurm.example.org:6820"},
+		{"--record-transport", "capture.jsonl", "https://slurm.example.org:6820"},
+		{"--port", "22", "http://slurm:6820"},
+		{"--token-file", "jwt", "cluster_alias"},
+	} {
+		if _, err := ParseArgs(args); err == nil {
+			t.Fatalf("expected %v to fail", args)
+		}
+	}
+}
+
+func TestParseArgsSourceRefresh(t *testing.T) {
+	cfg, err := ParseArgs([]string{"--source-refresh", "nodes=10s,partitions=1m", "--source-refresh", "queue=2s"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	want := map[slurm.Section]time.Duration{slurm.SectionNodes: 10 * time.Second, slurm.SectionPartitions: time.Minute, slurm.SectionQueue: 2 * time.Second}
+	if len(cfg.SourceRefresh) != len(want) {
+		t.Fatalf("expected %v, got %v", want, cfg.SourceRefresh)
+	}
+	for section, d := range want {
+		if cfg.SourceRefresh[section] != d {
+			t.Fatalf("expected %v, got %v", want, cfg.SourceRefresh)
+		}
+	}
+
+	for _, bad := range []string{"sdiag=5s", "nodes", "nodes=0s", "nodes=soon"} {
+		if _, err := ParseArgs([]string{"--source-refresh", bad}); err == nil {
+			t.Fatalf("expected --source-refresh %s to fail", bad)
+		}
+	}
+}
+
+func TestParseArgsConfigFileYieldsToFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slurm-monitor.conf")
+	content := "# cluster defaults\nrefresh = 5s\n\nsource-refresh = nodes=30s\ncompact = true\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := ParseArgs([]string{"--refresh", "1s", "--config", path, "cluster_alias"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if cfg.Refresh != time.Second {
+		t.Fatalf("expected the command-line refresh to win, got %s", cfg.Refresh)
+	}
+	if !cfg.Compact || cfg.SourceRefresh[slurm.SectionNodes] != 30*time.Second || cfg.Target != "cluster_alias" {
+		t.Fatalf("expected file settings applied, got %+v", cfg)
+	}
+
+	for _, bad := range []string{"refresh 5s\n", "colour = true\n", "config = other.conf\n", "refresh = soon\n"} {
+		if err := os.WriteFile(path, []byte(bad), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+		if _, err := ParseArgs([]string{"--config", path}); err == nil || !strings.Contains(err.Error(), path+":1") {
+			t.Fatalf("expected %q to fail with its line, got %v", bad, err)
+		}
+	}
+	if _, err := ParseArgs([]string{"--config", filepath.Join(t.TempDir(), "missing.conf")}); err == nil {
+		t.Fatalf("expected a missing config file to fail")
+	}
+}
+
+func TestParseArgsAdaptiveRefresh(t *testing.T) {
+	cfg, err := ParseArgs([]string{"--adaptive-refresh", "--refresh", "3s"})
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if cfg.MinRefresh != 3*time.Second || cfg.MaxRefresh != time.Minute {
+		t.Fatalf("expected bounds defaulting to --refresh and 1m, got %s-%s", cfg.MinRefresh, cfg.MaxRefresh)
+	}
+
+	cfg, err = ParseArgs([]string{"--adaptive-refresh", "--min-refresh", "1s", "--max-refresh", "20s"})
+	if err != nil || cfg.MinRefresh != time.Second || cfg.MaxRefresh != 20*time.Second {
+		t.Fatalf("expected explicit bounds, got %v %s-%s", err, cfg.MinRefresh, cfg.MaxRefresh)
+	}
+
+	for _, bad := range [][]string{
+		{"--max-refresh", "20s"},
+		{"--adaptive-refresh", "--min-refresh", "30s", "--max-refresh", "10s"},
+		{"--adaptive-refresh", "--min-refresh", "-1s"},
+	} {
+		if _, err := ParseArgs(bad); err == nil {
+			t.Fatalf("expected %v to fail", bad)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package export
+
+import (
+	"time"
+
+	"slurm_monitor/internal/slurm"
+)
+
+// SchemaVersion is bumped only for breaking changes (renamed or removed
+// fields, changed units). New fields are added without a bump so consumers
+// should ignore keys they do not know.
+const SchemaVersion = 1
+
+// Document is the stable, machine-readable form of one snapshot. Field names
+// and units are part of the public contract for --once --format output.
+type Document struct {
+	SchemaVersion int         `json:"schema_version"`
+	Source        string      `json:"source"`
+	SlurmVersion  string      `json:"slurm_version"`
+	CollectedAt   time.Time   `json:"collected_at"`
+	Sections      []Section   `json:"sections"`
+	Totals        Totals      `json:"totals"`
+	GPUTypes      []GPUType   `json:"gpu_types"`
+	Nodes         []Node      `json:"nodes"`
+	Partitions    []Partition `json:"partitions"`
+	Queue         Queue       `json:"queue"`
+	Users         []User      `json:"users"`
+	Jobs          []Job       `json:"jobs"`
+}
+
+// Section reports whether one data source is fresh, stale (carried from
+// updated_at after a failed collection) or failed (no data).
+type Section struct {
+	Name      string     `json:"name"`
+	State     string     `json:"state"`
+	UpdatedAt *time.Time `json:"updated_at"`
+	Error     string     `json:"error"`
+}
+
+type Totals struct {
+	CPUAlloc   int `json:"cpu_alloc"`
+	CPUTotal   int `json:"cpu_total"`
+	MemAllocMB int `json:"mem_alloc_mb"`
+	MemTotalMB int `json:"mem_total_mb"`
+	GPUAlloc   int `json:"gpu_alloc"`
+	GPUTotal   int `json:"gpu_total"`
+}
+
+// GPUType is one GPU model's node capacity and queue demand. Type is "" for
+// GPUs and requests that name no model.
+type GPUType struct {
+	Type        string `json:"type"`
+	Alloc       int    `json:"alloc"`
+	Total       int    `json:"total"`
+	RunningGPU  int    `json:"running_gpu"`
+	PendingGPU  int    `json:"pending_gpu"`
+	RunningJobs int    `json:"running_jobs"`
+	PendingJobs int    `json:"pending_jobs"`
+}
+
+// GPUDemand is one user's running and pending GPUs of one model.
+type GPUDemand struct {
+	Type        string `json:"type"`
+	RunningGPU  int    `json:"running_gpu"`
+	PendingGPU  int    `json:"pending_gpu"`
+	RunningJobs int    `json:"running_jobs"`
+	PendingJobs int    `json:"pending_jobs"`
+}
+
+// Node utilization fields are null when Slurm did not report the metric.
+type Node struct {
+	Name       string   `json:"name"`
+	State      string   `json:"state"`
+	Partition  string   `json:"partition"`
+	CPUAlloc   int      `json:"cpu_alloc"`
+	CPUTotal   int      `json:"cpu_total"`
+	CPUUtilPct *float64 `json:"cpu_util_pct"`
+	MemAllocMB int      `json:"mem_alloc_mb"`
+	MemTotalMB int      `json:"mem_total_mb"`
+	MemUtilPct *float64 `json:"mem_util_pct"`
+	GPUAlloc   int      `json:"gpu_alloc"`
+	GPUTotal   int      `json:"gpu_total"`
+	GPUUtilPct *float64 `json:"gpu_alloc_pct"`
+}
+
+// Partition capacity is summed from the nodes that list the partition, so a
+// node in several partitions counts in each; shared_nodes says how many. Idle
+// CPUs only count on nodes that can take work. MaxTimeSeconds is null when
+// the limit is UNLIMITED.
+type Partition struct {
+	Name           string `json:"name"`
+	State          string `json:"state"`
+	Default        bool   `json:"default"`
+	Nodes          string `json:"nodes"`
+	TotalNodes     int    `json:"total_nodes"`
+	SharedNodes    int    `json:"shared_nodes"`
+	CPUAlloc       int    `json:"cpu_alloc"`
+	CPUIdle        int    `json:"cpu_idle"`
+	CPUTotal       int    `json:"cpu_total"`
+	MemAllocMB     int    `json:"mem_alloc_mb"`
+	MemTotalMB     int    `json:"mem_total_mb"`
+	GPUAlloc       int    `json:"gpu_alloc"`
+	GPUTotal       int    `json:"gpu_total"`
+	MaxTimeSeconds *int64 `json:"max_time_seconds"`
+}
+
+type Queue struct {
+	Running        int             `json:"running"`
+	Pending        int             `json:"pending"`
+	Other          int             `json:"other"`
+	Total          int             `json:"total"`
+	RunningCPUJobs int             `json:"running_cpu_jobs"`
+	RunningGPUJobs int             `json:"running_gpu_jobs"`
+	PendingCPUJobs int             `json:"pending_cpu_jobs"`
+	PendingGPUJobs int             `json:"pending_gpu_jobs"`
+	Resources      Resources       `json:"resources"`
+	ByState        []StateCount    `json:"by_state"`
+	ByPartition    []PartitionLoad `json:"by_partition"`
+	ByJobName      []NameCount     `json:"by_job_name"`
+	PendingCause   []NameCount     `json:"pending_cause"`
+}
+
+type Resources struct {
+	RunningCPU   int `json:"running_cpu"`
+	PendingCPU   int `json:"pending_cpu"`
+	RunningMemMB int `json:"running_mem_mb"`
+	PendingMemMB int `json:"pending_mem_mb"`
+	RunningGPU   int `json:"running_gpu"`
+	PendingGPU   int `json:"pending_gpu"`
+}
+
+type StateCount struct {
+	State string `json:"state"`
+	Count int    `json:"count"`
+}
+
+// PartitionLoad counts jobs per partition. A job submitted to several
+// partitions counts in each and in each one's Shared, so partition counts do
+// not add up to the queue total when Shared is non-zero.
+type PartitionLoad struct {
+	Partition string `json:"partition"`
+	Running   int    `json:"running"`
+	Pending   int    `json:"pending"`
+	Other     int    `json:"other"`
+	Shared    int    `json:"shared"`
+}
+
+type NameCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type User struct {
+	User           string      `json:"user"`
+	Running        int         `json:"running"`
+	Pending        int         `json:"pending"`
+	RunningCPU     int         `json:"running_cpu"`
+	RunningGPU     int         `json:"running_gpu"`
+	RunningCPUJobs int         `json:"running_cpu_jobs"`
+	RunningGPUJobs int         `json:"running_gpu_jobs"`
+	PendingCPUJobs int         `json:"pending_cpu_jobs"`
+	PendingGPUJobs int         `json:"pending_gpu_jobs"`
+	PendingCPU     int         `json:"pending_cpu"`
+	PendingMemMB   int         `json:"pending_mem_mb"`
+	PendingGPU     int         `json:"pending_gpu"`
+	GPUTypes       []GPUDemand `json:"gpu_types"`
+}
+
+// Job times are RFC 3339 strings, or null when Slurm reported none.
+// TimeLimitSeconds is null for UNLIMITED jobs. Tasks is how many array tasks
+// the row stands for: one, or more for the range past the expansion cap,
+// whose cpus, mem_mb and gpus are per task.
+type Job struct {
+	ID               string     `json:"id"`
+	ArrayJobID       string     `json:"array_job_id"`
+	ArrayTaskID      string     `json:"array_task_id"`
+	Tasks            int        `json:"tasks"`
+	State            string     `json:"state"`
+	User             string     `json:"user"`
+	Partition        string     `json:"partition"`
+	Name             string     `json:"name"`
+	CPUs             int        `json:"cpus"`
+	MemMB            int        `json:"mem_mb"`
+	GPUs             int        `json:"gpus"`
+	Reason           string     `json:"reason"`
+	NodeList         string     `json:"node_list"`
+	SubmitTime       *time.Time `json:"submit_time"`
+	StartTime        *time.Time `json:"start_time"`
+	TimeLimitSeconds *int64     `json:"time_limit_seconds"`
+}
+
+// FromSnapshot converts a collected snapshot into the export schema. Nodes and
+// users keep the snapshot's order, which callers may have re-sorted.
+func FromSnapshot(source string, snap slurm.Snapshot) Document {
+	totals := snap.Totals()
+	q := snap.Queue
+
+	doc := Document{
+		SchemaVersion: SchemaVersion,
+		Source:        source,
+		SlurmVersion:  snap.SlurmVersion,
+		CollectedAt:   snap.CollectedAt.UTC(),
+		Totals: Totals{
+			CPUAlloc:   totals.CPUAlloc,
+			CPUTotal:   totals.CPUTotal,
+			MemAllocMB: totals.MemAllocMB,
+			MemTotalMB: totals.MemTotalMB,
+			GPUAlloc:   totals.GPUAlloc,
+			GPUTotal:   totals.GPUTotal,
+		},
+		Nodes:      make([]Node, 0, len(snap.Nodes)),
+		Partitions: make([]Partition, 0, len(snap.Partitions)),
+		Queue: Queue{
+			Running:        q.Running,
+			Pending:        q.Pending,
+			Other:          q.Other,
+			Total:          q.Running + q.Pending + q.Other,
+			RunningCPUJobs: q.RunningCPUJobs,
+			RunningGPUJobs: q.RunningGPUJobs,
+			PendingCPUJobs: q.PendingCPUJobs,
+			PendingGPUJobs: q.PendingGPUJobs,
+			Resources: Resources{
+				RunningCPU:   q.ResourceLoad.RunningCPU,
+				PendingCPU:   q.ResourceLoad.PendingCPU,
+				RunningMemMB: q.ResourceLoad.RunningMemMB,
+				PendingMemMB: q.ResourceLoad.PendingMemMB,
+				RunningGPU:   q.ResourceLoad.RunningGPU,
+				PendingGPU:   q.ResourceLoad.PendingGPU,
+			},
+			ByState:      make([]StateCount, 0, len(q.ByState)),
+			ByPartition:  make([]PartitionLoad, 0, len(q.ByPartition)),
+			ByJobName:    convertNameCounts(q.ByJobName),
+			PendingCause: convertNameCounts(q.PendingCause),
+		},
+		Sections: make([]Section, 0, len(snap.Sections)),
+		GPUTypes: make([]GPUType, 0),
+		Users:    make([]User, 0, len(snap.Users)),
+		Jobs:     make([]Job, 0, len(snap.Jobs)),
+	}
+
+	for _, st := range snap.Sections {
+		doc.Sections = append(doc.Sections, Section{
+			Name:      string(st.Section),
+			State:     string(st.State),
+			UpdatedAt: optionalTime(st.UpdatedAt),
+			Error:     st.Error,
+		})
+	}
+	for _, n := range snap.Nodes {
+		doc.Nodes = append(doc.Nodes, Node{
+			Name:       n.Name,
+			State:      n.State,
+			Partition:  n.Partition,
+			CPUAlloc:   n.CPUAlloc,
+			CPUTotal:   n.CPUTotal,
+			CPUUtilPct: optionalPct(n.CPUUtil, n.HasCPU),
+			MemAllocMB: n.MemAllocMB,
+			MemTotalMB: n.MemTotalMB,
+			MemUtilPct: optionalPct(n.MemUtil, n.HasMem),
+			GPUAlloc:   n.GPUAlloc,
+			GPUTotal:   n.GPUTotal,
+			GPUUtilPct: optionalPct(n.GPUUtil, n.HasGPU),
+		})
+	}
+	for _, s := range q.ByState {
+		doc.Queue.ByState = append(doc.Queue.ByState, StateCount{State: s.State, Count: s.Count})
+	}
+	for _, p := range q.ByPartition {
+		doc.Queue.ByPartition = append(doc.Queue.ByPartition, PartitionLoad{
+			Partition: p.Partition,
+			Running:   p.Running,
+			Pending:   p.Pending,
+			Other:     p.Other,
+			Shared:    p.Shared,
+		})
+	}
+	for _, p := range snap.Partitions {
+		doc.Partitions = append(doc.Partitions, Partition{
+			Name:           p.Name,
+			State:          p.State,
+			Default:        p.Default,
+			Nodes:          p.Nodes,
+			TotalNodes:     p.TotalNodes,
+			SharedNodes:    p.SharedNodes,
+			CPUAlloc:       p.AllocCPUs,
+			CPUIdle:        p.IdleCPUs,
+			CPUTotal:       p.TotalCPUs,
+			MemAllocMB:     p.AllocMemMB,
+			MemTotalMB:     p.TotalMemMB,
+			GPUAlloc:       p.AllocGPUs,
+			GPUTotal:       p.TotalGPUs,
+			MaxTimeSeconds: optionalSeconds(p.MaxTime),
+		})
+	}
+
+	for _, st := range snap.GPUTypeStats() {
+		doc.GPUTypes = append(doc.GPUTypes, GPUType(st))
+	}
+
+	for _, u := range snap.Users {
+		gpuTypes := make([]GPUDemand, 0, len(u.ByGPUType))
+		for _, l := range u.ByGPUType {
+			gpuTypes = append(gpuTypes, GPUDemand(l))
+		}
+		doc.Users = append(doc.Users, User{
+			User:           u.User,
+			Running:        u.Running,
+			Pending:        u.Pending,
+			RunningCPU:     u.RunningCPU,
+			RunningGPU:     u.RunningGPU,
+			RunningCPUJobs: u.RunningCPUJobs,
+			RunningGPUJobs: u.RunningGPUJobs,
+			PendingCPUJobs: u.PendingCPUJobs,
+			PendingGPUJobs: u.PendingGPUJobs,
+			PendingCPU:     u.PendingCPU,
+			PendingMemMB:   u.PendingMemMB,
+			PendingGPU:     u.PendingGPU,
+			GPUTypes:       gpuTypes,
+		})
+	}
+
+	for _, j := range snap.Jobs {
+		doc.Jobs = append(doc.Jobs, Job{
+			ID:               j.ID,
+			ArrayJobID:       j.ArrayJobID,
+			ArrayTaskID:      j.ArrayTaskID,
+			Tasks:            j.Count(),
+			State:            j.State,
+			User:             j.User,
+			Partition:        j.Partition,
+			Name:             j.Name,
+			CPUs:             j.CPUs,
+			MemMB:            j.MemMB,
+			GPUs:             j.GPUs,
+			Reason:           j.Reason,
+			NodeList:         j.NodeList,
+			SubmitTime:       optionalTime(j.SubmitTime),
+			StartTime:        optionalTime(j.StartTime),
+			TimeLimitSeconds: optionalSeconds(j.TimeLimit),
+		})
+	}
+
+	return doc
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+func optionalSeconds(d time.Duration) *int64 {
+	if d <= 0 {
+		return nil
+	}
+	secs := int64(d / time.Second)
+	return &secs
+}
+
+func convertNameCounts(in []slurm.NameCount) []NameCount {
+	out := make([]NameCount, 0, len(in))
+	for _, c := range in {
+		out = append(out, NameCount{Name: c.Name, Count: c.Count})
+	}
+	return out
+}
+
+func optionalPct(v float64, ok bool) *float64 {
+	if !ok {
+		return nil
+	}
+	// Round to one decimal so output matches the TUI and stays diff-friendly.
+	rounded := float64(int64(v*10+0.5)) / 10
+	return &rounded
+}
@@ -0,0 +1,235 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatYAML Format = "yaml"
+)
+
+// Write serializes doc in the requested format.
+func Write(w io.Writer, format Format, doc Document) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	case FormatCSV:
+		return writeCSV(w, doc)
+	case FormatYAML:
+		return writeYAML(w, doc)
+	default:
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// writeCSV emits a long-form table (section,name,field,value) so every part of
+// the document fits one header and new fields never shift existing columns.
+func writeCSV(w io.Writer, doc Document) error {
+	cw := csv.NewWriter(w)
+	rows := [][]string{{"section", "name", "field", "value"}}
+
+	appendStruct := func(section, name string, v any) {
+		for _, f := range scalarFields(reflect.ValueOf(v)) {
+			rows = append(rows, []string{section, name, f.name, f.value})
+		}
+	}
+
+	rows = append(rows,
+		[]string{"meta", "", "schema_version", strconv.Itoa(doc.SchemaVersion)},
+		[]string{"meta", "", "source", doc.Source},
+		[]string{"meta", "", "slurm_version", doc.SlurmVersion},
+		[]string{"meta", "", "collected_at", formatTime(doc.CollectedAt)},
+	)
+	for _, st := range doc.Sections {
+		appendStruct("section", st.Name, st)
+	}
+	appendStruct("totals", "", doc.Totals)
+	for _, g := range doc.GPUTypes {
+		appendStruct("gpu_type", g.Type, g)
+	}
+	for _, n := range doc.Nodes {
+		appendStruct("node", n.Name, n)
+	}
+	for _, p := range doc.Partitions {
+		appendStruct("partition", p.Name, p)
+	}
+	appendStruct("queue", "", doc.Queue)
+	appendStruct("queue_resources", "", doc.Queue.Resources)
+	for _, s := range doc.Queue.ByState {
+		rows = append(rows, []string{"queue_state", s.State, "count", strconv.Itoa(s.Count)})
+	}
+	for _, p := range doc.Queue.ByPartition {
+		appendStruct("queue_partition", p.Partition, p)
+	}
+	for _, c := range doc.Queue.ByJobName {
+		rows = append(rows, []string{"queue_job_name", c.Name, "count", strconv.Itoa(c.Count)})
+	}
+	for _, c := range doc.Queue.PendingCause {
+		rows = append(rows, []string{"queue_pending_cause", c.Name, "count", strconv.Itoa(c.Count)})
+	}
+	for _, u := range doc.Users {
+		appendStruct("user", u.User, u)
+		for _, g := range u.GPUTypes {
+			appendStruct("user_gpu_type", u.User+"/"+g.Type, g)
+		}
+	}
+	for _, j := range doc.Jobs {
+		appendStruct("job", j.ID, j)
+	}
+
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+type scalarField struct {
+	name  string
+	value string
+}
+
+// scalarFields lists the non-container fields of a struct by JSON name. Nested
+// structs and slices are skipped; writeCSV emits them as their own sections.
+func scalarFields(v reflect.Value) []scalarField {
+	t := v.Type()
+	out := make([]scalarField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonFieldName(t.Field(i))
+		if name == "" {
+			continue
+		}
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Slice || (fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Time{})) {
+			continue
+		}
+		out = append(out, scalarField{name: name, value: scalarString(fv)})
+	}
+	return out
+}
+
+func scalarString(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return formatTime(t)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Float64, reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+func jsonFieldName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return f.Name
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// writeYAML renders the document as block-style YAML using the same field
+// names as the JSON output. The schema only holds scalars, structs and slices,
+// so a small encoder avoids pulling in a YAML dependency.
+func writeYAML(w io.Writer, doc Document) error {
+	var b strings.Builder
+	writeYAMLStruct(&b, reflect.ValueOf(doc), 0, false)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeYAMLStruct(b *strings.Builder, v reflect.Value, indent int, firstInList bool) {
+	t := v.Type()
+	first := true
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonFieldName(t.Field(i))
+		if name == "" {
+			continue
+		}
+		pad := strings.Repeat("  ", indent)
+		if first && firstInList {
+			// The list marker already occupies this line's indentation.
+			pad = ""
+		}
+		first = false
+		writeYAMLField(b, pad, name, v.Field(i), indent)
+	}
+}
+
+func writeYAMLField(b *strings.Builder, pad, name string, v reflect.Value, indent int) {
+	switch {
+	case v.Kind() == reflect.Slice:
+		if v.Len() == 0 {
+			fmt.Fprintf(b, "%s%s: []\n", pad, name)
+			return
+		}
+		fmt.Fprintf(b, "%s%s:\n", pad, name)
+		itemPad := strings.Repeat("  ", indent+1)
+		for i := 0; i < v.Len(); i++ {
+			item := v.Index(i)
+			if item.Kind() == reflect.Struct && item.Type() != reflect.TypeOf(time.Time{}) {
+				b.WriteString(itemPad + "- ")
+				writeYAMLStruct(b, item, indent+2, true)
+				continue
+			}
+			fmt.Fprintf(b, "%s- %s\n", itemPad, yamlScalar(item))
+		}
+	case v.Kind() == reflect.Struct && v.Type() != reflect.TypeOf(time.Time{}):
+		fmt.Fprintf(b, "%s%s:\n", pad, name)
+		writeYAMLStruct(b, v, indent+1, false)
+	default:
+		fmt.Fprintf(b, "%s%s: %s\n", pad, name, yamlScalar(v))
+	}
+}
+
+func yamlScalar(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return "null"
+	}
+	s := scalarString(v)
+	if v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.String && v.Type() != reflect.TypeOf(time.Time{}) {
+		return s
+	}
+	// Quote every string so values like "N/A", "yes" or "1e3" stay strings.
+	return strconv.Quote(s)
+}
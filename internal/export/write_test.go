@@ -0,0 +1,188 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"slurm_monitor/internal/slurm"
+)
+
+func TestFromSnapshotCarriesQueueBreakdownsAndTotals(t *testing.T) {
+	doc := FromSnapshot("fake", sampleSnapshot())
+	if doc.SchemaVersion != SchemaVersion {
+		t.Fatalf("expected schema version %d, got %d", SchemaVersion, doc.SchemaVersion)
+	}
+	if doc.Totals.CPUAlloc != 32 || doc.Totals.GPUTotal != 4 {
+		t.Fatalf("unexpected totals: %+v", doc.Totals)
+	}
+	if doc.Queue.Total != 3 {
+		t.Fatalf("expected queue total 3, got %d", doc.Queue.Total)
+	}
+	if len(doc.Queue.PendingCause) != 1 || doc.Queue.PendingCause[0].Name != "Priority" {
+		t.Fatalf("expected pending cause to be exported, got %+v", doc.Queue.PendingCause)
+	}
+	if len(doc.Users) != 2 || doc.Users[0].User != "bob" {
+		t.Fatalf("expected users in snapshot order, got %+v", doc.Users)
+	}
+	if doc.Nodes[0].GPUUtilPct == nil || *doc.Nodes[0].GPUUtilPct != 50 {
+		t.Fatalf("expected gpu alloc pct 50, got %v", doc.Nodes[0].GPUUtilPct)
+	}
+	if doc.Nodes[0].MemUtilPct != nil {
+		t.Fatalf("expected missing mem util to export as null")
+	}
+	if len(doc.GPUTypes) != 1 || doc.GPUTypes[0] != (GPUType{Type: "a100", Alloc: 2, Total: 4, RunningGPU: 1, RunningJobs: 1}) {
+		t.Fatalf("expected per-model gpu capacity and demand, got %+v", doc.GPUTypes)
+	}
+	if len(doc.Users[0].GPUTypes) != 0 || len(doc.Users[1].GPUTypes) != 1 || doc.Users[1].GPUTypes[0].RunningGPU != 1 {
+		t.Fatalf("expected per-user gpu demand, got %+v", doc.Users)
+	}
+	if len(doc.Partitions) != 2 || doc.Partitions[0].CPUIdle != 32 || doc.Partitions[0].SharedNodes != 1 ||
+		doc.Partitions[0].MaxTimeSeconds == nil || *doc.Partitions[0].MaxTimeSeconds != 172800 || doc.Partitions[1].MaxTimeSeconds != nil {
+		t.Fatalf("expected partition capacity to be exported, got %+v", doc.Partitions)
+	}
+	if len(doc.Queue.ByPartition) != 1 || doc.Queue.ByPartition[0].Shared != 1 {
+		t.Fatalf("expected multi-partition job counts, got %+v", doc.Queue.ByPartition)
+	}
+	if len(doc.Jobs) != 2 || doc.Jobs[0].Tasks != 1 || doc.Jobs[1].Tasks != 30000 {
+		t.Fatalf("expected each job's task count, got %+v", doc.Jobs)
+	}
+	if len(doc.Sections) != 2 || doc.Sections[1].State != "stale" || doc.Sections[1].UpdatedAt == nil || doc.Sections[1].Error != "squeue timed out" {
+		t.Fatalf("expected section statuses to be exported, got %+v", doc.Sections)
+	}
+}
+
+func TestWriteJSONUsesStableFieldNames(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatJSON, FromSnapshot("fake", sampleSnapshot())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("expected valid json, got %v\n%s", err, buf.String())
+	}
+	for _, key := range []string{"schema_version", "source", "slurm_version", "collected_at", "sections", "totals", "gpu_types", "nodes", "partitions", "queue", "users"} {
+		if _, ok := decoded[key]; !ok {
+			t.Fatalf("expected top-level key %q in %s", key, buf.String())
+		}
+	}
+	if !strings.Contains(buf.String(), `"mem_util_pct": null`) {
+		t.Fatalf("expected unavailable metric as null, got %s", buf.String())
+	}
+}
+
+func TestWriteCSVUsesLongFormRows(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatCSV, FromSnapshot("fake", sampleSnapshot())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("expected valid csv, got %v", err)
+	}
+	if strings.Join(rows[0], ",") != "section,name,field,value" {
+		t.Fatalf("unexpected csv header: %v", rows[0])
+	}
+	want := map[string]bool{
+		"meta,,schema_version,1":                 false,
+		"meta,,slurm_version,23.02.7":            false,
+		"node,node001,cpu_alloc,32":              false,
+		"queue_pending_cause,Priority,count,1":   false,
+		"user,alice,running_gpu,1":               false,
+		"queue_partition,train,pending,1":        false,
+		"queue_partition,train,shared,1":         false,
+		"partition,train,cpu_idle,32":            false,
+		"partition,debug,max_time_seconds,":      false,
+		"gpu_type,a100,total,4":                  false,
+		"user_gpu_type,alice/a100,running_gpu,1": false,
+		"section,queue,state,stale":              false,
+		"job,1002_[65537-95536],tasks,30000":     false,
+	}
+	for _, row := range rows {
+		key := strings.Join(row, ",")
+		if _, ok := want[key]; ok {
+			want[key] = true
+		}
+	}
+	for key, seen := range want {
+		if !seen {
+			t.Fatalf("expected csv row %q", key)
+		}
+	}
+}
+
+func TestWriteYAMLNestsListsAndQuotesStrings(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, FormatYAML, FromSnapshot("fake", sampleSnapshot())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"schema_version: 1\n",
+		"source: \"fake\"\n",
+		"nodes:\n  - name: \"node001\"\n    state: \"MIXED\"\n",
+		"  mem_util_pct: null\n",
+		"queue:\n  running: 1\n",
+		"  pending_cause:\n    - name: \"Priority\"\n      count: 1\n",
+		"  by_job_name: []\n",
+		"partitions:\n  - name: \"train\"\n    state: \"UP\"\n    default: true\n",
+		"    max_time_seconds: 172800\n",
+		"      other: 1\n      shared: 1\n",
+		"jobs:\n  - id: \"1001_2\"\n    array_job_id: \"1001\"\n    array_task_id: \"2\"\n    tasks: 1\n",
+		"    submit_time: \"2026-02-25T09:00:00Z\"\n    start_time: null\n    time_limit_seconds: 7200\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected yaml to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteRejectsUnknownFormat(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, Format("xml"), Document{}); err == nil {
+		t.Fatalf("expected unsupported format error")
+	}
+}
+
+func sampleSnapshot() slurm.Snapshot {
+	return slurm.Snapshot{
+		CollectedAt:  time.Date(2026, 2, 25, 10, 0, 0, 0, time.UTC),
+		SlurmVersion: "23.02.7",
+		Sections: []slurm.SectionStatus{
+			{Section: slurm.SectionNodes, State: slurm.SectionFresh, UpdatedAt: time.Date(2026, 2, 25, 10, 0, 0, 0, time.UTC)},
+			{Section: slurm.SectionQueue, State: slurm.SectionStale, UpdatedAt: time.Date(2026, 2, 25, 9, 59, 0, 0, time.UTC), Error: "squeue timed out"},
+		},
+		Nodes: []slurm.Node{
+			{Name: "node001", State: "MIXED", Partition: "train", CPUAlloc: 32, CPUTotal: 64, CPUUtil: 25, HasCPU: true, MemAllocMB: 128000, MemTotalMB: 256000, GPUAlloc: 2, GPUTotal: 4, GPUUtil: 50, HasGPU: true,
+				GPUTypes: []slurm.GPUTypeUsage{{Type: "a100", Alloc: 2, Total: 4}}},
+		},
+		Partitions: []slurm.Partition{
+			{Name: "train", State: "UP", Default: true, Nodes: "node001", TotalNodes: 1, SharedNodes: 1, TotalCPUs: 64, AllocCPUs: 32, IdleCPUs: 32,
+				TotalMemMB: 256000, AllocMemMB: 128000, TotalGPUs: 4, AllocGPUs: 2, MaxTime: 48 * time.Hour},
+			{Name: "debug", State: "UP"},
+		},
+		Queue: slurm.QueueSummary{
+			Running:        1,
+			Pending:        1,
+			Other:          1,
+			RunningGPUJobs: 1,
+			PendingCPUJobs: 1,
+			ByState:        []slurm.StateCount{{State: "RUNNING", Count: 1}, {State: "PENDING", Count: 1}, {State: "FAILED", Count: 1}},
+			ByPartition:    []slurm.PartitionCount{{Partition: "train", Running: 1, Pending: 1, Other: 1, Shared: 1}},
+			PendingCause:   []slurm.NameCount{{Name: "Priority", Count: 1}},
+			ResourceLoad:   slurm.ResourceTotals{RunningCPU: 8, RunningGPU: 1, PendingCPU: 4},
+			ByGPUType:      []slurm.GPUTypeLoad{{Type: "a100", RunningGPU: 1, RunningJobs: 1}},
+		},
+		Jobs: []slurm.Job{
+			{ID: "1001_2", ArrayJobID: "1001", ArrayTaskID: "2", State: "RUNNING", User: "alice", Partition: "train", Name: "jobA", CPUs: 8, GPUs: 1, TimeLimit: 2 * time.Hour, SubmitTime: time.Date(2026, 2, 25, 9, 0, 0, 0, time.UTC)},
+			{ID: "1002_[65537-95536]", ArrayJobID: "1002", ArrayTaskID: "[65537-95536]", State: "PENDING", User: "bob", Partition: "train", Name: "sweep", CPUs: 1, Tasks: 30000},
+		},
+		Users: []slurm.UserSummary{
+			{User: "bob", Pending: 1, PendingCPUJobs: 1, PendingCPU: 4},
+			{User: "alice", Running: 1, RunningCPU: 8, RunningGPU: 1, RunningGPUJobs: 1,
+				ByGPUType: []slurm.GPUTypeLoad{{Type: "a100", RunningGPU: 1, RunningJobs: 1}}},
+		},
+	}
+}
@@ -0,0 +1,130 @@
+// Package history keeps a bounded in-memory trend of cluster snapshots so
+// the TUI can draw sparklines without retaining full snapshots.
+package history
+
+import (
+	"time"
+
+	"slurm_monitor/internal/slurm"
+)
+
+// maxSamples caps the ring regardless of window and refresh so a long window
+// with a short refresh cannot grow without bound.
+const maxSamples = 4096
+
+// Sample is the part of a snapshot that trends are drawn from.
+type Sample struct {
+	At            time.Time
+	Totals        slurm.Aggregate
+	PendingCPU    int
+	PendingGPU    int
+	HeldGPUByUser map[string]int
+}
+
+// Ring is a fixed-capacity ring buffer of samples covering at most window.
+// It is not safe for concurrent use.
+type Ring struct {
+	window time.Duration
+	buf    []Sample
+	start  int
+	n      int
+}
+
+// NewRing sizes the buffer for window at the given refresh interval. It
+// returns nil when window is not positive, which disables history.
+func NewRing(window, refresh time.Duration) *Ring {
+	if window <= 0 {
+		return nil
+	}
+	capacity := maxSamples
+	if refresh > 0 {
+		capacity = min(maxSamples, max(2, int(window/refresh)+1))
+	}
+	return &Ring{window: window, buf: make([]Sample, capacity)}
+}
+
+// Window is the time span the ring keeps.
+func (r *Ring) Window() time.Duration {
+	if r == nil {
+		return 0
+	}
+	return r.window
+}
+
+// Add records a snapshot and drops samples older than the window, measured
+// from the snapshot's collection time. A snapshot older than the newest
+// sample, as when a replay seeks backwards, starts the ring over.
+func (r *Ring) Add(snap slurm.Snapshot) {
+	if r == nil {
+		return
+	}
+	if r.n > 0 && snap.CollectedAt.Before(r.buf[(r.start+r.n-1)%len(r.buf)].At) {
+		clear(r.buf)
+		r.start, r.n = 0, 0
+	}
+	held := make(map[string]int, len(snap.Users))
+	for _, u := range snap.Users {
+		if u.RunningGPU > 0 {
+			held[u.User] = u.RunningGPU
+		}
+	}
+	s := Sample{
+		At:            snap.CollectedAt,
+		Totals:        snap.Totals(),
+		PendingCPU:    snap.Queue.PendingCPUJobs,
+		PendingGPU:    snap.Queue.PendingGPUJobs,
+		HeldGPUByUser: held,
+	}
+
+	if r.n == len(r.buf) {
+		r.buf[r.start] = s
+		r.start = (r.start + 1) % len(r.buf)
+	} else {
+		r.buf[(r.start+r.n)%len(r.buf)] = s
+		r.n++
+	}
+
+	cutoff := s.At.Add(-r.window)
+	for r.n > 1 && r.buf[r.start].At.Before(cutoff) {
+		r.buf[r.start] = Sample{}
+		r.start = (r.start + 1) % len(r.buf)
+		r.n--
+	}
+}
+
+// Len is the number of samples held.
+func (r *Ring) Len() int {
+	if r == nil {
+		return 0
+	}
+	return r.n
+}
+
+// Series returns f applied to each sample, oldest first.
+func (r *Ring) Series(f func(Sample) float64) []float64 {
+	if r == nil || r.n == 0 {
+		return nil
+	}
+	out := make([]float64, r.n)
+	for i := 0; i < r.n; i++ {
+		out[i] = f(r.buf[(r.start+i)%len(r.buf)])
+	}
+	return out
+}
+
+// Percent returns used/total as a percentage, or 0 when total is 0.
+func Percent(used, total int) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(used) / float64(total) * 100.0
+}
+
+// Span is the time between the oldest and newest samples.
+func (r *Ring) Span() time.Duration {
+	if r == nil || r.n < 2 {
+		return 0
+	}
+	newest := r.buf[(r.start+r.n-1)%len(r.buf)]
+	return newest.At.Sub(r.buf[r.start].At)
+}
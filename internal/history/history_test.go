@@ -0,0 +1,69 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"slurm_monitor/internal/slurm"
+)
+
+func snapAt(at time.Time, cpuAlloc, pending int) slurm.Snapshot {
+	return slurm.Snapshot{
+		Nodes:       []slurm.Node{{Name: "n1", CPUAlloc: cpuAlloc, CPUTotal: 100}},
+		Queue:       slurm.QueueSummary{PendingGPUJobs: pending},
+		Users:       []slurm.UserSummary{{User: "alice", RunningGPU: cpuAlloc / 10}},
+		CollectedAt: at,
+	}
+}
+
+func TestRingDropsSamplesOutsideWindow(t *testing.T) {
+	r := NewRing(time.Minute, 10*time.Second)
+	base := time.Date(2026, 1, 1, 3, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		r.Add(snapAt(base.Add(time.Duration(i)*10*time.Second), i*10, i))
+	}
+	if r.Len() != 7 {
+		t.Fatalf("expected samples from the last minute only, got %d", r.Len())
+	}
+	if r.Span() != time.Minute {
+		t.Fatalf("expected a one-minute span, got %s", r.Span())
+	}
+	got := r.Series(func(s Sample) float64 { return float64(s.PendingGPU) })
+	if got[0] != 3 || got[len(got)-1] != 9 {
+		t.Fatalf("expected oldest-first pending series 3..9, got %v", got)
+	}
+	cpu := r.Series(func(s Sample) float64 { return Percent(s.Totals.CPUAlloc, s.Totals.CPUTotal) })
+	if cpu[len(cpu)-1] != 90 {
+		t.Fatalf("expected latest cpu allocation 90%%, got %v", cpu)
+	}
+	held := r.Series(func(s Sample) float64 { return float64(s.HeldGPUByUser["alice"]) })
+	if held[len(held)-1] != 9 {
+		t.Fatalf("expected per-user held gpu trend, got %v", held)
+	}
+}
+
+func TestRingCapacityBoundsSamples(t *testing.T) {
+	r := NewRing(time.Hour, 30*time.Minute)
+	base := time.Date(2026, 1, 1, 3, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		r.Add(snapAt(base.Add(time.Duration(i)*time.Second), i, i))
+	}
+	if r.Len() != 3 {
+		t.Fatalf("expected ring capped at window/refresh+1 samples, got %d", r.Len())
+	}
+	got := r.Series(func(s Sample) float64 { return float64(s.PendingGPU) })
+	if got[0] != 7 || got[2] != 9 {
+		t.Fatalf("expected the newest samples to survive, got %v", got)
+	}
+}
+
+func TestNewRingDisabled(t *testing.T) {
+	r := NewRing(0, time.Second)
+	if r != nil {
+		t.Fatalf("expected nil ring for zero window")
+	}
+	r.Add(slurm.Snapshot{})
+	if r.Len() != 0 || r.Series(func(Sample) float64 { return 0 }) != nil {
+		t.Fatalf("expected nil ring to be inert")
+	}
+}
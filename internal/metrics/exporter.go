@@ -0,0 +1,302 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"slurm_monitor/internal/monitor"
+	"slurm_monitor/internal/slurm"
+)
+
+const bytesPerMB = 1024 * 1024
+
+// allStates lists every monitor state so the state gauge always exposes one
+// series per state and alerts can match on value instead of series presence.
+var allStates = []monitor.State{
+	monitor.StateConnected,
+	monitor.StateReconnecting,
+	monitor.StateDisconnectedRecovering,
+	monitor.StateDisconnected,
+}
+
+// Exporter keeps the latest monitor update and renders it in the Prometheus
+// text exposition format. It is safe for concurrent use.
+type Exporter struct {
+	source string
+
+	mu          sync.RWMutex
+	snapshot    *slurm.Snapshot
+	state       monitor.State
+	lastSuccess time.Time
+	failures    int
+	updates     int
+	refresh     time.Duration
+	latency     time.Duration
+}
+
+func NewExporter(source string) *Exporter {
+	return &Exporter{
+		source: source,
+		state:  monitor.StateReconnecting,
+	}
+}
+
+// Consume records updates until the channel closes or ctx is cancelled.
+func (e *Exporter) Consume(ctx context.Context, updates <-chan monitor.Update) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			e.Observe(update)
+		}
+	}
+}
+
+// Observe records one monitor update. Failed collections keep the last good
+// snapshot so cluster metrics stay scrapeable while the poller recovers.
+func (e *Exporter) Observe(update monitor.Update) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.updates++
+	e.state = update.State
+	e.failures = update.Failures
+	if !update.LastSuccess.IsZero() {
+		e.lastSuccess = update.LastSuccess
+	}
+	if update.Refresh > 0 {
+		e.refresh, e.latency = update.Refresh, update.Latency
+	}
+	if update.Snapshot != nil {
+		snap := *update.Snapshot
+		e.snapshot = &snap
+	}
+}
+
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = e.Write(w)
+}
+
+// Write renders all metric families for the latest observed state.
+func (e *Exporter) Write(w io.Writer) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	b := &builder{}
+	e.writeHealth(b)
+	if e.snapshot != nil {
+		writeNodes(b, e.snapshot)
+		writeQueue(b, e.snapshot)
+		writeUsers(b, e.snapshot)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (e *Exporter) writeHealth(b *builder) {
+	b.family("slurm_monitor_info", "gauge", "Static exporter information.")
+	b.sample("slurm_monitor_info", labels{{"source", e.source}}, 1)
+
+	up := 0.0
+	if e.state == monitor.StateConnected && e.snapshot != nil {
+		up = 1
+	}
+	b.family("slurm_monitor_up", "gauge", "Whether the last collection succeeded (1) or not (0).")
+	b.sample("slurm_monitor_up", nil, up)
+
+	b.family("slurm_monitor_state", "gauge", "Current poller connection state; exactly one state is 1.")
+	for _, state := range allStates {
+		v := 0.0
+		if e.state == state {
+			v = 1
+		}
+		b.sample("slurm_monitor_state", labels{{"state", string(state)}}, v)
+	}
+
+	b.family("slurm_monitor_consecutive_failures", "gauge", "Collections that failed in a row since the last success.")
+	b.sample("slurm_monitor_consecutive_failures", nil, float64(e.failures))
+
+	b.family("slurm_monitor_last_success_timestamp_seconds", "gauge", "Unix time of the last successful collection; 0 before the first success.")
+	last := 0.0
+	if !e.lastSuccess.IsZero() {
+		last = float64(e.lastSuccess.UnixMilli()) / 1000
+	}
+	b.sample("slurm_monitor_last_success_timestamp_seconds", nil, last)
+
+	b.family("slurm_monitor_updates_total", "counter", "Poller updates observed, successful or not.")
+	b.sample("slurm_monitor_updates_total", nil, float64(e.updates))
+
+	if e.refresh > 0 {
+		b.family("slurm_monitor_refresh_interval_seconds", "gauge", "Poll interval in effect; follows collection cost with --adaptive-refresh.")
+		b.sample("slurm_monitor_refresh_interval_seconds", nil, e.refresh.Seconds())
+		b.family("slurm_monitor_collect_duration_seconds", "gauge", "How long the last successful collection took.")
+		b.sample("slurm_monitor_collect_duration_seconds", nil, e.latency.Seconds())
+	}
+}
+
+func writeNodes(b *builder, snap *slurm.Snapshot) {
+	type nodeMetric struct {
+		name  string
+		help  string
+		value func(slurm.Node) float64
+	}
+	perNode := []nodeMetric{
+		{"slurm_node_cpu_alloc", "Allocated CPUs per node.", func(n slurm.Node) float64 { return float64(n.CPUAlloc) }},
+		{"slurm_node_cpu_total", "Configured CPUs per node.", func(n slurm.Node) float64 { return float64(n.CPUTotal) }},
+		{"slurm_node_mem_alloc_bytes", "Allocated memory per node.", func(n slurm.Node) float64 { return float64(n.MemAllocMB) * bytesPerMB }},
+		{"slurm_node_mem_total_bytes", "Configured memory per node.", func(n slurm.Node) float64 { return float64(n.MemTotalMB) * bytesPerMB }},
+		{"slurm_node_gpu_alloc", "Allocated GPUs per node.", func(n slurm.Node) float64 { return float64(n.GPUAlloc) }},
+		{"slurm_node_gpu_total", "Configured GPUs per node.", func(n slurm.Node) float64 { return float64(n.GPUTotal) }},
+	}
+	// The gauges carry only the node label so a state change does not start
+	// new series; slurm_node_info holds the labels that change and joins on
+	// node.
+	for _, m := range perNode {
+		b.family(m.name, "gauge", m.help)
+		for _, n := range snap.Nodes {
+			b.sample(m.name, labels{{"node", n.Name}}, m.value(n))
+		}
+	}
+	b.family("slurm_node_info", "gauge", "Partition and state per node; always 1.")
+	for _, n := range snap.Nodes {
+		b.sample("slurm_node_info", labels{{"node", n.Name}, {"partition", n.Partition}, {"state", n.State}}, 1)
+	}
+
+	stateCounts := make(map[string]int)
+	for _, n := range snap.Nodes {
+		stateCounts[n.State]++
+	}
+	b.family("slurm_nodes", "gauge", "Nodes per Slurm node state.")
+	for _, state := range sortedKeys(stateCounts) {
+		b.sample("slurm_nodes", labels{{"state", state}}, float64(stateCounts[state]))
+	}
+
+	t := snap.Totals()
+	b.family("slurm_cluster_cpu_alloc", "gauge", "Allocated CPUs across all nodes.")
+	b.sample("slurm_cluster_cpu_alloc", nil, float64(t.CPUAlloc))
+	b.family("slurm_cluster_cpu_total", "gauge", "Configured CPUs across all nodes.")
+	b.sample("slurm_cluster_cpu_total", nil, float64(t.CPUTotal))
+	b.family("slurm_cluster_mem_alloc_bytes", "gauge", "Allocated memory across all nodes.")
+	b.sample("slurm_cluster_mem_alloc_bytes", nil, float64(t.MemAllocMB)*bytesPerMB)
+	b.family("slurm_cluster_mem_total_bytes", "gauge", "Configured memory across all nodes.")
+	b.sample("slurm_cluster_mem_total_bytes", nil, float64(t.MemTotalMB)*bytesPerMB)
+	b.family("slurm_cluster_gpu_alloc", "gauge", "Allocated GPUs across all nodes.")
+	b.sample("slurm_cluster_gpu_alloc", nil, float64(t.GPUAlloc))
+	b.family("slurm_cluster_gpu_total", "gauge", "Configured GPUs across all nodes.")
+	b.sample("slurm_cluster_gpu_total", nil, float64(t.GPUTotal))
+}
+
+func writeQueue(b *builder, snap *slurm.Snapshot) {
+	q := snap.Queue
+	b.family("slurm_queue_jobs", "gauge", "Jobs (array tasks counted individually) by queue state and CPU/GPU kind.")
+	b.sample("slurm_queue_jobs", labels{{"state", "running"}, {"kind", "cpu"}}, float64(q.RunningCPUJobs))
+	b.sample("slurm_queue_jobs", labels{{"state", "running"}, {"kind", "gpu"}}, float64(q.RunningGPUJobs))
+	b.sample("slurm_queue_jobs", labels{{"state", "pending"}, {"kind", "cpu"}}, float64(q.PendingCPUJobs))
+	b.sample("slurm_queue_jobs", labels{{"state", "pending"}, {"kind", "gpu"}}, float64(q.PendingGPUJobs))
+	b.family("slurm_queue_other_jobs", "gauge", "Jobs that are neither running nor pending.")
+	b.sample("slurm_queue_other_jobs", nil, float64(q.Other))
+
+	r := q.ResourceLoad
+	b.family("slurm_queue_cpus", "gauge", "CPUs held by running jobs or requested by pending jobs.")
+	b.sample("slurm_queue_cpus", labels{{"state", "running"}}, float64(r.RunningCPU))
+	b.sample("slurm_queue_cpus", labels{{"state", "pending"}}, float64(r.PendingCPU))
+	b.family("slurm_queue_mem_bytes", "gauge", "Memory held by running jobs or requested by pending jobs.")
+	b.sample("slurm_queue_mem_bytes", labels{{"state", "running"}}, float64(r.RunningMemMB)*bytesPerMB)
+	b.sample("slurm_queue_mem_bytes", labels{{"state", "pending"}}, float64(r.PendingMemMB)*bytesPerMB)
+	b.family("slurm_queue_gpus", "gauge", "GPUs held by running jobs or requested by pending jobs.")
+	b.sample("slurm_queue_gpus", labels{{"state", "running"}}, float64(r.RunningGPU))
+	b.sample("slurm_queue_gpus", labels{{"state", "pending"}}, float64(r.PendingGPU))
+}
+
+func writeUsers(b *builder, snap *slurm.Snapshot) {
+	users := append([]slurm.UserSummary(nil), snap.Users...)
+	sort.Slice(users, func(i, j int) bool { return users[i].User < users[j].User })
+
+	b.family("slurm_user_jobs", "gauge", "Jobs per user by queue state and CPU/GPU kind.")
+	for _, u := range users {
+		b.sample("slurm_user_jobs", labels{{"user", u.User}, {"state", "running"}, {"kind", "cpu"}}, float64(u.RunningCPUJobs))
+		b.sample("slurm_user_jobs", labels{{"user", u.User}, {"state", "running"}, {"kind", "gpu"}}, float64(u.RunningGPUJobs))
+		b.sample("slurm_user_jobs", labels{{"user", u.User}, {"state", "pending"}, {"kind", "cpu"}}, float64(u.PendingCPUJobs))
+		b.sample("slurm_user_jobs", labels{{"user", u.User}, {"state", "pending"}, {"kind", "gpu"}}, float64(u.PendingGPUJobs))
+	}
+	b.family("slurm_user_cpus", "gauge", "CPUs held (running) or requested (pending) per user.")
+	for _, u := range users {
+		b.sample("slurm_user_cpus", labels{{"user", u.User}, {"state", "running"}}, float64(u.RunningCPU))
+		b.sample("slurm_user_cpus", labels{{"user", u.User}, {"state", "pending"}}, float64(u.PendingCPU))
+	}
+	b.family("slurm_user_gpus", "gauge", "GPUs held (running) or requested (pending) per user.")
+	for _, u := range users {
+		b.sample("slurm_user_gpus", labels{{"user", u.User}, {"state", "running"}}, float64(u.RunningGPU))
+		b.sample("slurm_user_gpus", labels{{"user", u.User}, {"state", "pending"}}, float64(u.PendingGPU))
+	}
+	b.family("slurm_user_pending_mem_bytes", "gauge", "Memory requested by pending jobs per user.")
+	for _, u := range users {
+		b.sample("slurm_user_pending_mem_bytes", labels{{"user", u.User}}, float64(u.PendingMemMB)*bytesPerMB)
+	}
+}
+
+type label struct {
+	name  string
+	value string
+}
+
+type labels []label
+
+type builder struct {
+	strings.Builder
+}
+
+func (b *builder) family(name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, kind)
+}
+
+func (b *builder) sample(name string, ls labels, v float64) {
+	b.WriteString(name)
+	if len(ls) > 0 {
+		b.WriteByte('{')
+		for i, l := range ls {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(l.name)
+			b.WriteString(`="`)
+			b.WriteString(escapeLabelValue(l.value))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+	b.WriteByte('\n')
+}
+
+func escapeLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func sortedKeys(m map[string]int) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
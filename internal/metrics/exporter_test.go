@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"slurm_monitor/internal/monitor"
+	"slurm_monitor/internal/slurm"
+)
+
+func TestExporterRendersClusterAndHealthMetrics(t *testing.T) {
+	e := NewExporter("ssh:cluster")
+	now := time.Unix(1772013600, 0)
+	snap := slurm.Snapshot{
+		CollectedAt: now,
+		Nodes: []slurm.Node{
+			{Name: "gpu-01", State: "MIXED", Partition: "gpu", CPUAlloc: 32, CPUTotal: 64, MemAllocMB: 1024, MemTotalMB: 2048, GPUAlloc: 2, GPUTotal: 4},
+			{Name: "cpu-01", State: "IDLE", Partition: "cpu", CPUTotal: 128},
+		},
+		Queue: slurm.QueueSummary{
+			RunningGPUJobs: 3,
+			PendingCPUJobs: 2,
+			ResourceLoad:   slurm.ResourceTotals{RunningGPU: 2, PendingCPU: 8},
+		},
+		Users: []slurm.UserSummary{{User: "alice", RunningGPU: 2, PendingCPU: 8, PendingMemMB: 1}},
+	}
+	e.Observe(monitor.Update{Snapshot: &snap, State: monitor.StateConnected, LastSuccess: now, Refresh: 4 * time.Second, Latency: 250 * time.Millisecond})
+
+	out := render(t, e)
+	for _, want := range []string{
+		`slurm_monitor_up 1`,
+		`slurm_monitor_refresh_interval_seconds 4`,
+		`slurm_monitor_collect_duration_seconds 0.25`,
+		`slurm_monitor_state{state="connected"} 1`,
+		`slurm_monitor_state{state="reconnecting"} 0`,
+		`slurm_monitor_last_success_timestamp_seconds 1.7720136e+09`,
+		`slurm_node_gpu_alloc{node="gpu-01"} 2`,
+		`slurm_node_mem_total_bytes{node="gpu-01"} 2.147483648e+09`,
+		`slurm_node_info{node="gpu-01",partition="gpu",state="MIXED"} 1`,
+		`slurm_nodes{state="IDLE"} 1`,
+		`slurm_cluster_cpu_total 192`,
+		`slurm_queue_jobs{state="running",kind="gpu"} 3`,
+		`slurm_queue_cpus{state="pending"} 8`,
+		`slurm_user_gpus{user="alice",state="running"} 2`,
+		`slurm_user_pending_mem_bytes{user="alice"} 1.048576e+06`,
+		"# TYPE slurm_queue_jobs gauge",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in exposition:\n%s", want, out)
+		}
+	}
+}
+
+func TestExporterKeepsLastSnapshotWhileRecovering(t *testing.T) {
+	e := NewExporter("local")
+	now := time.Now()
+	snap := slurm.Snapshot{CollectedAt: now, Nodes: []slurm.Node{{Name: "n1", State: "IDLE", CPUTotal: 4}}}
+	e.Observe(monitor.Update{Snapshot: &snap, State: monitor.StateConnected, LastSuccess: now})
+	e.Observe(monitor.Update{State: monitor.StateDisconnectedRecovering, LastError: "timeout", LastSuccess: now, Failures: 3})
+
+	out := render(t, e)
+	for _, want := range []string{
+		`slurm_monitor_up 0`,
+		`slurm_monitor_state{state="disconnected-recovering"} 1`,
+		`slurm_monitor_consecutive_failures 3`,
+		`slurm_node_cpu_total{node="n1"} 4`,
+		`slurm_node_info{node="n1",partition="",state="IDLE"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in exposition:\n%s", want, out)
+		}
+	}
+}
+
+func TestExporterBeforeFirstSnapshotOnlyReportsHealth(t *testing.T) {
+	out := render(t, NewExporter("local"))
+	if !strings.Contains(out, "slurm_monitor_last_success_timestamp_seconds 0") {
+		t.Fatalf("expected zero last-success timestamp, got:\n%s", out)
+	}
+	if strings.Contains(out, "slurm_node_") {
+		t.Fatalf("did not expect node metrics before first snapshot, got:\n%s", out)
+	}
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	got := escapeLabelValue("a\"b\\c\nd")
+	if got != `a\"b\\c\nd` {
+		t.Fatalf("unexpected escaped label value: %q", got)
+	}
+}
+
+func TestServeHTTPRejectsNonGet(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewExporter("local").ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", rec.Code)
+	}
+}
+
+func render(t *testing.T, e *Exporter) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	return rec.Body.String()
+}
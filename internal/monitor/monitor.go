@@ -24,12 +24,26 @@ type Update struct {
 	LastError   string
 	LastSuccess time.Time
 	NextRetry   time.Time
+	// Failures counts consecutive failed collections; it resets on success.
+	Failures int
+	// Refresh is the poll interval in effect after a successful collection;
+	// with adaptive refresh it follows collection cost.
+	Refresh time.Duration
+	// Latency is how long the successful collection took.
+	Latency time.Duration
 }
 
 type Collector interface {
 	Collect(ctx context.Context) (slurm.Snapshot, error)
 }
 
+// SectionCollector is a Collector that can refresh some sources and carry the
+// others over from its previous snapshot.
+type SectionCollector interface {
+	Collector
+	CollectSections(ctx context.Context, sections []slurm.Section) (slurm.Snapshot, error)
+}
+
 type Loop struct {
 	Collector        Collector
 	Refresh          time.Duration
@@ -37,8 +51,35 @@ type Loop struct {
 	MaxBackoff       time.Duration
 	FailureThreshold int
 	Rand             *rand.Rand
+	// Record, when set, receives each successful snapshot before it is
+	// published.
+	Record func(slurm.Snapshot)
+	// SourceRefresh overrides Refresh for individual sources (see
+	// slurm.SourceSections), so slow-changing ones can be read less often.
+	// Each collection reads only the sources that are due and the snapshot
+	// carries the rest. It needs a SectionCollector; other collectors read
+	// everything every Refresh.
+	SourceRefresh map[slurm.Section]time.Duration
+	// AdaptiveRefresh lets the interval follow collection cost: it grows when
+	// collections get slow or large and shrinks back gradually when they are
+	// cheap, within MinRefresh and MaxRefresh. Refresh is the starting
+	// interval; SourceRefresh intervals stay fixed.
+	AdaptiveRefresh bool
+	MinRefresh      time.Duration
+	MaxRefresh      time.Duration
 }
 
+const (
+	// adaptiveLatencyFactor keeps the adaptive interval at least this many
+	// times the collection latency, so polling occupies at most about a tenth
+	// of the time.
+	adaptiveLatencyFactor = 10
+	// adaptiveRowsPerSecond adds a second of adaptive interval per this many
+	// nodes and jobs in the snapshot: slurmctld's cost grows with the payload
+	// even when the transport is fast.
+	adaptiveRowsPerSecond = 5000
+)
+
 func NewLoop(collector Collector, refresh time.Duration) *Loop {
 	return &Loop{
 		Collector:        collector,
@@ -58,20 +99,34 @@ func (l *Loop) Run(ctx context.Context, updates chan<- Update) {
 
 	failures := 0
 	var lastSuccess time.Time
+	sched := l.newSchedule()
 
 	for {
-		snapshot, err := l.Collector.Collect(ctx)
+		start := time.Now()
+		due := sched.due(start)
+		snapshot, err := l.collect(ctx, due)
 		if err == nil {
+			latency := time.Since(start)
 			failures = 0
 			lastSuccess = snapshot.CollectedAt
+			if l.AdaptiveRefresh {
+				sched.refresh = l.adapt(sched.refresh, latency, len(snapshot.Nodes)+len(snapshot.Jobs))
+			}
+			if l.Record != nil {
+				l.Record(snapshot)
+			}
 			if !sendUpdate(ctx, updates, Update{
 				Snapshot:    &snapshot,
 				State:       StateConnected,
 				LastSuccess: lastSuccess,
+				Refresh:     sched.refresh,
+				Latency:     latency,
 			}) {
 				return
 			}
-			if !wait(ctx, l.Refresh) {
+			now := time.Now()
+			sched.collected(now, due, snapshot)
+			if !wait(ctx, sched.next(now)) {
 				return
 			}
 			continue
@@ -83,6 +138,7 @@ func (l *Loop) Run(ctx context.Context, updates chan<- Update) {
 				State:       StateDisconnected,
 				LastError:   err.Error(),
 				LastSuccess: lastSuccess,
+				Failures:    failures,
 			})
 			<-ctx.Done()
 			return
@@ -99,6 +155,7 @@ func (l *Loop) Run(ctx context.Context, updates chan<- Update) {
 			LastError:   err.Error(),
 			LastSuccess: lastSuccess,
 			NextRetry:   time.Now().Add(delay),
+			Failures:    failures,
 		}) {
 			return
 		}
@@ -109,6 +166,114 @@ func (l *Loop) Run(ctx context.Context, updates chan<- Update) {
 	}
 }
 
+// collect reads the due sources, or everything when due is nil.
+func (l *Loop) collect(ctx context.Context, due []slurm.Section) (slurm.Snapshot, error) {
+	if due == nil {
+		return l.Collector.Collect(ctx)
+	}
+	return l.Collector.(SectionCollector).CollectSections(ctx, due)
+}
+
+// schedule tracks when each source is next due. Without per-source
+// intervals every collection reads everything and waits refresh.
+type schedule struct {
+	// refresh is the interval for sources without their own; adaptive
+	// refresh changes it after each collection.
+	refresh  time.Duration
+	interval map[slurm.Section]time.Duration
+	nextDue  map[slurm.Section]time.Time
+}
+
+// newSchedule sets per-source intervals only when SourceRefresh is set and
+// the collector can read sources separately.
+func (l *Loop) newSchedule() *schedule {
+	s := &schedule{refresh: l.Refresh}
+	if l.AdaptiveRefresh {
+		s.refresh = l.clampRefresh(l.Refresh)
+	}
+	if len(l.SourceRefresh) == 0 {
+		return s
+	}
+	if _, ok := l.Collector.(SectionCollector); !ok {
+		return s
+	}
+	s.interval = make(map[slurm.Section]time.Duration)
+	s.nextDue = make(map[slurm.Section]time.Time)
+	for section, d := range l.SourceRefresh {
+		if d > 0 {
+			s.interval[section] = d
+		}
+	}
+	return s
+}
+
+// due returns the sources whose interval has elapsed, or nil for every
+// source. A source not yet collected is due.
+func (s *schedule) due(now time.Time) []slurm.Section {
+	if s.interval == nil {
+		return nil
+	}
+	var out []slurm.Section
+	for _, section := range slurm.SourceSections() {
+		if !now.Before(s.nextDue[section]) {
+			out = append(out, section)
+		}
+	}
+	return out
+}
+
+// collected schedules the sources just read. A source the snapshot marks
+// stale or failed is retried after refresh rather than its own interval.
+func (s *schedule) collected(now time.Time, due []slurm.Section, snap slurm.Snapshot) {
+	for _, section := range due {
+		d := s.refresh
+		if own, ok := s.interval[section]; ok {
+			d = own
+			if snap.SectionStatus(section).State != slurm.SectionFresh {
+				d = min(own, s.refresh)
+			}
+		}
+		s.nextDue[section] = now.Add(d)
+	}
+}
+
+// next returns how long to wait before the next collection.
+func (s *schedule) next(now time.Time) time.Duration {
+	if s.interval == nil {
+		return s.refresh
+	}
+	var earliest time.Time
+	for _, t := range s.nextDue {
+		if earliest.IsZero() || t.Before(earliest) {
+			earliest = t
+		}
+	}
+	return max(0, earliest.Sub(now))
+}
+
+// adapt returns the interval after a collection that took latency and
+// returned rows nodes and jobs. It rises straight to what the collection
+// needs but falls only a quarter of the way per collection, so one fast
+// collection does not undo a slowdown.
+func (l *Loop) adapt(current, latency time.Duration, rows int) time.Duration {
+	target := max(latency*adaptiveLatencyFactor, time.Duration(rows)*time.Second/adaptiveRowsPerSecond)
+	next := target
+	if target < current {
+		next = current - (current-target)/4
+	}
+	return l.clampRefresh(next)
+}
+
+func (l *Loop) clampRefresh(d time.Duration) time.Duration {
+	if l.MinRefresh > 0 {
+		d = max(d, l.MinRefresh)
+	}
+	if l.MaxRefresh > 0 {
+		d = min(d, l.MaxRefresh)
+	}
+	return d
+}
+
 func (l *Loop) backoffDelay(attempt int) time.Duration {
 	if attempt <= 0 {
 		attempt = 1
@@ -3,6 +3,7 @@ package monitor
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/rand"
 	"sync"
 	"testing"
@@ -132,6 +133,43 @@ func TestLoopEmitsConnectedThenRecovering(t *testing.T) {
 	}
 }
 
+func TestLoopRecordsSuccessfulSnapshots(t *testing.T) {
+	fc := &fakeCollector{
+		results: []slurm.Snapshot{{CollectedAt: time.Now()}},
+		errors: []error{
+			&transport.RunError{Stderr: "Connection timed out", ExitCode: 255, Err: errors.New("exit status 255")},
+		},
+	}
+	var recorded int
+	loop := &Loop{
+		Collector:        fc,
+		Refresh:          5 * time.Millisecond,
+		BaseBackoff:      5 * time.Millisecond,
+		MaxBackoff:       10 * time.Millisecond,
+		FailureThreshold: 2,
+		Rand:             rand.New(rand.NewSource(1)),
+		Record:           func(slurm.Snapshot) { recorded++ },
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 80*time.Millisecond)
+	defer cancel()
+	updates := make(chan Update, 10)
+	go loop.Run(ctx, updates)
+
+	var successes int
+	for update := range updates {
+		if update.Snapshot != nil {
+			successes++
+		}
+		if successes > 0 && update.State != StateConnected {
+			cancel()
+		}
+	}
+	if recorded != successes || recorded == 0 {
+		t.Fatalf("expected one record per successful snapshot, got %d records for %d snapshots", recorded, successes)
+	}
+}
+
 func TestLoopRecoversAfterTransientFailures(t *testing.T) {
 	now := time.Now()
 	sc := &scriptedCollector{
@@ -229,3 +267,174 @@ func TestLoopStopsRetryingAfterPermanentFailure(t *testing.T) {
 		t.Fatalf("expected collector to stop after permanent failure, got %d calls", sc.position)
 	}
 }
+
+func TestLoopReportsConsecutiveFailures(t *testing.T) {
+	timeout := &transport.RunError{Stderr: "Connection timed out", ExitCode: 255, Err: errors.New("exit status 255")}
+	sc := &scriptedCollector{
+		steps: []collectStep{
+			{err: timeout},
+			{err: timeout},
+			{snapshot: slurm.Snapshot{CollectedAt: time.Now()}},
+		},
+	}
+
+	loop := &Loop{
+		Collector:        sc,
+		Refresh:          5 * time.Millisecond,
+		BaseBackoff:      5 * time.Millisecond,
+		MaxBackoff:       10 * time.Millisecond,
+		FailureThreshold: 2,
+		Rand:             rand.New(rand.NewSource(1)),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Millisecond)
+	defer cancel()
+
+	updates := make(chan Update, 16)
+	go loop.Run(ctx, updates)
+
+	var failures []int
+	for update := range updates {
+		failures = append(failures, update.Failures)
+		if len(failures) == 3 {
+			cancel()
+		}
+	}
+
+	if len(failures) < 3 || failures[0] != 1 || failures[1] != 2 || failures[2] != 0 {
+		t.Fatalf("expected failure counts [1 2 0], got %v", failures)
+	}
+}
+
+// sectionRecorder records the sources each collection asks for. The first
+// collection marks partitions stale.
+type sectionRecorder struct {
+	mu    sync.Mutex
+	calls [][]slurm.Section
+}
+
+func (r *sectionRecorder) Collect(ctx context.Context) (slurm.Snapshot, error) {
+	return r.CollectSections(ctx, nil)
+}
+
+func (r *sectionRecorder) CollectSections(_ context.Context, sections []slurm.Section) (slurm.Snapshot, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, sections)
+	snap := slurm.Snapshot{CollectedAt: time.Now()}
+	if len(r.calls) == 1 {
+		snap.Sections = []slurm.SectionStatus{{Section: slurm.SectionPartitions, State: slurm.SectionStale}}
+	}
+	return snap, nil
+}
+
+func TestLoopCollectsSourcesOnTheirOwnIntervals(t *testing.T) {
+	rec := &sectionRecorder{}
+	loop := &Loop{
+		Collector: rec,
+		Refresh:   5 * time.Millisecond,
+		SourceRefresh: map[slurm.Section]time.Duration{
+			slurm.SectionNodes:      time.Hour,
+			slurm.SectionPartitions: time.Hour,
+		},
+		Rand: rand.New(rand.NewSource(1)),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	updates := make(chan Update, 16)
+	go loop.Run(ctx, updates)
+
+	received := 0
+	for range updates {
+		received++
+		if received == 4 {
+			cancel()
+		}
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.calls) < 4 {
+		t.Fatalf("expected at least 4 collections, got %v", rec.calls)
+	}
+	want := []string{"[nodes queue partitions]", "[queue partitions]", "[queue]", "[queue]"}
+	for i, w := range want {
+		if got := fmt.Sprint(rec.calls[i]); got != w {
+			t.Fatalf("collection %d: expected %s, got %s (all: %v)", i, w, got, rec.calls)
+		}
+	}
+}
+
+func TestLoopWithoutSourceRefreshCollectsEverything(t *testing.T) {
+	rec := &sectionRecorder{}
+	loop := &Loop{Collector: rec, Refresh: 5 * time.Millisecond, Rand: rand.New(rand.NewSource(1))}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	updates := make(chan Update, 16)
+	go loop.Run(ctx, updates)
+	received := 0
+	for range updates {
+		received++
+		if received == 2 {
+			cancel()
+		}
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	for _, call := range rec.calls {
+		if call != nil {
+			t.Fatalf("expected full collections without SourceRefresh, got %v", rec.calls)
+		}
+	}
+}
+
+func TestAdaptGrowsWithCostAndShrinksGradually(t *testing.T) {
+	l := &Loop{AdaptiveRefresh: true, MinRefresh: 2 * time.Second, MaxRefresh: time.Minute}
+
+	if got := l.adapt(2*time.Second, 100*time.Millisecond, 500); got != 2*time.Second {
+		t.Fatalf("expected a cheap collection to stay at the minimum, got %s", got)
+	}
+	if got := l.adapt(2*time.Second, 3*time.Second, 500); got != 30*time.Second {
+		t.Fatalf("expected a 3s collection to lengthen the interval to 30s, got %s", got)
+	}
+	if got := l.adapt(2*time.Second, 100*time.Millisecond, 50000); got != 10*time.Second {
+		t.Fatalf("expected 50000 rows to lengthen the interval to 10s, got %s", got)
+	}
+	if got := l.adapt(30*time.Second, 3*time.Minute, 0); got != time.Minute {
+		t.Fatalf("expected the interval capped at the maximum, got %s", got)
+	}
+	if got := l.adapt(30*time.Second, 100*time.Millisecond, 0); got != 22*time.Second+750*time.Millisecond {
+		t.Fatalf("expected the interval to fall a quarter of the way to 1s, got %s", got)
+	}
+}
+
+func TestLoopReportsAdaptiveIntervalAndLatency(t *testing.T) {
+	sc := &scriptedCollector{steps: []collectStep{
+		{snapshot: slurm.Snapshot{CollectedAt: time.Now(), Jobs: make([]slurm.Job, 20000)}},
+	}}
+	loop := &Loop{
+		Collector:       sc,
+		Refresh:         time.Second,
+		AdaptiveRefresh: true,
+		MinRefresh:      2 * time.Second,
+		MaxRefresh:      time.Minute,
+		Rand:            rand.New(rand.NewSource(1)),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan Update, 1)
+	go loop.Run(ctx, updates)
+
+	update := <-updates
+	cancel()
+	if update.Refresh != 4*time.Second {
+		t.Fatalf("expected 20000 jobs to set a 4s interval, got %s", update.Refresh)
+	}
+	if update.Latency <= 0 {
+		t.Fatalf("expected the collection latency to be reported, got %s", update.Latency)
+	}
+}
@@ -0,0 +1,128 @@
+// Package record stores snapshots as gzip-compressed JSON lines so a session
+// can be replayed later without cluster access.
+package record
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"slurm_monitor/internal/slurm"
+)
+
+// Frame is one recorded snapshot and the transport it came from.
+type Frame struct {
+	Source   string         `json:"source"`
+	Snapshot slurm.Snapshot `json:"snapshot"`
+}
+
+// Writer appends frames to a recording. Each Create starts a new gzip member
+// at the end of the file, so several sessions can share one file; readers see
+// them as a single stream.
+type Writer struct {
+	mu     sync.Mutex
+	file   *os.File
+	gz     *gzip.Writer
+	enc    *json.Encoder
+	source string
+	err    error
+}
+
+// Create opens path for appending, creating it if needed.
+func Create(path, source string) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open recording: %w", err)
+	}
+	gz := gzip.NewWriter(f)
+	return &Writer{file: f, gz: gz, enc: json.NewEncoder(gz), source: source}, nil
+}
+
+// Record appends one snapshot and flushes it, so an interrupted session stays
+// readable up to the last snapshot. After the first failure Record keeps
+// returning that error without writing.
+func (w *Writer) Record(snap slurm.Snapshot) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.enc.Encode(Frame{Source: w.source, Snapshot: snap}); err != nil {
+		w.err = fmt.Errorf("write recording: %w", err)
+		return w.err
+	}
+	if err := w.gz.Flush(); err != nil {
+		w.err = fmt.Errorf("flush recording: %w", err)
+	}
+	return w.err
+}
+
+// Err returns the first write error, if any.
+func (w *Writer) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Close finishes the gzip member and closes the file. It returns the first
+// write error if one occurred earlier.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	gzErr := w.gz.Close()
+	fileErr := w.file.Close()
+	if w.err != nil {
+		return w.err
+	}
+	if gzErr != nil {
+		return fmt.Errorf("close recording: %w", gzErr)
+	}
+	if fileErr != nil {
+		return fmt.Errorf("close recording: %w", fileErr)
+	}
+	return nil
+}
+
+// Load reads every frame in a recording, ordered by collection time. A
+// recording cut off mid-write returns the frames before the damage together
+// with an error describing it.
+func Load(path string) ([]Frame, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open recording: %w", err)
+	}
+	defer f.Close()
+
+	frames, err := decode(f)
+	sort.SliceStable(frames, func(i, j int) bool {
+		return frames[i].Snapshot.CollectedAt.Before(frames[j].Snapshot.CollectedAt)
+	})
+	return frames, err
+}
+
+func decode(r io.Reader) ([]Frame, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("read recording: %w", err)
+	}
+	defer gz.Close()
+
+	var frames []Frame
+	dec := json.NewDecoder(gz)
+	for {
+		var frame Frame
+		err := dec.Decode(&frame)
+		if errors.Is(err, io.EOF) {
+			return frames, nil
+		}
+		if err != nil {
+			return frames, fmt.Errorf("read recording after %d snapshots: %w", len(frames), err)
+		}
+		frames = append(frames, frame)
+	}
+}
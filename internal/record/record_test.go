@@ -0,0 +1,77 @@
+package record
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"slurm_monitor/internal/slurm"
+)
+
+func snapAt(at time.Time, pending int) slurm.Snapshot {
+	return slurm.Snapshot{
+		Nodes:       []slurm.Node{{Name: "gpu-a01", GPUTotal: 8, Fields: []slurm.KeyValue{{Key: "NodeName", Value: "gpu-a01"}}}},
+		Queue:       slurm.QueueSummary{Pending: pending},
+		CollectedAt: at,
+	}
+}
+
+func TestRecordingRoundTripsAcrossSessions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl.gz")
+	base := time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC)
+
+	for session := 0; session < 2; session++ {
+		w, err := Create(path, "ssh:cluster")
+		if err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if err := w.Record(snapAt(base.Add(time.Duration(session*2+i)*time.Minute), session*2+i)); err != nil {
+				t.Fatalf("record: %v", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("close: %v", err)
+		}
+	}
+
+	frames, err := Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(frames) != 4 {
+		t.Fatalf("expected frames from both sessions, got %d", len(frames))
+	}
+	last := frames[3]
+	if last.Source != "ssh:cluster" || last.Snapshot.Queue.Pending != 3 || !last.Snapshot.CollectedAt.Equal(base.Add(3*time.Minute)) {
+		t.Fatalf("unexpected last frame %+v", last)
+	}
+	if last.Snapshot.Nodes[0].Fields[0].Value != "gpu-a01" {
+		t.Fatalf("expected node fields to survive, got %+v", last.Snapshot.Nodes[0])
+	}
+}
+
+func TestLoadKeepsFramesBeforeTruncation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cut.jsonl.gz")
+	w, err := Create(path, "local")
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	base := time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if err := w.Record(snapAt(base.Add(time.Duration(i)*time.Minute), i)); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+	// Load before Close, as after a crash: the flushed frames are on disk but
+	// the gzip footer is not.
+	frames, err := Load(path)
+	if len(frames) != 3 {
+		t.Fatalf("expected the flushed frames, got %d (err %v)", len(frames), err)
+	}
+	if err == nil || !strings.Contains(err.Error(), "after 3 snapshots") {
+		t.Fatalf("expected truncation to be reported, got %v", err)
+	}
+	_ = w.Close()
+}
@@ -0,0 +1,202 @@
+// Package replay plays a recording back as monitor updates so the TUI can
+// show it exactly as it showed the live session.
+package replay
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"slurm_monitor/internal/monitor"
+	"slurm_monitor/internal/record"
+)
+
+const (
+	minSpeed = 1.0 / 16
+	maxSpeed = 64.0
+	// maxGap caps the real-time wait between frames so gaps in a recording,
+	// such as between two appended sessions, do not stall playback.
+	maxGap = 5 * time.Second
+)
+
+// Status describes the playback position.
+type Status struct {
+	At     time.Time
+	Index  int
+	Total  int
+	Paused bool
+	Speed  float64
+}
+
+// Player emits recorded frames at their original pace scaled by a speed
+// factor. Its controls are safe to call from the UI goroutine while Run is
+// active.
+type Player struct {
+	frames []record.Frame
+
+	mu     sync.Mutex
+	pos    int
+	paused bool
+	speed  float64
+	// dirty marks that pos changed and the frame must be emitted again.
+	dirty bool
+	wake  chan struct{}
+}
+
+// NewPlayer starts at the first frame, playing at real time. frames must be
+// ordered by collection time, as record.Load returns them.
+func NewPlayer(frames []record.Frame) *Player {
+	return &Player{frames: frames, speed: 1, paused: len(frames) <= 1, wake: make(chan struct{}, 1)}
+}
+
+// Run sends frames until ctx is done. At the last frame playback pauses; the
+// channel stays open so the TUI keeps showing it.
+func (p *Player) Run(ctx context.Context, updates chan<- monitor.Update) {
+	defer close(updates)
+	if len(p.frames) == 0 {
+		<-ctx.Done()
+		return
+	}
+	for {
+		p.mu.Lock()
+		snap := p.frames[p.pos].Snapshot
+		p.dirty = false
+		p.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case updates <- monitor.Update{Snapshot: &snap, State: monitor.StateConnected, LastSuccess: snap.CollectedAt}:
+		}
+
+		if !p.waitForNext(ctx) {
+			return
+		}
+	}
+}
+
+// waitForNext blocks until the position changes, either because the next
+// frame is due or because a control moved it. It reports false when ctx ends.
+func (p *Player) waitForNext(ctx context.Context) bool {
+	for {
+		p.mu.Lock()
+		if p.dirty {
+			p.mu.Unlock()
+			return true
+		}
+		delay, playing := p.nextDelayLocked()
+		p.mu.Unlock()
+
+		var due <-chan time.Time
+		var timer *time.Timer
+		if playing {
+			timer = time.NewTimer(delay)
+			due = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return false
+		case <-p.wake:
+			if timer != nil {
+				timer.Stop()
+			}
+		case <-due:
+			p.mu.Lock()
+			if !p.dirty && !p.paused && p.pos < len(p.frames)-1 {
+				p.pos++
+				p.dirty = true
+				if p.pos == len(p.frames)-1 {
+					p.paused = true
+				}
+			}
+			p.mu.Unlock()
+		}
+	}
+}
+
+func (p *Player) nextDelayLocked() (time.Duration, bool) {
+	if p.paused || p.pos >= len(p.frames)-1 {
+		return 0, false
+	}
+	gap := p.frames[p.pos+1].Snapshot.CollectedAt.Sub(p.frames[p.pos].Snapshot.CollectedAt)
+	delay := time.Duration(float64(gap) / p.speed)
+	return min(max(0, delay), maxGap), true
+}
+
+// moveLocked jumps to frame i and asks Run to emit it.
+func (p *Player) moveLocked(i int) {
+	i = min(max(0, i), len(p.frames)-1)
+	if i != p.pos {
+		p.pos = i
+		p.dirty = true
+	}
+}
+
+func (p *Player) signal() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// TogglePause pauses or resumes playback. Resuming at the last frame starts
+// again from the beginning.
+func (p *Player) TogglePause() {
+	p.mu.Lock()
+	p.paused = !p.paused
+	if !p.paused && p.pos >= len(p.frames)-1 {
+		p.moveLocked(0)
+	}
+	p.mu.Unlock()
+	p.signal()
+}
+
+// Step moves by n frames and pauses, for frame-by-frame inspection.
+func (p *Player) Step(n int) {
+	p.mu.Lock()
+	p.paused = true
+	p.moveLocked(p.pos + n)
+	p.mu.Unlock()
+	p.signal()
+}
+
+// Seek moves by d of recorded time to the first frame at or after the
+// target (or the last frame before it when seeking backwards).
+func (p *Player) Seek(d time.Duration) {
+	p.mu.Lock()
+	if len(p.frames) > 0 {
+		target := p.frames[p.pos].Snapshot.CollectedAt.Add(d)
+		i := sort.Search(len(p.frames), func(i int) bool {
+			return !p.frames[i].Snapshot.CollectedAt.Before(target)
+		})
+		if d < 0 && (i >= len(p.frames) || p.frames[i].Snapshot.CollectedAt.After(target)) {
+			i--
+		}
+		p.moveLocked(i)
+	}
+	p.mu.Unlock()
+	p.signal()
+}
+
+// ScaleSpeed multiplies the playback speed by factor, within 1/16x and 64x.
+func (p *Player) ScaleSpeed(factor float64) {
+	p.mu.Lock()
+	p.speed = min(maxSpeed, max(minSpeed, p.speed*factor))
+	p.mu.Unlock()
+	p.signal()
+}
+
+// Status reports the current position.
+func (p *Player) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := Status{Index: p.pos, Total: len(p.frames), Paused: p.paused, Speed: p.speed}
+	if len(p.frames) > 0 {
+		st.At = p.frames[p.pos].Snapshot.CollectedAt
+	}
+	return st
+}
@@ -0,0 +1,85 @@
+package replay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"slurm_monitor/internal/monitor"
+	"slurm_monitor/internal/record"
+	"slurm_monitor/internal/slurm"
+)
+
+var base = time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC)
+
+func framesEvery(step time.Duration, count int) []record.Frame {
+	frames := make([]record.Frame, count)
+	for i := range frames {
+		frames[i] = record.Frame{Snapshot: slurm.Snapshot{
+			Queue:       slurm.QueueSummary{Pending: i},
+			CollectedAt: base.Add(time.Duration(i) * step),
+		}}
+	}
+	return frames
+}
+
+func TestPlayerSeekStepAndSpeed(t *testing.T) {
+	p := NewPlayer(framesEvery(30*time.Second, 10))
+
+	p.Seek(time.Minute)
+	if st := p.Status(); st.Index != 2 || !st.At.Equal(base.Add(time.Minute)) {
+		t.Fatalf("expected seek +1m to land on frame 2, got %+v", st)
+	}
+	p.Seek(-45 * time.Second)
+	if st := p.Status(); st.Index != 0 {
+		t.Fatalf("expected backwards seek to the frame before the target, got %+v", st)
+	}
+	p.Seek(time.Hour)
+	if st := p.Status(); st.Index != 9 {
+		t.Fatalf("expected seek past the end to clamp, got %+v", st)
+	}
+
+	p.Step(-3)
+	if st := p.Status(); st.Index != 6 || !st.Paused {
+		t.Fatalf("expected step to move and pause, got %+v", st)
+	}
+
+	for i := 0; i < 20; i++ {
+		p.ScaleSpeed(2)
+	}
+	if st := p.Status(); st.Speed != maxSpeed {
+		t.Fatalf("expected speed clamped at %v, got %v", maxSpeed, st.Speed)
+	}
+}
+
+func TestPlayerRunEmitsFramesAndPausesAtEnd(t *testing.T) {
+	p := NewPlayer(framesEvery(time.Millisecond, 3))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan monitor.Update)
+	go p.Run(ctx, updates)
+
+	for want := 0; want < 3; want++ {
+		select {
+		case u := <-updates:
+			if u.Snapshot == nil || u.Snapshot.Queue.Pending != want || u.State != monitor.StateConnected {
+				t.Fatalf("expected frame %d, got %+v", want, u)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for frame %d", want)
+		}
+	}
+	if st := p.Status(); !st.Paused || st.Index != 2 {
+		t.Fatalf("expected playback to pause on the last frame, got %+v", st)
+	}
+
+	p.Step(-1)
+	select {
+	case u := <-updates:
+		if u.Snapshot.Queue.Pending != 1 {
+			t.Fatalf("expected step back to re-emit frame 1, got %+v", u.Snapshot.Queue)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for stepped frame")
+	}
+}
@@ -0,0 +1,493 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxStep bounds how much simulated time one Advance covers, so a long
+	// pause between polls does not submit hours of work at once.
+	maxStep = 10 * time.Minute
+	// meanDrain is how long a drained or down node stays out of service.
+	meanDrain = 45 * time.Minute
+	// backfillDepth is how many jobs behind a blocked one the scheduler still
+	// tries in the same partition, a crude stand-in for Slurm's backfill.
+	backfillDepth = 20
+	// warmupSpan is how far back the initial queue's submit times reach.
+	warmupSpan = 6 * time.Hour
+)
+
+var (
+	userNames = []string{
+		"alice", "bram", "chen", "dana", "emeka", "fatima", "goran", "hana", "ivan", "jun",
+		"kofi", "lena", "mateo", "nadia", "oskar", "priya", "quinn", "rosa", "sami", "tomas",
+		"uma", "viktor", "wei", "ximena", "yusuf", "zofia",
+	}
+	cpuJobNames  = []string{"preprocess", "align", "md-run", "bash", "snakemake", "nextflow", "postproc", "mesh"}
+	gpuJobNames  = []string{"train", "finetune", "eval", "infer", "jupyter", "sweep"}
+	drainReasons = []string{"maintenance", "kernel upgrade", "NHC: ECC errors", "bad DIMM", "firmware update", "gpu xid 79"}
+	timeLimits   = []time.Duration{time.Hour, 4 * time.Hour, 12 * time.Hour, 24 * time.Hour, 48 * time.Hour}
+	cpuSizes     = []weighted{{1, 30}, {2, 15}, {4, 20}, {8, 15}, {16, 10}, {32, 6}, {64, 4}}
+	gpuSizes     = []weighted{{1, 50}, {2, 20}, {4, 20}, {8, 10}}
+)
+
+type weighted struct {
+	value  int
+	weight int
+}
+
+type node struct {
+	name       string
+	partitions string
+	gpuType    string
+	cpus       int
+	memMB      int
+	gpus       int
+	allocCPU   int
+	allocMemMB int
+	allocGPU   int
+	load       float64
+	drained    bool
+	down       bool
+	reason     string
+	boot       time.Time
+}
+
+func (n *node) fits(j *job) bool {
+	return !n.drained && !n.down &&
+		n.cpus-n.allocCPU >= j.cpus && n.memMB-n.allocMemMB >= j.memMB && n.gpus-n.allocGPU >= j.gpus
+}
+
+// job is one submission; arrays hold several tasks.
+type job struct {
+	id        int
+	user      string
+	name      string
+	partition string
+	cpus      int
+	memMB     int
+	gpus      int
+	limit     time.Duration
+	submit    time.Time
+	// heldUntil and hold keep the job pending with that reason, for
+	// dependencies and user holds.
+	heldUntil time.Time
+	hold      string
+	array     bool
+	tasks     []*task
+	live      int
+}
+
+type task struct {
+	job     *job
+	index   int
+	node    *node
+	start   time.Time
+	end     time.Time
+	reason  string
+	done    bool
+	running bool
+}
+
+func (t *task) id() string {
+	if !t.job.array {
+		return strconv.Itoa(t.job.id)
+	}
+	return strconv.Itoa(t.job.id) + "_" + strconv.Itoa(t.index)
+}
+
+// Cluster is the simulated cluster state. It is not safe for concurrent use;
+// Transport serializes access.
+type Cluster struct {
+	p   Profile
+	rng *rand.Rand
+	now time.Time
+
+	nodes       []*node
+	byPartition map[string][]*node
+	cursor      map[string]int
+	gpuNodes    int
+
+	// tasks holds every queued or running task in submission order, which is
+	// also scheduling priority order.
+	tasks  []*task
+	jobs   map[int]*job
+	nextID int
+}
+
+// NewCluster builds the nodes for p and fills the queue to its initial size
+// as if the cluster had been running for a while before now.
+func NewCluster(p Profile, now time.Time) *Cluster {
+	c := &Cluster{
+		p:           p,
+		rng:         rand.New(rand.NewSource(p.Seed)),
+		now:         now,
+		byPartition: make(map[string][]*node),
+		cursor:      make(map[string]int),
+		jobs:        make(map[int]*job),
+		nextID:      1000 + int(p.Seed%1000)*1000,
+	}
+	c.buildNodes()
+	c.warmup()
+	return c
+}
+
+func (c *Cluster) buildNodes() {
+	p := c.p
+	gpuNodes := int(math.Round(float64(p.Nodes) * p.GPUFraction))
+	cpuNodes := p.Nodes - gpuNodes
+	width := max(3, len(strconv.Itoa(p.Nodes)))
+
+	cpuPartitions := partitionBlocks(p.CPUPartitions, cpuNodes)
+	for i := 0; i < cpuNodes; i++ {
+		c.addNode(&node{
+			name:  fmt.Sprintf("cn%0*d", width, i+1),
+			cpus:  p.CPUsPerNode,
+			memMB: p.MemMBPerNode,
+		}, cpuPartitions(i))
+	}
+
+	gpuPartitions := partitionBlocks(p.GPUPartitions, gpuNodes)
+	for i := 0; i < gpuNodes; i++ {
+		gt := p.GPUTypes[i*len(p.GPUTypes)/gpuNodes]
+		c.addNode(&node{
+			name:    fmt.Sprintf("%s-%0*d", gt.Name, width, i+1),
+			gpuType: gt.Name,
+			cpus:    p.CPUsPerNode,
+			memMB:   p.MemMBPerNode,
+			gpus:    gt.PerNode,
+		}, gpuPartitions(i))
+	}
+	c.gpuNodes = gpuNodes
+}
+
+// partitionBlocks returns the partitions of the i-th of n nodes: the first
+// name covers all of them and each later name one contiguous slice.
+func partitionBlocks(names []string, n int) func(int) []string {
+	return func(i int) []string {
+		out := []string{names[0]}
+		if rest := len(names) - 1; rest > 0 {
+			out = append(out, names[1+i*rest/n])
+		}
+		return out
+	}
+}
+
+func (c *Cluster) addNode(n *node, partitions []string) {
+	if c.p.Scavenger != "" {
+		partitions = append(partitions, c.p.Scavenger)
+	}
+	for i, name := range partitions {
+		if i > 0 {
+			n.partitions += ","
+		}
+		n.partitions += name
+		c.byPartition[name] = append(c.byPartition[name], n)
+	}
+	n.boot = c.now.Add(-time.Duration(1+c.rng.Intn(60*24)) * time.Hour).Truncate(time.Second)
+	c.nodes = append(c.nodes, n)
+}
+
+// warmup drains the steady-state share of nodes, submits the initial queue
+// with submit times spread over the past and starts what fits with start
+// times staggered so completions do not arrive in one burst.
+func (c *Cluster) warmup() {
+	for _, n := range c.nodes {
+		if c.rng.Float64() < c.p.DrainFraction {
+			c.drain(n)
+		}
+	}
+	var jobs []*job
+	for queued := 0; queued < c.p.InitialTasks; {
+		j := c.newJob(c.now)
+		queued += len(j.tasks)
+		jobs = append(jobs, j)
+	}
+	for i, j := range jobs {
+		j.submit = c.now.Add(-warmupSpan + time.Duration(i)*warmupSpan/time.Duration(len(jobs))).Truncate(time.Second)
+		if !j.heldUntil.IsZero() {
+			j.heldUntil = j.submit.Add(c.randDuration(c.p.MeanRuntime) * 2)
+		}
+	}
+	c.schedule(true)
+	c.updateLoad()
+}
+
+// Advance moves the simulation to now: running tasks finish, nodes drain and
+// return, new jobs arrive and the scheduler starts what fits.
+func (c *Cluster) Advance(now time.Time) {
+	if !now.After(c.now) {
+		return
+	}
+	step := min(now.Sub(c.now), maxStep)
+	prev := c.now
+	c.now = now
+
+	for _, t := range c.tasks {
+		if t.running && !t.end.After(now) {
+			c.finish(t)
+		}
+	}
+	c.churnNodes(step)
+	c.compact()
+	arrivals := int(c.p.Arrival*step.Minutes() + c.rng.Float64())
+	for i := 0; i < arrivals && len(c.tasks) < c.p.MaxTasks; i++ {
+		at := prev.Add(time.Duration(c.rng.Int63n(int64(now.Sub(prev)) + 1)))
+		c.newJob(at.Truncate(time.Second))
+	}
+	c.schedule(false)
+	c.updateLoad()
+}
+
+func (c *Cluster) churnNodes(step time.Duration) {
+	resume := step.Seconds() / meanDrain.Seconds()
+	f := c.p.DrainFraction
+	fail := resume * f / (1 - f)
+	for _, n := range c.nodes {
+		switch {
+		case n.drained || n.down:
+			if n.allocCPU == 0 && c.rng.Float64() < resume {
+				if n.down {
+					n.boot = c.now.Truncate(time.Second)
+				}
+				n.drained, n.down, n.reason = false, false, ""
+			}
+		case c.rng.Float64() < fail:
+			c.drain(n)
+		}
+	}
+}
+
+// drain takes a node out of service. One in five goes down outright, which
+// kills its running tasks; the rest drain and let their tasks finish.
+func (c *Cluster) drain(n *node) {
+	if c.rng.Intn(5) == 0 {
+		n.down = true
+		n.reason = "Not responding [slurm@" + formatTime(c.now) + "]"
+		for _, t := range c.tasks {
+			if t.running && t.node == n {
+				c.finish(t)
+			}
+		}
+		return
+	}
+	n.drained = true
+	n.reason = drainReasons[c.rng.Intn(len(drainReasons))] + " [root@" + formatTime(c.now) + "]"
+}
+
+func (c *Cluster) finish(t *task) {
+	if t.running {
+		t.node.allocCPU -= t.job.cpus
+		t.node.allocMemMB -= t.job.memMB
+		t.node.allocGPU -= t.job.gpus
+	}
+	t.running = false
+	t.done = true
+	t.job.live--
+	if t.job.live == 0 {
+		delete(c.jobs, t.job.id)
+	}
+}
+
+// compact drops finished tasks from the queue.
+func (c *Cluster) compact() {
+	kept := c.tasks[:0]
+	for _, t := range c.tasks {
+		if !t.done {
+			kept = append(kept, t)
+		}
+	}
+	clear(c.tasks[len(kept):])
+	c.tasks = kept
+}
+
+// schedule starts pending tasks in priority order. A task that does not fit
+// blocks its partition for the rest of the pass, except for a few backfill
+// attempts, and the tasks behind it wait on Priority.
+func (c *Cluster) schedule(warm bool) {
+	blocked := make(map[string]int)
+	for _, t := range c.tasks {
+		if t.running {
+			continue
+		}
+		j := t.job
+		if c.now.Before(j.heldUntil) {
+			t.reason = j.hold
+			continue
+		}
+		tries, isBlocked := blocked[j.partition]
+		if isBlocked && tries >= backfillDepth {
+			t.reason = "Priority"
+			continue
+		}
+		n := c.place(j)
+		if n == nil {
+			if isBlocked {
+				blocked[j.partition] = tries + 1
+				t.reason = "Priority"
+			} else {
+				blocked[j.partition] = 0
+				t.reason = "Resources"
+			}
+			continue
+		}
+		c.start(t, n, warm)
+	}
+}
+
+// place finds a node for j, scanning the partition round-robin from where the
+// last placement stopped.
+func (c *Cluster) place(j *job) *node {
+	nodes := c.byPartition[j.partition]
+	start := c.cursor[j.partition]
+	for i := range nodes {
+		k := (start + i) % len(nodes)
+		if nodes[k].fits(j) {
+			c.cursor[j.partition] = k
+			return nodes[k]
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) start(t *task, n *node, warm bool) {
+	j := t.job
+	n.allocCPU += j.cpus
+	n.allocMemMB += j.memMB
+	n.allocGPU += j.gpus
+	t.node = n
+	t.running = true
+	t.reason = "None"
+
+	runtime := min(j.limit, max(30*time.Second, c.randDuration(c.p.MeanRuntime)))
+	t.start = c.now
+	if warm {
+		// Pretend the task started a while ago so the initial running set
+		// finishes gradually.
+		t.start = c.now.Add(-time.Duration(c.rng.Float64() * float64(runtime)))
+		if t.start.Before(j.submit) {
+			t.start = j.submit
+		}
+	}
+	t.start = t.start.Truncate(time.Second)
+	t.end = t.start.Add(runtime)
+	if !t.end.After(c.now) {
+		t.end = c.now.Add(time.Minute)
+	}
+}
+
+// newJob submits one job at the given time and queues its tasks.
+func (c *Cluster) newJob(at time.Time) *job {
+	p := c.p
+	c.nextID++
+	j := &job{
+		id:     c.nextID,
+		user:   c.pickUser(),
+		limit:  timeLimits[c.rng.Intn(len(timeLimits))],
+		submit: at,
+	}
+
+	gpuShare := 0.0
+	switch {
+	case c.gpuNodes == len(c.nodes):
+		gpuShare = 1
+	case c.gpuNodes > 0:
+		gpuShare = min(0.6, 1.5*float64(c.gpuNodes)/float64(len(c.nodes)))
+	}
+	if c.rng.Float64() < gpuShare {
+		maxGPUs := 0
+		for _, gt := range p.GPUTypes {
+			maxGPUs = max(maxGPUs, gt.PerNode)
+		}
+		j.gpus = min(maxGPUs, pick(c.rng, gpuSizes))
+		j.cpus = min(p.CPUsPerNode, j.gpus*8)
+		j.memMB = max(1024, j.gpus*(p.MemMBPerNode/maxGPUs)/2/1024*1024)
+		j.partition = c.pickPartition(p.GPUPartitions)
+		j.name = gpuJobNames[c.rng.Intn(len(gpuJobNames))]
+	} else {
+		j.cpus = min(p.CPUsPerNode, pick(c.rng, cpuSizes))
+		perCPU := p.MemMBPerNode / p.CPUsPerNode
+		j.memMB = max(1024, j.cpus*perCPU/(1+c.rng.Intn(2))/1024*1024)
+		j.partition = c.pickPartition(p.CPUPartitions)
+		j.name = cpuJobNames[c.rng.Intn(len(cpuJobNames))]
+	}
+
+	switch r := c.rng.Float64(); {
+	case r < 0.04:
+		j.hold = "Dependency"
+		j.heldUntil = at.Add(c.randDuration(p.MeanRuntime) * 2)
+	case r < 0.05:
+		j.hold = "JobHeldUser"
+		j.heldUntil = at.Add(c.randDuration(p.MeanRuntime) * 4)
+	}
+
+	count := 1
+	if p.MaxArray > 1 && c.rng.Float64() < p.ArrayFraction {
+		u := c.rng.Float64()
+		count = 2 + int(float64(p.MaxArray-2)*u*u)
+		j.array = true
+	}
+	j.tasks = make([]*task, count)
+	for i := range j.tasks {
+		j.tasks[i] = &task{job: j, index: i}
+	}
+	j.live = count
+	c.tasks = append(c.tasks, j.tasks...)
+	c.jobs[j.id] = j
+	return j
+}
+
+// pickUser favours a few heavy users, as real queues do.
+func (c *Cluster) pickUser() string {
+	u := c.rng.Float64()
+	i := int(float64(c.p.Users) * u * u * u)
+	name := userNames[i%len(userNames)]
+	if i >= len(userNames) {
+		name += strconv.Itoa(i / len(userNames))
+	}
+	return name
+}
+
+// pickPartition sends most jobs to the first partition of their kind and
+// some to the scavenger partition.
+func (c *Cluster) pickPartition(names []string) string {
+	r := c.rng.Float64()
+	switch {
+	case c.p.Scavenger != "" && r < 0.1:
+		return c.p.Scavenger
+	case len(names) == 1 || r < 0.7:
+		return names[0]
+	default:
+		return names[1+c.rng.Intn(len(names)-1)]
+	}
+}
+
+func (c *Cluster) updateLoad() {
+	for _, n := range c.nodes {
+		n.load = float64(n.allocCPU) * (0.6 + 0.4*c.rng.Float64())
+	}
+}
+
+// randDuration draws from an exponential distribution with the given mean.
+func (c *Cluster) randDuration(mean time.Duration) time.Duration {
+	return time.Duration(c.rng.ExpFloat64() * float64(mean)).Truncate(time.Second)
+}
+
+func pick(rng *rand.Rand, choices []weighted) int {
+	total := 0
+	for _, c := range choices {
+		total += c.weight
+	}
+	r := rng.Intn(total)
+	for _, c := range choices {
+		if r < c.weight {
+			return c.value
+		}
+		r -= c.weight
+	}
+	return choices[len(choices)-1].value
+}
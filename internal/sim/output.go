@@ -0,0 +1,493 @@
+package sim
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const slurmTimeLayout = "2006-01-02T15:04:05"
+
+// WriteNodes writes `scontrol show node -o` output, one node per line.
+func (c *Cluster) WriteNodes(b *strings.Builder) {
+	b.Grow(len(c.nodes) * 700)
+	for _, n := range c.nodes {
+		c.writeNode(b, n)
+		b.WriteByte('\n')
+	}
+}
+
+func (c *Cluster) writeNode(b *strings.Builder, n *node) {
+	features := "cpu,ib,icelake"
+	gres := "(null)"
+	cfgTRES := "cpu=" + strconv.Itoa(n.cpus) + ",mem=" + strconv.Itoa(n.memMB) + "M,billing=" + strconv.Itoa(n.cpus)
+	allocTRES := ""
+	if n.allocCPU > 0 {
+		allocTRES = "cpu=" + strconv.Itoa(n.allocCPU) + ",mem=" + strconv.Itoa(n.allocMemMB) + "M"
+	}
+	if n.gpus > 0 {
+		features = n.gpuType + ",ib"
+		gres = "gpu:" + n.gpuType + ":" + strconv.Itoa(n.gpus) + "(S:0-1)"
+		// Typed GPUs are accounted under both keys, as with
+		// AccountingStorageTRES=gres/gpu,gres/gpu:<type>.
+		cfgTRES += gpuTRES(n.gpus, n.gpuType)
+		if n.allocGPU > 0 {
+			allocTRES += gpuTRES(n.allocGPU, n.gpuType)
+		}
+	}
+	weight := 1
+	if n.gpus > 0 {
+		weight = 10
+	}
+	freeMem := max(0, n.memMB-n.allocMemMB*7/10-2048)
+
+	b.WriteString("NodeName=")
+	b.WriteString(n.name)
+	b.WriteString(" Arch=x86_64 CoresPerSocket=")
+	b.WriteString(strconv.Itoa(n.cpus / 2))
+	b.WriteString(" CPUAlloc=")
+	b.WriteString(strconv.Itoa(n.allocCPU))
+	b.WriteString(" CPUEfctv=")
+	b.WriteString(strconv.Itoa(n.cpus))
+	b.WriteString(" CPUTot=")
+	b.WriteString(strconv.Itoa(n.cpus))
+	b.WriteString(" CPULoad=")
+	if n.down {
+		b.WriteString("N/A")
+	} else {
+		b.WriteString(strconv.FormatFloat(n.load, 'f', 2, 64))
+	}
+	b.WriteString(" AvailableFeatures=")
+	b.WriteString(features)
+	b.WriteString(" ActiveFeatures=")
+	b.WriteString(features)
+	b.WriteString(" Gres=")
+	b.WriteString(gres)
+	if n.gpus > 0 {
+		b.WriteString(" GresUsed=gpu:")
+		b.WriteString(n.gpuType)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(n.allocGPU))
+		if n.allocGPU > 0 {
+			b.WriteString("(IDX:0-")
+			b.WriteString(strconv.Itoa(n.allocGPU - 1))
+			b.WriteByte(')')
+		} else {
+			b.WriteString("(IDX:N/A)")
+		}
+	}
+	b.WriteString(" NodeAddr=")
+	b.WriteString(n.name)
+	b.WriteString(" NodeHostName=")
+	b.WriteString(n.name)
+	b.WriteString(" OS=Linux 5.14.0-427.el9.x86_64 #1 SMP PREEMPT_DYNAMIC RealMemory=")
+	b.WriteString(strconv.Itoa(n.memMB))
+	b.WriteString(" AllocMem=")
+	b.WriteString(strconv.Itoa(n.allocMemMB))
+	b.WriteString(" FreeMem=")
+	if n.down {
+		b.WriteString("N/A")
+	} else {
+		b.WriteString(strconv.Itoa(freeMem))
+	}
+	b.WriteString(" Sockets=2 Boards=1 State=")
+	b.WriteString(nodeState(n))
+	b.WriteString(" ThreadsPerCore=1 TmpDisk=0 Weight=")
+	b.WriteString(strconv.Itoa(weight))
+	b.WriteString(" Owner=N/A MCS_label=N/A Partitions=")
+	b.WriteString(n.partitions)
+	b.WriteString(" BootTime=")
+	b.WriteString(formatTime(n.boot))
+	b.WriteString(" SlurmdStartTime=")
+	b.WriteString(formatTime(n.boot.Add(2 * time.Minute)))
+	b.WriteString(" CfgTRES=")
+	b.WriteString(cfgTRES)
+	b.WriteString(" AllocTRES=")
+	b.WriteString(allocTRES)
+	if n.reason != "" {
+		// Reason goes last because it holds spaces, as scontrol prints it.
+		b.WriteString(" Reason=")
+		b.WriteString(n.reason)
+	}
+}
+
+// WritePartitions writes `scontrol show partition -o` output in the order
+// the profile names partitions. The first partition is the default, and the
+// scavenger partition preempts by requeue at a lower priority tier.
+func (c *Cluster) WritePartitions(b *strings.Builder) {
+	seen := make(map[string]bool)
+	for _, name := range append(append(append([]string{}, c.p.CPUPartitions...), c.p.GPUPartitions...), c.p.Scavenger) {
+		nodes := c.byPartition[name]
+		if name == "" || seen[name] || len(nodes) == 0 {
+			continue
+		}
+		seen[name] = true
+
+		cpus, memMB, gpus := 0, 0, 0
+		names := make([]string, 0, len(nodes))
+		for _, n := range nodes {
+			cpus += n.cpus
+			memMB += n.memMB
+			gpus += n.gpus
+			names = append(names, n.name)
+		}
+		tres := "cpu=" + strconv.Itoa(cpus) + ",mem=" + strconv.Itoa(memMB) + "M,node=" + strconv.Itoa(len(nodes)) + ",billing=" + strconv.Itoa(cpus)
+		if gpus > 0 {
+			tres += ",gres/gpu=" + strconv.Itoa(gpus)
+		}
+		isDefault, tier, preempt, maxTime := "NO", "10", "OFF", timeLimits[len(timeLimits)-1]
+		if len(seen) == 1 {
+			isDefault = "YES"
+		}
+		if name == c.p.Scavenger {
+			tier, preempt, maxTime = "1", "REQUEUE", 24*time.Hour
+		}
+
+		b.WriteString("PartitionName=")
+		b.WriteString(name)
+		b.WriteString(" AllowGroups=ALL AllowAccounts=ALL AllowQos=ALL Default=")
+		b.WriteString(isDefault)
+		b.WriteString(" QoS=N/A DefaultTime=01:00:00 DisableRootJobs=NO ExclusiveUser=NO GraceTime=0 Hidden=NO")
+		b.WriteString(" MaxNodes=UNLIMITED MaxTime=")
+		b.WriteString(formatLimit(maxTime))
+		b.WriteString(" MinNodes=0 LLN=NO MaxCPUsPerNode=UNLIMITED Nodes=")
+		b.WriteString(hostlist(names))
+		b.WriteString(" PriorityJobFactor=1 PriorityTier=")
+		b.WriteString(tier)
+		b.WriteString(" RootOnly=NO ReqResv=NO OverSubscribe=NO OverTimeLimit=NONE PreemptMode=")
+		b.WriteString(preempt)
+		b.WriteString(" State=UP TotalCPUs=")
+		b.WriteString(strconv.Itoa(cpus))
+		b.WriteString(" TotalNodes=")
+		b.WriteString(strconv.Itoa(len(nodes)))
+		b.WriteString(" SelectTypeParameters=NONE JobDefaults=(null) DefMemPerNode=UNLIMITED MaxMemPerNode=UNLIMITED TRES=")
+		b.WriteString(tres)
+		b.WriteByte('\n')
+	}
+}
+
+// hostlist compresses node names the way Slurm prints them, as in
+// cn[001-016],a100-[001-004]. Names must share zero-padded numeric suffixes
+// within a prefix, which the simulator's naming guarantees.
+func hostlist(names []string) string {
+	var b strings.Builder
+	for i := 0; i < len(names); {
+		prefix, digits := splitNodeName(names[i])
+		last, _ := strconv.Atoi(digits)
+		end := digits
+		j := i
+		for ; j+1 < len(names); j++ {
+			p, d := splitNodeName(names[j+1])
+			n, _ := strconv.Atoi(d)
+			if p != prefix || len(d) != len(digits) || n != last+1 {
+				break
+			}
+			last, end = n, d
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(prefix)
+		if j == i {
+			b.WriteString(digits)
+		} else {
+			b.WriteString("[" + digits + "-" + end + "]")
+		}
+		i = j + 1
+	}
+	return b.String()
+}
+
+func splitNodeName(name string) (prefix, digits string) {
+	i := len(name)
+	for i > 0 && name[i-1] >= '0' && name[i-1] <= '9' {
+		i--
+	}
+	return name[:i], name[i:]
+}
+
+func nodeState(n *node) string {
+	if n.down {
+		return "DOWN*"
+	}
+	state := "MIXED"
+	switch {
+	case n.allocCPU == 0 && n.allocGPU == 0:
+		state = "IDLE"
+	case n.allocCPU == n.cpus || (n.gpus > 0 && n.allocGPU == n.gpus):
+		state = "ALLOCATED"
+	}
+	if n.drained {
+		state += "+DRAIN"
+	}
+	return state
+}
+
+// WriteQueue writes one line per task in the collector's squeue -O layout:
+// JobID|State|UserName|NumCPUs|MinMemory|tres-alloc|Partition|Name|Reason|
+// ArrayJobID|ArrayTaskID|NodeList|SubmitTime|StartTime|TimeLimit.
+func (c *Cluster) WriteQueue(b *strings.Builder) {
+	b.Grow(len(c.tasks) * 160)
+	for _, t := range c.tasks {
+		if t.done {
+			continue
+		}
+		j := t.job
+		state, nodeList, start := "PENDING", "", "N/A"
+		if t.running {
+			state, nodeList, start = "RUNNING", t.node.name, formatTime(t.start)
+		}
+		taskID := "N/A"
+		if j.array {
+			taskID = strconv.Itoa(t.index)
+		}
+		b.WriteString(t.id())
+		b.WriteByte('|')
+		b.WriteString(state)
+		b.WriteByte('|')
+		b.WriteString(j.user)
+		b.WriteByte('|')
+		b.WriteString(strconv.Itoa(j.cpus))
+		b.WriteByte('|')
+		b.WriteString(minMemory(j))
+		b.WriteByte('|')
+		// squeue reports the request in tres-alloc until the job starts,
+		// and the allocated GPU model after.
+		gpuType := ""
+		if t.running {
+			gpuType = t.node.gpuType
+		}
+		b.WriteString(jobTRES(j, gpuType))
+		b.WriteByte('|')
+		b.WriteString(j.partition)
+		b.WriteByte('|')
+		b.WriteString(j.name)
+		b.WriteByte('|')
+		b.WriteString(t.reason)
+		b.WriteByte('|')
+		b.WriteString(strconv.Itoa(j.id))
+		b.WriteByte('|')
+		b.WriteString(taskID)
+		b.WriteByte('|')
+		b.WriteString(nodeList)
+		b.WriteByte('|')
+		b.WriteString(formatTime(j.submit))
+		b.WriteByte('|')
+		b.WriteString(start)
+		b.WriteByte('|')
+		b.WriteString(formatLimit(j.limit))
+		b.WriteByte('\n')
+	}
+}
+
+// WriteJob writes `scontrol show job -o` output for a job, an array root or
+// one array task, and reports whether the job exists. Like scontrol, an array
+// root prints its pending tasks as one record followed by each running task.
+func (c *Cluster) WriteJob(b *strings.Builder, id string) bool {
+	rootPart, taskPart, isTask := strings.Cut(id, "_")
+	rootID, err := strconv.Atoi(rootPart)
+	if err != nil {
+		return false
+	}
+	j := c.jobs[rootID]
+	if j == nil {
+		return false
+	}
+	if isTask {
+		index, err := strconv.Atoi(taskPart)
+		if err != nil || !j.array || index < 0 || index >= len(j.tasks) || j.tasks[index].done {
+			return false
+		}
+		c.writeJobLine(b, j, j.tasks[index], strconv.Itoa(index))
+		return true
+	}
+
+	var pending []int
+	var firstPending *task
+	for _, t := range j.tasks {
+		if !t.done && !t.running {
+			if firstPending == nil {
+				firstPending = t
+			}
+			pending = append(pending, t.index)
+		}
+	}
+	if firstPending != nil {
+		taskIDs := "N/A"
+		if j.array {
+			taskIDs = compressRanges(pending)
+		}
+		c.writeJobLine(b, j, firstPending, taskIDs)
+	}
+	for _, t := range j.tasks {
+		if t.running {
+			c.writeJobLine(b, j, t, strconv.Itoa(t.index))
+		}
+	}
+	return true
+}
+
+func (c *Cluster) writeJobLine(b *strings.Builder, j *job, t *task, taskIDs string) {
+	state, reason, nodeList, allocTRES := "PENDING", t.reason, "(null)", "(null)"
+	start, end, runTime := "Unknown", "Unknown", time.Duration(0)
+	if t.running {
+		state, reason, nodeList, allocTRES = "RUNNING", "None", t.node.name, jobTRES(j, t.node.gpuType)
+		start, end = formatTime(t.start), formatTime(t.start.Add(j.limit))
+		runTime = c.now.Sub(t.start)
+	}
+	dependency := "(null)"
+	if j.hold == "Dependency" && c.now.Before(j.heldUntil) {
+		dependency = "afterok:" + strconv.Itoa(j.id-1) + "(unfulfilled)"
+	}
+	eligible := j.submit
+	if j.heldUntil.After(eligible) {
+		eligible = j.heldUntil
+	}
+	out := "/home/" + j.user + "/slurm-" + strconv.Itoa(j.id) + ".out"
+	if j.array {
+		out = "/home/" + j.user + "/slurm-" + strconv.Itoa(j.id) + "_" + strconv.Itoa(t.index) + ".out"
+	}
+
+	b.WriteString("JobId=")
+	b.WriteString(strconv.Itoa(j.id))
+	if j.array {
+		b.WriteString(" ArrayJobId=")
+		b.WriteString(strconv.Itoa(j.id))
+		b.WriteString(" ArrayTaskId=")
+		b.WriteString(taskIDs)
+	}
+	b.WriteString(" JobName=")
+	b.WriteString(j.name)
+	b.WriteString(" UserId=")
+	b.WriteString(j.user)
+	b.WriteString("(20001) GroupId=")
+	b.WriteString(j.user)
+	b.WriteString("(20001) Account=")
+	b.WriteString(j.user)
+	b.WriteString(" QOS=normal JobState=")
+	b.WriteString(state)
+	b.WriteString(" Reason=")
+	b.WriteString(reason)
+	b.WriteString(" Dependency=")
+	b.WriteString(dependency)
+	b.WriteString(" RunTime=")
+	b.WriteString(formatLimit(runTime))
+	b.WriteString(" TimeLimit=")
+	b.WriteString(formatLimit(j.limit))
+	b.WriteString(" SubmitTime=")
+	b.WriteString(formatTime(j.submit))
+	b.WriteString(" EligibleTime=")
+	b.WriteString(formatTime(eligible))
+	b.WriteString(" StartTime=")
+	b.WriteString(start)
+	b.WriteString(" EndTime=")
+	b.WriteString(end)
+	b.WriteString(" Partition=")
+	b.WriteString(j.partition)
+	b.WriteString(" NodeList=")
+	b.WriteString(nodeList)
+	b.WriteString(" NumNodes=1 NumCPUs=")
+	b.WriteString(strconv.Itoa(j.cpus))
+	b.WriteString(" NumTasks=1 ReqTRES=")
+	b.WriteString(jobTRES(j, ""))
+	b.WriteString(" AllocTRES=")
+	b.WriteString(allocTRES)
+	if perCPU, ok := memPerCPU(j); ok {
+		b.WriteString(" MinMemoryCPU=")
+		b.WriteString(strconv.Itoa(perCPU))
+	} else {
+		b.WriteString(" MinMemoryNode=")
+		b.WriteString(strconv.Itoa(j.memMB))
+	}
+	b.WriteString("M Command=/home/")
+	b.WriteString(j.user)
+	b.WriteString("/jobs/")
+	b.WriteString(j.name)
+	b.WriteString(".sh WorkDir=/home/")
+	b.WriteString(j.user)
+	b.WriteString(" StdErr=")
+	b.WriteString(out)
+	b.WriteString(" StdOut=")
+	b.WriteString(out)
+	b.WriteByte('\n')
+}
+
+// memPerCPU returns the per-CPU amount for CPU jobs whose memory divides
+// evenly across their CPUs; those are written as --mem-per-cpu requests.
+func memPerCPU(j *job) (int, bool) {
+	if j.gpus > 0 || j.cpus <= 1 || j.memMB%j.cpus != 0 {
+		return 0, false
+	}
+	return j.memMB / j.cpus, true
+}
+
+// minMemory renders squeue's MinMemory column, with the c suffix squeue
+// prints for per-CPU requests.
+func minMemory(j *job) string {
+	if perCPU, ok := memPerCPU(j); ok {
+		return strconv.Itoa(perCPU) + "Mc"
+	}
+	return strconv.Itoa(j.memMB) + "M"
+}
+
+// jobTRES renders a job's TRES. gpuType names the model of allocated GPUs;
+// requests carry none because simulated jobs do not ask for a model.
+func jobTRES(j *job, gpuType string) string {
+	tres := "cpu=" + strconv.Itoa(j.cpus) + ",mem=" + strconv.Itoa(j.memMB) + "M,node=1,billing=" + strconv.Itoa(j.cpus)
+	if j.gpus > 0 {
+		tres += gpuTRES(j.gpus, gpuType)
+	}
+	return tres
+}
+
+func gpuTRES(n int, gpuType string) string {
+	tres := ",gres/gpu=" + strconv.Itoa(n)
+	if gpuType != "" {
+		tres += ",gres/gpu:" + gpuType + "=" + strconv.Itoa(n)
+	}
+	return tres
+}
+
+func formatTime(t time.Time) string {
+	return t.Format(slurmTimeLayout)
+}
+
+// formatLimit renders a duration as Slurm's [days-]hours:minutes:seconds.
+func formatLimit(d time.Duration) string {
+	total := int(d / time.Second)
+	days, rest := total/86400, total%86400
+	minSec := pad2(rest%3600/60) + ":" + pad2(rest%60)
+	if days > 0 {
+		return strconv.Itoa(days) + "-" + pad2(rest/3600) + ":" + minSec
+	}
+	return strconv.Itoa(rest/3600) + ":" + minSec
+}
+
+func pad2(n int) string {
+	if n < 10 {
+		return "0" + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
+
+// compressRanges renders sorted task indexes the way Slurm prints array task
+// lists, as in 0-3,7,9-12.
+func compressRanges(indexes []int) string {
+	var b strings.Builder
+	for i := 0; i < len(indexes); {
+		j := i
+		for j+1 < len(indexes) && indexes[j+1] == indexes[j]+1 {
+			j++
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(indexes[i]))
+		if j > i {
+			b.WriteByte('-')
+			b.WriteString(strconv.Itoa(indexes[j]))
+		}
+		i = j + 1
+	}
+	return b.String()
+}
@@ -0,0 +1,293 @@
+// Package sim generates a synthetic Slurm cluster that evolves over time and
+// answers the collector's commands with realistic scontrol and squeue output.
+// It backs sim://<profile> targets for demos and load tests.
+package sim
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scheme prefixes a simulated target, as in sim://large?nodes=2000.
+const Scheme = "sim://"
+
+// DefaultProfile is used for a bare sim:// target.
+const DefaultProfile = "small"
+
+// GPUType is one kind of GPU node: the gres type name and GPUs per node.
+type GPUType struct {
+	Name    string
+	PerNode int
+}
+
+// Profile describes the shape and workload of a simulated cluster.
+type Profile struct {
+	Name         string
+	Nodes        int
+	CPUsPerNode  int
+	MemMBPerNode int
+	// GPUFraction is the share of nodes that carry GPUs; GPU nodes are split
+	// evenly across GPUTypes.
+	GPUFraction float64
+	GPUTypes    []GPUType
+	// The first of CPUPartitions spans every CPU node and each later one a
+	// contiguous slice of them, so partitions overlap as they do on real
+	// sites; GPUPartitions work the same way. Every node is also in Scavenger
+	// when it is set.
+	CPUPartitions []string
+	GPUPartitions []string
+	Scavenger     string
+	Users         int
+	// Arrival is job submissions per simulated minute. ArrayFraction of them
+	// are arrays of up to MaxArray tasks.
+	Arrival       float64
+	ArrayFraction float64
+	MaxArray      int
+	MeanRuntime   time.Duration
+	// InitialTasks is the queue size the simulation starts with. MaxTasks caps
+	// it like MaxJobCount does in slurm.conf: submissions beyond it are dropped.
+	InitialTasks int
+	MaxTasks     int
+	// DrainFraction is the steady-state share of nodes that are drained or down.
+	DrainFraction float64
+	// Speed is simulated seconds per wall-clock second.
+	Speed float64
+	Seed  int64
+}
+
+var builtinProfiles = map[string]Profile{
+	"small": {
+		Nodes:         48,
+		CPUsPerNode:   64,
+		MemMBPerNode:  256000,
+		GPUFraction:   0.25,
+		GPUTypes:      []GPUType{{Name: "a100", PerNode: 4}},
+		CPUPartitions: []string{"cpu"},
+		GPUPartitions: []string{"gpu"},
+		Users:         12,
+		Arrival:       4,
+		ArrayFraction: 0.2,
+		MaxArray:      40,
+		MeanRuntime:   20 * time.Minute,
+		InitialTasks:  400,
+		MaxTasks:      2000,
+		DrainFraction: 0.04,
+		Speed:         1,
+		Seed:          1,
+	},
+	"medium": {
+		Nodes:         1000,
+		CPUsPerNode:   64,
+		MemMBPerNode:  512000,
+		GPUFraction:   0.2,
+		GPUTypes:      []GPUType{{Name: "a100", PerNode: 4}, {Name: "h100", PerNode: 8}},
+		CPUPartitions: []string{"cpu", "bigmem"},
+		GPUPartitions: []string{"gpu"},
+		Scavenger:     "preempt",
+		Users:         150,
+		Arrival:       3,
+		ArrayFraction: 0.25,
+		MaxArray:      500,
+		MeanRuntime:   time.Hour,
+		InitialTasks:  20000,
+		MaxTasks:      60000,
+		DrainFraction: 0.02,
+		Speed:         1,
+		Seed:          1,
+	},
+	// large is the load-test shape: 10k nodes and half a million array tasks.
+	"large": {
+		Nodes:         10000,
+		CPUsPerNode:   64,
+		MemMBPerNode:  512000,
+		GPUFraction:   0.15,
+		GPUTypes:      []GPUType{{Name: "a100", PerNode: 4}, {Name: "h100", PerNode: 8}, {Name: "l40s", PerNode: 4}},
+		CPUPartitions: []string{"cpu", "cpu-long"},
+		GPUPartitions: []string{"gpu", "gpu-interactive"},
+		Scavenger:     "scavenge",
+		Users:         800,
+		Arrival:       3,
+		ArrayFraction: 0.3,
+		MaxArray:      2000,
+		MeanRuntime:   2 * time.Hour,
+		InitialTasks:  500000,
+		MaxTasks:      750000,
+		DrainFraction: 0.01,
+		Speed:         1,
+		Seed:          1,
+	},
+}
+
+// ProfileNames lists the built-in profiles in a stable order.
+func ProfileNames() []string {
+	names := make([]string, 0, len(builtinProfiles))
+	for name := range builtinProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseTarget resolves a sim:// target (with or without the scheme) into a
+// profile. The path names a built-in profile and query parameters override
+// its fields, as in sim://medium?nodes=4000&gpus=h100:8&drain=0.05.
+func ParseTarget(target string) (Profile, error) {
+	spec := strings.TrimPrefix(strings.TrimSpace(target), Scheme)
+	name, query, _ := strings.Cut(spec, "?")
+	name = strings.Trim(name, "/")
+	if name == "" {
+		name = DefaultProfile
+	}
+	p, ok := builtinProfiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown sim profile %q (available: %s)", name, strings.Join(ProfileNames(), ", "))
+	}
+	p.Name = name
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return Profile{}, fmt.Errorf("parse sim options: %w", err)
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := p.set(key, values.Get(key)); err != nil {
+			return Profile{}, fmt.Errorf("sim option %s: %w", key, err)
+		}
+	}
+	if err := p.validate(); err != nil {
+		return Profile{}, err
+	}
+	return p, nil
+}
+
+func (p *Profile) set(key, value string) error {
+	var err error
+	switch key {
+	case "nodes":
+		p.Nodes, err = strconv.Atoi(value)
+	case "cpus":
+		p.CPUsPerNode, err = strconv.Atoi(value)
+	case "mem":
+		p.MemMBPerNode, err = parseMemMB(value)
+	case "gpu-fraction":
+		p.GPUFraction, err = strconv.ParseFloat(value, 64)
+	case "gpus":
+		p.GPUTypes, err = parseGPUTypes(value)
+	case "cpu-partitions":
+		p.CPUPartitions = splitList(value)
+	case "gpu-partitions":
+		p.GPUPartitions = splitList(value)
+	case "scavenger":
+		p.Scavenger = strings.TrimSpace(value)
+	case "users":
+		p.Users, err = strconv.Atoi(value)
+	case "arrival":
+		p.Arrival, err = strconv.ParseFloat(value, 64)
+	case "arrays":
+		p.ArrayFraction, err = strconv.ParseFloat(value, 64)
+	case "max-array":
+		p.MaxArray, err = strconv.Atoi(value)
+	case "runtime":
+		p.MeanRuntime, err = time.ParseDuration(value)
+	case "tasks":
+		p.InitialTasks, err = strconv.Atoi(value)
+		if err == nil && p.MaxTasks < p.InitialTasks {
+			p.MaxTasks = p.InitialTasks + p.InitialTasks/2
+		}
+	case "max-tasks":
+		p.MaxTasks, err = strconv.Atoi(value)
+	case "drain":
+		p.DrainFraction, err = strconv.ParseFloat(value, 64)
+	case "speed":
+		p.Speed, err = strconv.ParseFloat(value, 64)
+	case "seed":
+		p.Seed, err = strconv.ParseInt(value, 10, 64)
+	default:
+		return fmt.Errorf("unknown option")
+	}
+	return err
+}
+
+func (p Profile) validate() error {
+	switch {
+	case p.Nodes <= 0:
+		return fmt.Errorf("sim profile needs at least one node")
+	case p.CPUsPerNode <= 0 || p.MemMBPerNode <= 0:
+		return fmt.Errorf("sim nodes need positive cpus and mem")
+	case p.GPUFraction < 0 || p.GPUFraction > 1:
+		return fmt.Errorf("sim gpu-fraction must be between 0 and 1")
+	case p.GPUFraction > 0 && len(p.GPUTypes) == 0:
+		return fmt.Errorf("sim gpu-fraction needs at least one gpu type")
+	case len(p.CPUPartitions) == 0 && p.GPUFraction < 1:
+		return fmt.Errorf("sim cpu-partitions must not be empty")
+	case len(p.GPUPartitions) == 0 && p.GPUFraction > 0:
+		return fmt.Errorf("sim gpu-partitions must not be empty")
+	case p.Users <= 0:
+		return fmt.Errorf("sim users must be > 0")
+	case p.Arrival < 0 || p.ArrayFraction < 0 || p.ArrayFraction > 1:
+		return fmt.Errorf("sim arrival must be >= 0 and arrays between 0 and 1")
+	case p.MaxArray < 1:
+		return fmt.Errorf("sim max-array must be >= 1")
+	case p.MeanRuntime <= 0:
+		return fmt.Errorf("sim runtime must be > 0")
+	case p.InitialTasks < 0 || p.MaxTasks < p.InitialTasks:
+		return fmt.Errorf("sim max-tasks must be >= tasks >= 0")
+	case p.DrainFraction < 0 || p.DrainFraction >= 1:
+		return fmt.Errorf("sim drain must be in [0, 1)")
+	case p.Speed <= 0:
+		return fmt.Errorf("sim speed must be > 0")
+	}
+	return nil
+}
+
+// parseGPUTypes reads a comma-separated list of type:per-node pairs such as
+// a100:4,h100:8.
+func parseGPUTypes(v string) ([]GPUType, error) {
+	var out []GPUType
+	for _, item := range splitList(v) {
+		name, count, ok := strings.Cut(item, ":")
+		n, err := strconv.Atoi(count)
+		if !ok || name == "" || err != nil || n <= 0 {
+			return nil, fmt.Errorf("expected type:count, got %q", item)
+		}
+		out = append(out, GPUType{Name: name, PerNode: n})
+	}
+	return out, nil
+}
+
+// parseMemMB accepts a plain MB count or a G/T suffix.
+func parseMemMB(v string) (int, error) {
+	v = strings.ToUpper(strings.TrimSpace(v))
+	mult := 1
+	switch {
+	case strings.HasSuffix(v, "T"):
+		mult, v = 1024*1024, strings.TrimSuffix(v, "T")
+	case strings.HasSuffix(v, "G"):
+		mult, v = 1024, strings.TrimSuffix(v, "G")
+	case strings.HasSuffix(v, "M"):
+		v = strings.TrimSuffix(v, "M")
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, err
+	}
+	return n * mult, nil
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
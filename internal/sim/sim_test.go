@@ -0,0 +1,282 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"slurm_monitor/internal/slurm"
+	"slurm_monitor/internal/transport"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func newTestTransport(t *testing.T, target string) (*Transport, *fakeClock) {
+	t.Helper()
+	p, err := ParseTarget(target)
+	if err != nil {
+		t.Fatalf("parse %s: %v", target, err)
+	}
+	clock := &fakeClock{now: time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local)}
+	return newTransport(target, p, clock.Now), clock
+}
+
+func TestParseTargetAppliesOverrides(t *testing.T) {
+	p, err := ParseTarget("sim://medium?nodes=200&gpus=h100:8,l40s:4&drain=0.1&cpu-partitions=batch&tasks=5000")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Name != "medium" || p.Nodes != 200 || p.DrainFraction != 0.1 || p.InitialTasks != 5000 {
+		t.Fatalf("overrides not applied: %+v", p)
+	}
+	if len(p.GPUTypes) != 2 || p.GPUTypes[1] != (GPUType{Name: "l40s", PerNode: 4}) {
+		t.Fatalf("unexpected gpu types: %+v", p.GPUTypes)
+	}
+	if len(p.CPUPartitions) != 1 || p.CPUPartitions[0] != "batch" {
+		t.Fatalf("unexpected cpu partitions: %+v", p.CPUPartitions)
+	}
+
+	if p, err := ParseTarget("sim://"); err != nil || p.Name != DefaultProfile {
+		t.Fatalf("expected bare target to use %s, got %+v %v", DefaultProfile, p, err)
+	}
+}
+
+func TestParseTargetRejectsBadInput(t *testing.T) {
+	for _, target := range []string{
+		"sim://tiny",
+		"sim://small?nodes=0",
+		"sim://small?colour=blue",
+		"sim://small?gpus=a100",
+		"sim://small?drain=1",
+	} {
+		if _, err := ParseTarget(target); err == nil {
+			t.Fatalf("expected %s to be rejected", target)
+		}
+	}
+}
+
+func TestTransportFeedsCollector(t *testing.T) {
+	tr, clock := newTestTransport(t, "sim://small")
+	collector := slurm.NewCollector(tr, time.Second)
+
+	first, err := collector.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	if len(first.Nodes) != 48 {
+		t.Fatalf("expected 48 nodes, got %d", len(first.Nodes))
+	}
+	if first.Queue.Running == 0 || first.Queue.Pending == 0 {
+		t.Fatalf("expected running and pending jobs, got %+v", first.Queue)
+	}
+
+	// Node allocations and running jobs come from separate commands and must
+	// still agree, as they do on a real cluster.
+	totals := first.Totals()
+	var runningCPU, runningGPU, runningMemMB int
+	for _, job := range first.Jobs {
+		if job.State == "RUNNING" {
+			runningCPU += job.CPUs
+			runningGPU += job.GPUs
+			runningMemMB += job.MemMB
+		}
+	}
+	if totals.CPUAlloc != runningCPU || totals.GPUAlloc != runningGPU {
+		t.Fatalf("node allocation %d cpu/%d gpu does not match running jobs %d/%d",
+			totals.CPUAlloc, totals.GPUAlloc, runningCPU, runningGPU)
+	}
+	if totals.MemAllocMB != runningMemMB {
+		t.Fatalf("node memory allocation %d MB does not match running jobs %d MB", totals.MemAllocMB, runningMemMB)
+	}
+	if totals.GPUTotal != 12*4 {
+		t.Fatalf("expected 48 GPUs, got %d", totals.GPUTotal)
+	}
+	// Typed TRES keys repeat the gres/gpu count and must not double it.
+	if types := first.GPUTypes(); len(types) != 1 || types[0].Type != "a100" || types[0].Total != 48 || types[0].Alloc != totals.GPUAlloc {
+		t.Fatalf("expected all GPUs under a100, got %+v", types)
+	}
+	for _, load := range first.Queue.ByGPUType {
+		if load.Type == "a100" && load.RunningGPU != runningGPU {
+			t.Fatalf("expected running a100 demand %d, got %+v", runningGPU, load)
+		}
+	}
+
+	clock.now = clock.now.Add(30 * time.Minute)
+	second, err := collector.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	before := make(map[string]bool, len(first.Jobs))
+	for _, job := range first.Jobs {
+		before[job.ID] = true
+	}
+	var arrived int
+	for _, job := range second.Jobs {
+		if !before[job.ID] {
+			arrived++
+		}
+	}
+	if arrived == 0 || len(second.Jobs)-arrived == len(first.Jobs) {
+		t.Fatalf("expected jobs to finish and arrive over 30 minutes, %d new of %d", arrived, len(second.Jobs))
+	}
+}
+
+func TestClusterIsDeterministicPerSeed(t *testing.T) {
+	render := func(target string) string {
+		tr, clock := newTestTransport(t, target)
+		clock.now = clock.now.Add(5 * time.Minute)
+		res, err := tr.Run(context.Background(), "scontrol show node -o; squeue -h -r")
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+		return res.Stdout
+	}
+	if render("sim://small") != render("sim://small") {
+		t.Fatalf("expected identical output for the same seed")
+	}
+	if render("sim://small") == render("sim://small?seed=2") {
+		t.Fatalf("expected a different seed to change the cluster")
+	}
+}
+
+func TestDrainedNodesReportReason(t *testing.T) {
+	tr, _ := newTestTransport(t, "sim://small?drain=0.5")
+	res, err := tr.Run(context.Background(), "scontrol show node -o")
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	var drained int
+	for _, line := range strings.Split(strings.TrimSpace(res.Stdout), "\n") {
+		if strings.Contains(line, "+DRAIN") || strings.Contains(line, "State=DOWN") {
+			drained++
+			if !strings.Contains(line, " Reason=") {
+				t.Fatalf("expected a reason on an unavailable node: %s", line)
+			}
+		}
+	}
+	if drained == 0 {
+		t.Fatalf("expected drained nodes at drain=0.5")
+	}
+}
+
+func TestShowJobPrintsArrayRootAndTasks(t *testing.T) {
+	tr, _ := newTestTransport(t, "sim://small?arrays=1")
+	var root *job
+	for _, j := range tr.cluster.jobs {
+		if j.array && len(j.tasks) > 2 && (root == nil || j.id < root.id) {
+			root = j
+		}
+	}
+	if root == nil {
+		t.Fatalf("expected an array job")
+	}
+
+	id := root.tasks[0].id()
+	res, err := tr.Run(context.Background(), "scontrol show job -o "+id)
+	if err != nil {
+		t.Fatalf("show task %s: %v", id, err)
+	}
+	if !strings.Contains(res.Stdout, "ArrayTaskId=0 ") || !strings.Contains(res.Stdout, "ReqTRES=cpu=") {
+		t.Fatalf("unexpected task detail: %s", res.Stdout)
+	}
+
+	res, err = tr.Run(context.Background(), "scontrol show job -o "+strings.Split(id, "_")[0])
+	if err != nil {
+		t.Fatalf("show root: %v", err)
+	}
+	if lines := strings.Count(res.Stdout, "\n"); lines == 0 || lines > len(root.tasks) {
+		t.Fatalf("expected one pending record plus running tasks, got %d lines", lines)
+	}
+
+	batch := "scontrol show job -o " + id + " 2>/dev/null; scontrol show job -o 1 2>/dev/null; true"
+	res, err = tr.Run(context.Background(), batch)
+	if err != nil || strings.Count(res.Stdout, "\n") != 1 {
+		t.Fatalf("expected a batched lookup to skip the unknown job, got %q err=%v", res.Stdout, err)
+	}
+	res, err = tr.Run(context.Background(), "echo PendingLookup=1; scontrol show job -o 1 2>&1; true")
+	if err != nil || res.Stdout != "PendingLookup=1\n"+invalidJobIDError+"\n" {
+		t.Fatalf("expected the invalid job id error folded into stdout, got %q err=%v", res.Stdout, err)
+	}
+
+	_, err = tr.Run(context.Background(), "scontrol show job -o 1")
+	var runErr *transport.RunError
+	if !errors.As(err, &runErr) || runErr.ExitCode != 1 || transport.IsRetryable(err) {
+		t.Fatalf("expected a permanent invalid job id error, got %v", err)
+	}
+}
+
+func TestUnsupportedCommandFailsFast(t *testing.T) {
+	tr, _ := newTestTransport(t, "sim://small")
+	_, err := tr.Run(context.Background(), "sinfo -h")
+	var runErr *transport.RunError
+	if !errors.As(err, &runErr) || runErr.ExitCode != 127 || transport.IsRetryable(err) {
+		t.Fatalf("expected a non-retryable exit 127, got %v", err)
+	}
+}
+
+func TestCompressRanges(t *testing.T) {
+	if got := compressRanges([]int{0, 1, 2, 3, 7, 9, 10, 11, 12}); got != "0-3,7,9-12" {
+		t.Fatalf("unexpected ranges: %q", got)
+	}
+	if got := formatLimit(26*time.Hour + 5*time.Minute); got != "1-02:05:00" {
+		t.Fatalf("unexpected limit: %q", got)
+	}
+	if got := formatLimit(4 * time.Hour); got != "4:00:00" {
+		t.Fatalf("unexpected limit: %q", got)
+	}
+}
+
+func TestCollectorDetectsSimulatedRelease(t *testing.T) {
+	tr, _ := newTestTransport(t, "sim://small")
+	collector := slurm.NewCollector(tr, time.Second)
+
+	caps, err := collector.DetectCapabilities(context.Background())
+	if err != nil {
+		t.Fatalf("detect: %v", err)
+	}
+	if caps.Version.String() != Version || caps.JSON || !caps.TRESAlloc {
+		t.Fatalf("expected text output on %s, got %+v", Version, caps)
+	}
+}
+
+func TestPartitionsMatchNodes(t *testing.T) {
+	tr, _ := newTestTransport(t, "sim://small?nodes=40&cpu-partitions=cpu,short&scavenger=preempt")
+	snap, err := slurm.NewCollector(tr, time.Second).Collect(context.Background())
+	if err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	names := make([]string, 0, len(snap.Partitions))
+	byName := make(map[string]slurm.Partition)
+	for _, p := range snap.Partitions {
+		names = append(names, p.Name)
+		byName[p.Name] = p
+	}
+	if strings.Join(names, ",") != "cpu,short,gpu,preempt" {
+		t.Fatalf("unexpected partitions: %v", names)
+	}
+	if cpu := byName["cpu"]; !cpu.Default || cpu.Nodes != "cn[001-030]" || cpu.TotalNodes != 30 || cpu.PreemptMode != "OFF" {
+		t.Fatalf("unexpected cpu partition: %+v", cpu)
+	}
+	if gpu := byName["gpu"]; gpu.Nodes != "a100-[001-010]" || gpu.TotalGPUs != 40 || gpu.Default {
+		t.Fatalf("unexpected gpu partition: %+v", gpu)
+	}
+	scav := byName["preempt"]
+	if scav.TotalNodes != 40 || scav.PreemptMode != "REQUEUE" || scav.PriorityTier >= byName["cpu"].PriorityTier {
+		t.Fatalf("unexpected scavenger partition: %+v", scav)
+	}
+	totals := snap.Totals()
+	if scav.TotalCPUs != totals.CPUTotal || scav.AllocCPUs != totals.CPUAlloc || scav.AllocGPUs != totals.GPUAlloc {
+		t.Fatalf("expected the all-node partition to match cluster totals, got %+v vs %+v", scav, totals)
+	}
+}
+
+func TestHostlist(t *testing.T) {
+	got := hostlist([]string{"cn001", "cn002", "cn003", "cn005", "a100-001", "a100-002", "login"})
+	if got != "cn[001-003],cn005,a100-[001-002],login" {
+		t.Fatalf("unexpected hostlist: %q", got)
+	}
+}
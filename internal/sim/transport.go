@@ -0,0 +1,130 @@
+package sim
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"slurm_monitor/internal/transport"
+)
+
+// Version is the Slurm release the simulator reports.
+const Version = "23.11.0"
+
+// Transport answers Slurm commands from a simulated cluster. The cluster
+// advances to the current simulated time before each command, so successive
+// polls see jobs finish, arrive and start.
+type Transport struct {
+	target string
+
+	mu      sync.Mutex
+	cluster *Cluster
+	started time.Time
+	speed   float64
+	clock   func() time.Time
+}
+
+// NewTransport builds the cluster for a sim:// target.
+func NewTransport(target string) (*Transport, error) {
+	p, err := ParseTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	return newTransport(target, p, time.Now), nil
+}
+
+func newTransport(target string, p Profile, clock func() time.Time) *Transport {
+	now := clock()
+	return &Transport{
+		target:  target,
+		cluster: NewCluster(p, now),
+		started: now,
+		speed:   p.Speed,
+		clock:   clock,
+	}
+}
+
+func (t *Transport) Describe() string {
+	return t.target
+}
+
+// invalidJobIDError is what scontrol prints for a job that does not exist.
+const invalidJobIDError = "slurm_load_jobs error: Invalid job id specified"
+
+// Run serves the commands the collector and preflight check issue. The
+// collector's compound command is split on ';' and each part answered in
+// turn; squeue always prints the collector's -O layout.
+func (t *Transport) Run(ctx context.Context, command string) (transport.RunResult, error) {
+	if err := ctx.Err(); err != nil {
+		return transport.RunResult{}, &transport.RunError{Command: command, Target: t.target, Timeout: true, Err: err}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	elapsed := t.clock().Sub(t.started)
+	t.cluster.Advance(t.started.Add(time.Duration(float64(elapsed) * t.speed)))
+
+	// The preflight check probes for the Slurm tools, which the simulator
+	// always has.
+	if strings.Contains(command, "command -v") {
+		return transport.RunResult{}, nil
+	}
+	if id, ok := strings.CutPrefix(command, "scontrol show job -o "); ok && !strings.Contains(command, ";") {
+		var b strings.Builder
+		if !t.cluster.WriteJob(&b, strings.TrimSpace(id)) {
+			return transport.RunResult{ExitCode: 1}, &transport.RunError{
+				Command:  command,
+				Target:   t.target,
+				Stderr:   invalidJobIDError,
+				ExitCode: 1,
+			}
+		}
+		return transport.RunResult{Stdout: b.String()}, nil
+	}
+
+	var b strings.Builder
+	for _, part := range strings.Split(command, ";") {
+		part = strings.TrimSpace(part)
+		switch {
+		case strings.HasPrefix(part, "scontrol --version"):
+			b.WriteString("slurm " + Version + "\n")
+		case strings.HasPrefix(part, "squeue --help"):
+			// The simulator prints text only, so no --json here; the
+			// helpFormat field list names tres-alloc.
+			if strings.HasPrefix(part, "squeue --helpFormat") {
+				b.WriteString("JobID State UserName NumCPUs MinMemory tres-alloc Partition Name Reason\n")
+			} else {
+				b.WriteString("Usage: squeue [OPTIONS]\n  -O, --Format=fields\n")
+			}
+		case part == "true":
+		case strings.HasPrefix(part, "scontrol show job -o "):
+			// Batched lookups either silence errors for jobs that already
+			// left or fold them into stdout.
+			id := strings.TrimPrefix(part, "scontrol show job -o ")
+			id, silenced := strings.CutSuffix(id, " 2>/dev/null")
+			id = strings.TrimSuffix(id, " 2>&1")
+			if !t.cluster.WriteJob(&b, strings.TrimSpace(id)) && !silenced {
+				b.WriteString(invalidJobIDError + "\n")
+			}
+		case part == "scontrol show node -o":
+			t.cluster.WriteNodes(&b)
+		case part == "scontrol show partition -o":
+			t.cluster.WritePartitions(&b)
+		case strings.HasPrefix(part, "squeue "):
+			t.cluster.WriteQueue(&b)
+		case strings.HasPrefix(part, "echo "):
+			b.WriteString(strings.Trim(strings.TrimPrefix(part, "echo "), `"'`))
+			b.WriteByte('\n')
+		default:
+			// Exit code 127 mirrors a missing command and is not retryable.
+			return transport.RunResult{}, &transport.RunError{
+				Command:  command,
+				Target:   t.target,
+				Stderr:   "sim: unsupported command: " + part,
+				ExitCode: 127,
+			}
+		}
+	}
+	return transport.RunResult{Stdout: b.String()}, nil
+}
@@ -0,0 +1,158 @@
+package slurm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"slurm_monitor/internal/transport"
+)
+
+// capabilityCommand reads the release and the squeue options and -O fields
+// this cluster understands in one round trip. sinfo covers sites that wrap
+// scontrol. The trailing true keeps a missing --helpFormat from failing the
+// probe; a dropped connection still fails it.
+const capabilityCommand = `scontrol --version 2>/dev/null || sinfo --version; echo "__SLURM_MONITOR_SPLIT__"; squeue --help 2>&1; echo "__SLURM_MONITOR_SPLIT__"; squeue --helpFormat 2>&1; true`
+
+// Version is a Slurm release such as 23.02.7. The zero value means unknown.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+func (v Version) IsZero() bool {
+	return v == Version{}
+}
+
+// String renders the version the way Slurm does, with a two-digit minor.
+func (v Version) String() string {
+	if v.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%d.%02d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast reports whether v is major.minor or newer. An unknown version is
+// never at least anything.
+func (v Version) AtLeast(major, minor int) bool {
+	if v.IsZero() {
+		return false
+	}
+	if v.Major != major {
+		return v.Major > major
+	}
+	return v.Minor >= minor
+}
+
+var versionRe = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ParseVersion reads the first release number in s, so it accepts both
+// `scontrol --version` output ("slurm-wlm 21.08.5") and slurmrestd's bare
+// release strings ("23.02.7").
+func ParseVersion(s string) (Version, bool) {
+	m := versionRe.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, false
+	}
+	var v Version
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	return v, true
+}
+
+// Capabilities records what the cluster's Slurm supports, so collectors pick
+// command variants instead of assuming one squeue format works everywhere.
+type Capabilities struct {
+	Version Version
+	// JSON is true when squeue and scontrol accept --json (Slurm 21.08+).
+	JSON bool
+	// TRESAlloc is true when squeue -O knows the tres-alloc field. Without it
+	// GPU demand comes from the older gres field.
+	TRESAlloc bool
+	// OnlyFields is true when squeue advertises --only-fields. It is reported
+	// by doctor; no collect command depends on it yet.
+	OnlyFields bool
+}
+
+// defaultCapabilities is assumed when the probe cannot tell, and matches
+// what the text collector always ran before detection existed.
+var defaultCapabilities = Capabilities{TRESAlloc: true}
+
+// Features lists the detected optional features for display.
+func (c Capabilities) Features() []string {
+	var out []string
+	if c.JSON {
+		out = append(out, "json")
+	}
+	if c.TRESAlloc {
+		out = append(out, "tres-alloc")
+	}
+	if c.OnlyFields {
+		out = append(out, "only-fields")
+	}
+	return out
+}
+
+// parseCapabilities reads capabilityCommand output. Sections that are missing
+// or unrecognizable fall back to defaultCapabilities.
+func parseCapabilities(raw string) Capabilities {
+	sections := strings.Split(raw, "__SLURM_MONITOR_SPLIT__")
+	if len(sections) != 3 {
+		return defaultCapabilities
+	}
+	versionRaw := strings.TrimSpace(sections[0])
+	help := sections[1]
+	fields := strings.ToLower(sections[2])
+
+	caps := defaultCapabilities
+	if strings.HasPrefix(strings.ToLower(versionRaw), "slurm") {
+		caps.Version, _ = ParseVersion(versionRaw)
+	}
+	caps.JSON = strings.Contains(help, "--json") && (caps.Version.IsZero() || caps.Version.AtLeast(21, 8))
+	caps.OnlyFields = strings.Contains(help, "--only-fields")
+	if strings.TrimSpace(fields) != "" {
+		caps.TRESAlloc = strings.Contains(fields, "tres-alloc")
+	}
+	return caps
+}
+
+// DetectCapabilities probes the cluster and settles the collect format. It
+// runs on the first Collect; callers such as doctor may run it earlier. A
+// transient failure is returned and leaves detection to the next call;
+// anything else, such as a probe the target cannot run, settles on
+// defaultCapabilities.
+func (c *Collector) DetectCapabilities(ctx context.Context) (Capabilities, error) {
+	caps := defaultCapabilities
+	raw, err := c.runWithTimeout(ctx, capabilityCommand)
+	if err != nil {
+		if transport.IsRetryable(err) {
+			return Capabilities{}, err
+		}
+	} else {
+		caps = parseCapabilities(raw)
+	}
+
+	c.caps = caps
+	c.format = formatText
+	if caps.JSON {
+		c.format = formatJSON
+	}
+	return caps, nil
+}
+
+// Capabilities returns what DetectCapabilities found, or the defaults before
+// detection. When the JSON command proved unusable JSON is reported false.
+func (c *Collector) Capabilities() Capabilities {
+	if c.format == formatUnknown {
+		return defaultCapabilities
+	}
+	caps := c.caps
+	caps.JSON = c.format == formatJSON
+	return caps
+}
@@ -0,0 +1,97 @@
+package slurm
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"slurm_monitor/internal/transport"
+)
+
+const (
+	modernHelp   = "Usage: squeue [OPTIONS]\n  -O, --Format=fields\n      --json          Produce JSON output\n"
+	modernFields = "Account AccrueTime ArrayJobID Gres Name tres-alloc tres-per-node\n"
+)
+
+func probeOutput(version, help, fields string) string {
+	return strings.Join([]string{version, help, fields}, "\n__SLURM_MONITOR_SPLIT__\n")
+}
+
+func TestParseCapabilities(t *testing.T) {
+	cases := []struct {
+		name string
+		raw  string
+		want Capabilities
+	}{
+		{"modern", probeOutput("slurm 23.02.7", modernHelp, modernFields),
+			Capabilities{Version: Version{23, 2, 7}, JSON: true, TRESAlloc: true}},
+		{"debian packaging", probeOutput("slurm-wlm 21.08.5", modernHelp+"      --only-fields=list\n", modernFields),
+			Capabilities{Version: Version{21, 8, 5}, JSON: true, TRESAlloc: true, OnlyFields: true}},
+		{"no json before 21.08", probeOutput("slurm 20.11.9", modernHelp, modernFields),
+			Capabilities{Version: Version{20, 11, 9}, TRESAlloc: true}},
+		{"gres only", probeOutput("slurm 18.08.8", "  -O, --Format=fields", "Account Gres Name"),
+			Capabilities{Version: Version{18, 8, 8}}},
+		{"unrecognized", "NodeName=node001 CPUTot=32", defaultCapabilities},
+	}
+	for _, tc := range cases {
+		if got := parseCapabilities(tc.raw); got != tc.want {
+			t.Fatalf("%s: expected %+v, got %+v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestVersionStringAndCompare(t *testing.T) {
+	v, ok := ParseVersion("slurm 24.11.0-0rc1")
+	if !ok || v != (Version{24, 11, 0}) || v.String() != "24.11.0" {
+		t.Fatalf("unexpected version %+v %q", v, v.String())
+	}
+	if got := (Version{Major: 23, Minor: 2, Patch: 7}).String(); got != "23.02.7" {
+		t.Fatalf("expected a two-digit minor, got %q", got)
+	}
+	if !v.AtLeast(21, 8) || (Version{21, 2, 0}).AtLeast(21, 8) || (Version{}).AtLeast(0, 0) {
+		t.Fatalf("unexpected AtLeast results")
+	}
+}
+
+func TestCollectUsesGresColumnWithoutTRESAlloc(t *testing.T) {
+	tr := writeFixture(t,
+		transport.Exchange{Command: capabilityCommand, Stdout: probeOutput("slurm 18.08.8", "  -O, --Format=fields", "Account Gres Name")},
+		transport.Exchange{Command: nodesCommand, Stdout: "NodeName=gpu001 State=MIXED CPUTot=64 CPUAlloc=8 RealMemory=512000 Partitions=gpu\n"},
+		transport.Exchange{Command: gresQueueCommand, Stdout: "5001|PENDING|frank|8|32G|gpu:a100:2|gpu|train|Resources\n"},
+		transport.Exchange{Command: partitionsCommand},
+	)
+	c := NewCollector(tr, time.Second)
+
+	snap, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	if len(snap.Jobs) != 1 || snap.Jobs[0].GPUs != 2 || snap.SlurmVersion != "18.08.8" {
+		t.Fatalf("expected gres demand on 18.08.8, got %+v version=%q", snap.Jobs, snap.SlurmVersion)
+	}
+}
+
+func TestCollectRetriesDetectionAfterTransientFailure(t *testing.T) {
+	tr := writeFixture(t,
+		transport.Exchange{Command: capabilityCommand, ExitCode: 255, Stderr: "Connection reset by peer", Error: "exit status 255"},
+	)
+	c := NewCollector(tr, time.Second)
+
+	_, err := c.Collect(context.Background())
+	if err == nil || !transport.IsRetryable(err) || c.format != formatUnknown {
+		t.Fatalf("expected a retryable error with detection undecided, got %v format=%s", err, c.format)
+	}
+}
+
+func TestDetectCapabilitiesDefaultsWhenProbeIsUnsupported(t *testing.T) {
+	// A fixture recorded before detection existed has no probe exchange, so
+	// the probe fails with a non-retryable exit code 127.
+	tr := writeFixture(t, transport.Exchange{Command: nodesCommand})
+	c := NewCollector(tr, time.Second)
+
+	caps, err := c.DetectCapabilities(context.Background())
+	if err != nil || caps != defaultCapabilities || c.format != formatText {
+		t.Fatalf("expected defaults and text output, got %+v %v format=%s", caps, err, c.format)
+	}
+}
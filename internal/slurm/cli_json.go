@@ -0,0 +1,100 @@
+package slurm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// The JSON commands print the same data_parser schema slurmrestd serves, so
+// the REST models decode them. squeue --json ignores -r and -O: pending
+// array tasks arrive as one record with a task range and every field is
+// present.
+const (
+	jsonNodesCommand      = `scontrol show node --json`
+	jsonQueueCommand      = `squeue --json`
+	jsonPartitionsCommand = `scontrol show partition --json`
+)
+
+type collectFormat int
+
+const (
+	formatUnknown collectFormat = iota
+	formatText
+	formatJSON
+)
+
+func (f collectFormat) String() string {
+	switch f {
+	case formatText:
+		return "text"
+	case formatJSON:
+		return "json"
+	default:
+		return "unknown"
+	}
+}
+
+// errJSONOutput marks CLI output that is not the expected JSON document, as
+// when the data_parser plugin is missing and Slurm prints a text error.
+var errJSONOutput = errors.New("unexpected slurm JSON output")
+
+func parseNodesJSON(raw string) ([]Node, error) {
+	var resp restNodesResponse
+	if err := decodeCLIJSON(raw, &resp); err != nil {
+		return nil, err
+	}
+	nodes := make([]Node, 0, len(resp.Nodes))
+	for _, n := range resp.Nodes {
+		if n.Name == "" {
+			continue
+		}
+		nodes = append(nodes, nodeFromFields(n.fields()))
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+	return nodes, nil
+}
+
+func parseJobsJSON(raw string) ([]Job, error) {
+	var resp restJobsResponse
+	if err := decodeCLIJSON(raw, &resp); err != nil {
+		return nil, err
+	}
+	return restJobsToQueue(resp.Jobs), nil
+}
+
+// parsePartitionsJSON reads empty output as no partitions.
+func parsePartitionsJSON(raw string) ([]Partition, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var resp restPartitionsResponse
+	if err := decodeCLIJSON(raw, &resp); err != nil {
+		return nil, err
+	}
+	return restPartitionsToModel(resp.Partitions), nil
+}
+
+func restPartitionsToModel(records []restPartition) []Partition {
+	out := make([]Partition, 0, len(records))
+	for _, r := range records {
+		if jsonString(r.lookup("name")) == "" {
+			continue
+		}
+		out = append(out, partitionFromFields(r.fields()))
+	}
+	return out
+}
+
+func decodeCLIJSON(raw string, out restEnvelope) error {
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		return fmt.Errorf("%w: %v", errJSONOutput, err)
+	}
+	if msg := out.message(); msg != "" {
+		return fmt.Errorf("%w: %s", errJSONOutput, msg)
+	}
+	return nil
+}
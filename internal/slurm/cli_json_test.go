@@ -0,0 +1,165 @@
+package slurm
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"slurm_monitor/internal/transport"
+)
+
+const cliQueueJSON = `{
+  "jobs": [
+    {"job_id": 3001, "name": "eval|ablation", "user_name": "dana", "partition": "gpu",
+     "job_state": ["PENDING"], "state_reason": "Resources", "cpus": 8,
+     "memory_per_node": 64000, "tres_req_str": "cpu=8,mem=64000M,node=1,gres/gpu:a100=2"},
+    {"job_id": 3002, "name": "done", "user_name": "dana", "partition": "gpu",
+     "job_state": ["COMPLETED"], "cpus": 8}
+  ],
+  "errors": []
+}`
+
+func writeFixture(t *testing.T, exchanges ...transport.Exchange) transport.Transport {
+	t.Helper()
+	var buf strings.Builder
+	for _, ex := range exchanges {
+		line, err := json.Marshal(ex)
+		if err != nil {
+			t.Fatalf("marshal exchange: %v", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	if err := os.WriteFile(path, []byte(buf.String()), 0o600); err != nil {
+		t.Fatalf("write fixture: %v", err)
+	}
+	tr, err := transport.LoadFixture(path)
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	return tr
+}
+
+func TestCollectUsesJSONOnNewerSlurm(t *testing.T) {
+	tr := writeFixture(t,
+		transport.Exchange{Command: capabilityCommand, Stdout: probeOutput("slurm 23.02.7", modernHelp, modernFields)},
+		transport.Exchange{Command: jsonNodesCommand, Stdout: restNodesJSON + "\n"},
+		transport.Exchange{Command: jsonQueueCommand, Stdout: cliQueueJSON + "\n"},
+		transport.Exchange{Command: jsonPartitionsCommand, Stdout: restPartitionsJSON + "\n"},
+	)
+	c := NewCollector(tr, time.Second)
+
+	snap, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	if c.format != formatJSON || snap.SlurmVersion != "23.02.7" {
+		t.Fatalf("expected json format on 23.02.7, got %s on %q", c.format, snap.SlurmVersion)
+	}
+	if len(snap.Nodes) != 2 || snap.Nodes[1].GPUTotal != 4 || snap.Nodes[1].GPUAlloc != 2 {
+		t.Fatalf("unexpected nodes: %+v", snap.Nodes)
+	}
+	if len(snap.Jobs) != 1 {
+		t.Fatalf("expected the completed job to be dropped, got %+v", snap.Jobs)
+	}
+	job := snap.Jobs[0]
+	if job.Name != "eval|ablation" || job.Reason != "Resources" || job.GPUs != 2 || job.MemMB != 64000 {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+}
+
+func TestCollectUsesTextOnOlderSlurm(t *testing.T) {
+	tr := writeFixture(t,
+		transport.Exchange{Command: capabilityCommand, Stdout: probeOutput("slurm-wlm 20.11.9", "  -O, --Format=fields", modernFields)},
+		transport.Exchange{Command: nodesCommand, Stdout: "NodeName=node001 State=IDLE CPUTot=32 CPUAlloc=0 RealMemory=128000 Partitions=main\n"},
+		transport.Exchange{Command: queueCommand, Stdout: "4001|PENDING|erin|4|8G|N/A|main|prep|Priority|4001|N/A||2026-02-25T09:00:00|N/A|1:00:00\n"},
+		transport.Exchange{Command: partitionsCommand},
+	)
+	c := NewCollector(tr, time.Second)
+
+	snap, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	if c.format != formatText || len(snap.Nodes) != 1 || len(snap.Jobs) != 1 || snap.Jobs[0].Name != "prep" {
+		t.Fatalf("expected the text path, got format=%s snapshot=%+v", c.format, snap)
+	}
+}
+
+func TestCollectFallsBackToTextWhenJSONIsUnusable(t *testing.T) {
+	tr := writeFixture(t,
+		transport.Exchange{Command: capabilityCommand, Stdout: probeOutput("slurm 22.05.2", modernHelp, modernFields)},
+		transport.Exchange{Command: jsonNodesCommand, Stdout: restNodesJSON + "\n"},
+		// Without the data_parser plugin the CLI exits non-zero with a text error.
+		transport.Exchange{Command: jsonQueueCommand, ExitCode: 1, Stderr: "squeue: error: unable to find data_parser plugin", Error: "exit status 1"},
+		transport.Exchange{Command: jsonPartitionsCommand, Stdout: restPartitionsJSON + "\n"},
+		transport.Exchange{Command: nodesCommand, Stdout: "NodeName=node001 State=IDLE CPUTot=32 CPUAlloc=0 RealMemory=128000 Partitions=main\n"},
+		transport.Exchange{Command: queueCommand},
+		transport.Exchange{Command: partitionsCommand},
+	)
+	c := NewCollector(tr, time.Second)
+
+	snap, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	if c.format != formatText || len(snap.Nodes) != 1 {
+		t.Fatalf("expected a text fallback, got format=%s nodes=%d", c.format, len(snap.Nodes))
+	}
+}
+
+func TestCollectFallsBackToTextWhenJSONOptionIsUnknown(t *testing.T) {
+	tr := writeFixture(t,
+		transport.Exchange{Command: capabilityCommand, Stdout: probeOutput("slurm 21.08.8", modernHelp, modernFields)},
+		transport.Exchange{Command: jsonNodesCommand, ExitCode: 1, Stderr: "scontrol: unrecognized option '--json'", Error: "exit status 1"},
+		transport.Exchange{Command: jsonQueueCommand, Stdout: cliQueueJSON + "\n"},
+		transport.Exchange{Command: jsonPartitionsCommand, Stdout: restPartitionsJSON + "\n"},
+		transport.Exchange{Command: nodesCommand, Stdout: "NodeName=node001 State=IDLE CPUTot=32 CPUAlloc=0 RealMemory=128000 Partitions=main\n"},
+		transport.Exchange{Command: queueCommand},
+		transport.Exchange{Command: partitionsCommand},
+	)
+	c := NewCollector(tr, time.Second)
+
+	if _, err := c.Collect(context.Background()); err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	if c.format != formatText {
+		t.Fatalf("expected a text fallback, got format=%s", c.format)
+	}
+}
+
+func TestCollectKeepsJSONThroughControllerOutage(t *testing.T) {
+	outage := transport.Exchange{ExitCode: 1, Stderr: "slurm_load_jobs error: Unable to contact slurm controller (connect failure)", Error: "exit status 1"}
+	nodesDown, queueDown, partitionsDown := outage, outage, outage
+	nodesDown.Command, queueDown.Command, partitionsDown.Command = jsonNodesCommand, jsonQueueCommand, jsonPartitionsCommand
+	tr := writeFixture(t,
+		transport.Exchange{Command: capabilityCommand, Stdout: probeOutput("slurm 23.02.7", modernHelp, modernFields)},
+		nodesDown,
+		transport.Exchange{Command: jsonNodesCommand, Stdout: restNodesJSON + "\n"},
+		queueDown,
+		transport.Exchange{Command: jsonQueueCommand, Stdout: cliQueueJSON + "\n"},
+		partitionsDown,
+		transport.Exchange{Command: jsonPartitionsCommand, Stdout: restPartitionsJSON + "\n"},
+	)
+	c := NewCollector(tr, time.Second)
+
+	if _, err := c.Collect(context.Background()); err == nil {
+		t.Fatalf("expected the outage reported")
+	}
+	if c.format != formatJSON {
+		t.Fatalf("expected json kept through an outage, got %s", c.format)
+	}
+
+	snap, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("collect after the outage: %v", err)
+	}
+	if c.format != formatJSON || len(snap.Nodes) != 2 || len(snap.Jobs) != 1 {
+		t.Fatalf("expected json recovered, got format=%s nodes=%d jobs=%d", c.format, len(snap.Nodes), len(snap.Jobs))
+	}
+}
@@ -2,59 +2,264 @@ package slurm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"slurm_monitor/internal/transport"
 )
 
 const (
+	nodesCommand      = `scontrol show node -o`
+	partitionsCommand = `scontrol show partition -o`
+
 	// Use -r so job arrays are expanded one task per line; this keeps queue/user
 	// counts and requested/allocated CPU/GPU demand accurate for large arrays.
 	// Use tres-alloc instead of %b so GPU demand comes from Slurm's documented
-	// TRES view for both running and pending jobs.
-	combinedCollectCommand = `scontrol show node -o; echo "__SLURM_MONITOR_SPLIT__"; squeue -h -r -O "JobID:|,State:|,UserName:|,NumCPUs:|,MinMemory:|,tres-alloc:|,Partition:|,Name:|,Reason"`
+	// TRES view for both running and pending jobs. Name stays the only free-text
+	// column; see parseJobLine for the field layout.
+	queueCommand = `squeue -h -r -O "JobID:|,State:|,UserName:|,NumCPUs:|,MinMemory:|,tres-alloc:|,Partition:|,Name:|,Reason:|,ArrayJobID:|,ArrayTaskID:|,NodeList:|,SubmitTime:|,StartTime:|,TimeLimit"`
+
+	// gresQueueCommand is queueCommand for squeue builds without tres-alloc.
+	// The gres column ("gpu:a100:2") fills the same position and parseGPUs
+	// reads both forms.
+	gresQueueCommand = `squeue -h -r -O "JobID:|,State:|,UserName:|,NumCPUs:|,MinMemory:|,gres:|,Partition:|,Name:|,Reason:|,ArrayJobID:|,ArrayTaskID:|,NodeList:|,SubmitTime:|,StartTime:|,TimeLimit"`
+
+	// defaultPendingLookupBudget bounds the scontrol batch that fills in
+	// pending GPU and memory requests, so a submission burst of thousands of
+	// jobs is looked up over several cycles instead of stalling one.
+	defaultPendingLookupBudget = 200
 )
 
 type Collector struct {
-	transport                transport.Transport
-	commandTimeout           time.Duration
-	pendingGPUCountByJobRoot map[string]int
+	transport        transport.Transport
+	commandTimeout   time.Duration
+	pendingByJobRoot map[string]jobRequest
+	// pendingLookupBudget caps the pending job roots looked up per cycle.
+	pendingLookupBudget int
+
+	// caps and format are settled by DetectCapabilities. format drops back to
+	// text for good if the JSON output turns out to be unusable.
+	caps       Capabilities
+	format     collectFormat
+	jobDetails jobDetailCache
+	sections   sectionCarry
 }
 
 func NewCollector(t transport.Transport, commandTimeout time.Duration) *Collector {
 	return &Collector{
-		transport:                t,
-		commandTimeout:           commandTimeout,
-		pendingGPUCountByJobRoot: make(map[string]int),
+		transport:           t,
+		commandTimeout:      commandTimeout,
+		pendingByJobRoot:    make(map[string]jobRequest),
+		pendingLookupBudget: defaultPendingLookupBudget,
+		caps:                defaultCapabilities,
 	}
 }
 
+// Collect reads nodes, the queue and partitions with one command each, run
+// side by side, in the format the detected capabilities allow. A source that
+// fails leaves its section stale or failed (see Snapshot.Sections) instead of
+// failing the snapshot; Collect returns an error only when every source
+// fails.
 func (c *Collector) Collect(ctx context.Context) (Snapshot, error) {
-	raw, err := c.runWithTimeout(ctx, combinedCollectCommand)
-	if err != nil {
-		return Snapshot{}, fmt.Errorf("collect snapshot: %w", err)
+	return c.CollectSections(ctx, nil)
+}
+
+// CollectSections is Collect limited to the listed sources (see
+// SourceSections); the others keep their data and status from the previous
+// snapshot. nil collects every source, as does the first collection.
+func (c *Collector) CollectSections(ctx context.Context, sections []Section) (Snapshot, error) {
+	if c.format == formatUnknown {
+		if _, err := c.DetectCapabilities(ctx); err != nil {
+			return Snapshot{}, fmt.Errorf("detect slurm capabilities: %w", err)
+		}
 	}
+	snap, err := c.collect(ctx, c.sections.due(sections))
+	snap.SlurmVersion = c.caps.Version.String()
+	return snap, err
+}
 
-	nodesRaw, queueRaw, err := splitCombinedOutput(raw)
-	if err != nil {
+// sources is one round of per-source results. Each field is written by one
+// goroutine in collectSources.
+type sources struct {
+	nodes    []Node
+	nodesErr error
+
+	jobs     []Job
+	queueErr error
+	// lookupErr is the pending request lookup's error; lookups only run for
+	// text output, which lacks pending GPUs and memory.
+	lookups   bool
+	lookupErr error
+
+	partitions    []Partition
+	partitionsErr error
+}
+
+func (c *Collector) collect(ctx context.Context, due map[Section]bool) (Snapshot, error) {
+	src := c.collectSources(ctx, c.format, due)
+	if c.format == formatJSON && src.jsonUnusable(ctx) {
+		c.format = formatText
+		src = c.collectSources(ctx, formatText, due)
+	}
+
+	snap := Snapshot{
+		Nodes:       src.nodes,
+		Partitions:  src.partitions,
+		Jobs:        src.jobs,
+		CollectedAt: time.Now(),
+	}
+	results := []sectionResult{
+		{section: SectionNodes, err: src.nodesErr, skipped: !due[SectionNodes]},
+		{section: SectionQueue, err: src.queueErr, skipped: !due[SectionQueue]},
+		{section: SectionPartitions, err: src.partitionsErr, skipped: !due[SectionPartitions]},
+	}
+	switch {
+	case !due[SectionQueue] || src.queueErr != nil:
+		// Lookups follow the queue: while its data is carried, so is their
+		// status.
+		results = append(results, sectionResult{section: SectionPendingLookups, skipped: true})
+	default:
+		snap.Queue, snap.Users = SummarizeJobs(src.jobs)
+		c.jobDetails.prune(src.jobs)
+		if src.lookups {
+			results = append(results, sectionResult{section: SectionPendingLookups, err: src.lookupErr})
+		}
+	}
+	if err := c.sections.merge(&snap, results); err != nil {
 		return Snapshot{}, err
 	}
+	applyNodeUsage(snap.Partitions, snap.Nodes)
+	return snap, nil
+}
+
+// collectSources runs the due sources side by side.
+func (c *Collector) collectSources(ctx context.Context, format collectFormat, due map[Section]bool) sources {
+	var (
+		src sources
+		wg  sync.WaitGroup
+	)
+	run := func(section Section, fn func()) {
+		if !due[section] {
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn()
+		}()
+	}
+	run(SectionNodes, func() {
+		src.nodes, src.nodesErr = c.collectNodes(ctx, format)
+	})
+	run(SectionQueue, func() {
+		src.jobs, src.lookupErr, src.queueErr = c.collectQueue(ctx, format)
+	})
+	run(SectionPartitions, func() {
+		src.partitions, src.partitionsErr = c.collectPartitions(ctx, format)
+	})
+	wg.Wait()
+	src.lookups = format == formatText
+	return src
+}
+
+// jsonUnusable reports a source whose JSON command cannot work on this
+// cluster, which switches the collector to text. Any other failure, such as
+// a controller outage, leaves the format alone for the next poll to retry.
+func (s sources) jsonUnusable(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	for _, err := range []error{s.nodesErr, s.queueErr, s.partitionsErr} {
+		if jsonCommandUnusable(err) {
+			return true
+		}
+	}
+	return false
+}
 
-	nodes, err := parseNodeLines(nodesRaw)
+// jsonCommandUnusable reports output that is not the JSON document, or a
+// command that rejects --json or lacks the data_parser plugin.
+func jsonCommandUnusable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, errJSONOutput) {
+		return true
+	}
+	var runErr *transport.RunError
+	if !errors.As(err, &runErr) {
+		return false
+	}
+	stderr := strings.ToLower(runErr.Stderr)
+	if strings.Contains(stderr, "data_parser") {
+		return true
+	}
+	return strings.Contains(stderr, "--json") &&
+		(strings.Contains(stderr, "unrecognized option") || strings.Contains(stderr, "invalid option") || strings.Contains(stderr, "unknown option"))
+}
+
+func (c *Collector) collectNodes(ctx context.Context, format collectFormat) ([]Node, error) {
+	command, parse := nodesCommand, parseNodeLines
+	if format == formatJSON {
+		command, parse = jsonNodesCommand, parseNodesJSON
+	}
+	raw, err := c.runWithTimeout(ctx, command)
 	if err != nil {
-		return Snapshot{}, fmt.Errorf("parse nodes: %w", err)
+		return nil, fmt.Errorf("collect nodes: %w", err)
 	}
-	c.fillPendingGPURequestCache(ctx, queueRaw)
-	queue, users := parseQueueLines(queueRaw, c.pendingGPUCountByJobRoot)
+	nodes, err := parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse nodes: %w", err)
+	}
+	return nodes, nil
+}
 
-	return Snapshot{
-		Nodes:       nodes,
-		Queue:       queue,
-		Users:       users,
-		CollectedAt: time.Now(),
-	}, nil
+func (c *Collector) collectPartitions(ctx context.Context, format collectFormat) ([]Partition, error) {
+	command, parse := partitionsCommand, parsePartitionLines
+	if format == formatJSON {
+		command, parse = jsonPartitionsCommand, parsePartitionsJSON
+	}
+	raw, err := c.runWithTimeout(ctx, command)
+	if err != nil {
+		return nil, fmt.Errorf("collect partitions: %w", err)
+	}
+	parts, err := parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse partitions: %w", err)
+	}
+	return parts, nil
+}
+
+// collectQueue reads the queue and, for text output, looks up pending job
+// requests. A failed lookup is reported separately and leaves the queue
+// usable with the requests cached so far.
+func (c *Collector) collectQueue(ctx context.Context, format collectFormat) (jobs []Job, lookupErr, err error) {
+	if format == formatJSON {
+		raw, err := c.runWithTimeout(ctx, jsonQueueCommand)
+		if err != nil {
+			return nil, nil, fmt.Errorf("collect queue: %w", err)
+		}
+		jobs, err := parseJobsJSON(raw)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse queue: %w", err)
+		}
+		return jobs, nil, nil
+	}
+
+	command := queueCommand
+	if !c.caps.TRESAlloc {
+		command = gresQueueCommand
+	}
+	raw, err := c.runWithTimeout(ctx, command)
+	if err != nil {
+		return nil, nil, fmt.Errorf("collect queue: %w", err)
+	}
+	lookupErr = c.fillPendingGPURequestCache(ctx, raw)
+	return parseJobLines(raw, c.pendingByJobRoot), lookupErr, nil
 }
 
 func (c *Collector) runWithTimeout(ctx context.Context, command string) (string, error) {
@@ -68,56 +273,118 @@ func (c *Collector) runWithTimeout(ctx context.Context, command string) (string,
 	return strings.TrimRight(res.Stdout, "\n"), nil
 }
 
-func (c *Collector) fillPendingGPURequestCache(ctx context.Context, queueRaw string) {
+// fillPendingGPURequestCache looks up the ReqTRES of pending job roots not
+// seen before, in one scontrol batch of at most pendingLookupBudget roots.
+// Roots over the budget wait for later cycles, oldest first. A root scontrol
+// reports as an invalid job id has left the queue and is cached as empty; one
+// the batch says nothing about stays uncached so the next cycle retries it.
+func (c *Collector) fillPendingGPURequestCache(ctx context.Context, queueRaw string) error {
 	roots := extractPendingJobRoots(queueRaw)
 	active := make(map[string]struct{}, len(roots))
+	var missing []string
 	for _, root := range roots {
 		active[root] = struct{}{}
-		if _, ok := c.pendingGPUCountByJobRoot[root]; ok {
+		if _, ok := c.pendingByJobRoot[root]; ok || !isNumericJobID(root) {
 			continue
 		}
-		gpuCount, err := c.jobRootRequestsGPU(ctx, root)
-		if err != nil {
-			continue
-		}
-		c.pendingGPUCountByJobRoot[root] = gpuCount
+		missing = append(missing, root)
 	}
-	for root := range c.pendingGPUCountByJobRoot {
+	for root := range c.pendingByJobRoot {
 		if _, ok := active[root]; !ok {
-			delete(c.pendingGPUCountByJobRoot, root)
+			delete(c.pendingByJobRoot, root)
 		}
 	}
-}
-
-func (c *Collector) jobRootRequestsGPU(ctx context.Context, root string) (int, error) {
-	if !isNumericJobID(root) {
-		return 0, fmt.Errorf("invalid job root id %q", root)
+	if len(missing) > c.pendingLookupBudget {
+		missing = missing[:c.pendingLookupBudget]
 	}
-	raw, err := c.runWithTimeout(ctx, fmt.Sprintf("scontrol show job -o %s", root))
+	if len(missing) == 0 {
+		return nil
+	}
+	raw, err := c.runWithTimeout(ctx, pendingLookupCommand(missing))
 	if err != nil {
-		return 0, err
+		return fmt.Errorf("look up pending jobs: %w", err)
+	}
+	reqs, gone := parseJobRequests(raw)
+	unanswered := 0
+	for _, root := range missing {
+		switch req, ok := reqs[root]; {
+		case ok:
+			c.pendingByJobRoot[root] = req
+		case gone[root]:
+			c.pendingByJobRoot[root] = jobRequest{}
+		default:
+			unanswered++
+		}
 	}
-	reqTRES := extractReqTRES(raw)
-	return parseGPUReq(reqTRES), nil
+	if unanswered > 0 {
+		return fmt.Errorf("look up pending jobs: no record for %d of %d jobs", unanswered, len(missing))
+	}
+	return nil
 }
 
-func extractPendingJobRoots(queueRaw string) []string {
-	lines := strings.Split(queueRaw, "\n")
-	set := make(map[string]struct{})
-	for _, line := range lines {
+// pendingLookupMarker precedes each root's output in a lookup batch, so an
+// error scontrol prints can be tied to the root it is about.
+const pendingLookupMarker = "PendingLookup="
+
+// pendingLookupCommand runs `scontrol show job -o` for each root in one
+// remote invocation. Errors go to stdout after the root's marker instead of
+// failing the whole batch, so jobs that ended in the meantime can be told
+// apart from lookups that failed.
+func pendingLookupCommand(roots []string) string {
+	parts := make([]string, 0, 2*len(roots)+1)
+	for _, root := range roots {
+		parts = append(parts, "echo "+pendingLookupMarker+root, "scontrol show job -o "+root+" 2>&1")
+	}
+	return strings.Join(append(parts, "true"), "; ")
+}
+
+// parseJobRequests reads batched `scontrol show job -o` output into requests
+// per job root. An array root prints a record per task or task range; the
+// first one stands for the root. gone holds the roots scontrol reported as an
+// invalid job id, which left the queue after squeue listed them.
+func parseJobRequests(raw string) (reqs map[string]jobRequest, gone map[string]bool) {
+	reqs = make(map[string]jobRequest)
+	gone = make(map[string]bool)
+	current := ""
+	for _, line := range strings.Split(raw, "\n") {
 		line = strings.TrimSpace(line)
 		if line == "" {
 			continue
 		}
-		parts := strings.SplitN(line, "|", 9)
-		if len(parts) < 9 {
+		if root, ok := strings.CutPrefix(line, pendingLookupMarker); ok {
+			current = root
 			continue
 		}
-		state := strings.ToUpper(strings.TrimSpace(parts[1]))
-		if !strings.Contains(state, "PENDING") {
+		if strings.Contains(line, "Invalid job id") {
+			if current != "" {
+				gone[current] = true
+			}
 			continue
 		}
-		root := rootJobID(parts[0])
+		fields := kvMap(parseKVPairs(line))
+		root := fields["ArrayJobId"]
+		if root == "" {
+			root = rootJobID(fields["JobId"])
+		}
+		if _, ok := reqs[root]; ok || root == "" {
+			continue
+		}
+		reqs[root] = parseJobRequest(fields["ReqTRES"])
+	}
+	return reqs, gone
+}
+
+// extractPendingJobRoots returns the roots of pending jobs, oldest (lowest
+// job ID) first.
+func extractPendingJobRoots(queueRaw string) []string {
+	lines := strings.Split(queueRaw, "\n")
+	set := make(map[string]struct{})
+	for _, line := range lines {
+		job, ok := parseJobLine(line)
+		if !ok || !strings.Contains(job.State, "PENDING") {
+			continue
+		}
+		root := job.RootID()
 		if root == "" {
 			continue
 		}
@@ -128,22 +395,15 @@ func extractPendingJobRoots(queueRaw string) []string {
 	for root := range set {
 		out = append(out, root)
 	}
+	sort.Slice(out, func(i, j int) bool {
+		if len(out[i]) != len(out[j]) {
+			return len(out[i]) < len(out[j])
+		}
+		return out[i] < out[j]
+	})
 	return out
 }
 
-func extractReqTRES(raw string) string {
-	idx := strings.Index(raw, "ReqTRES=")
-	if idx < 0 {
-		return ""
-	}
-	tail := raw[idx+len("ReqTRES="):]
-	fields := strings.Fields(tail)
-	if len(fields) == 0 {
-		return ""
-	}
-	return fields[0]
-}
-
 func isNumericJobID(id string) bool {
 	id = strings.TrimSpace(id)
 	if id == "" {
@@ -156,14 +416,3 @@ func isNumericJobID(id string) bool {
 	}
 	return true
 }
-
-func splitCombinedOutput(raw string) (nodes string, queue string, err error) {
-	const marker = "__SLURM_MONITOR_SPLIT__"
-	parts := strings.SplitN(raw, marker, 2)
-	if len(parts) != 2 {
-		return "", "", fmt.Errorf("unexpected collector output format: split marker missing")
-	}
-	nodes = strings.TrimSpace(parts[0])
-	queue = strings.TrimSpace(parts[1])
-	return nodes, queue, nil
-}
@@ -4,52 +4,338 @@ import (
 	"context"
 	"strings"
 	"testing"
-)
+	"time"
 
-func TestCombinedCollectCommandExpandsArrayTasks(t *testing.T) {
-	if !strings.Contains(combinedCollectCommand, "squeue -h -r ") {
-		t.Fatalf("combined collect command must include squeue -r to expand arrays: %q", combinedCollectCommand)
-	}
-	if !strings.Contains(combinedCollectCommand, "tres-alloc") {
-		t.Fatalf("combined collect command must include tres-alloc for documented GPU totals: %q", combinedCollectCommand)
-	}
-	if strings.Contains(combinedCollectCommand, "%b") {
-		t.Fatalf("combined collect command must not rely on %%b for GPU totals: %q", combinedCollectCommand)
-	}
-}
+	"slurm_monitor/internal/transport"
+)
 
-func TestSplitCombinedOutput(t *testing.T) {
-	raw := "node-a\n__SLURM_MONITOR_SPLIT__\n1001|PENDING|alice|1|4G|N/A|gpu|job|Priority"
-	nodes, queue, err := splitCombinedOutput(raw)
-	if err != nil {
-		t.Fatalf("expected no error, got %v", err)
+func TestQueueCommandExpandsArrayTasks(t *testing.T) {
+	if !strings.Contains(queueCommand, "squeue -h -r ") {
+		t.Fatalf("queue command must include squeue -r to expand arrays: %q", queueCommand)
 	}
-	if nodes != "node-a" {
-		t.Fatalf("unexpected nodes payload: %q", nodes)
+	if !strings.Contains(queueCommand, "tres-alloc") {
+		t.Fatalf("queue command must include tres-alloc for documented GPU totals: %q", queueCommand)
 	}
-	if queue != "1001|PENDING|alice|1|4G|N/A|gpu|job|Priority" {
-		t.Fatalf("unexpected queue payload: %q", queue)
+	if strings.Contains(queueCommand, "%b") {
+		t.Fatalf("queue command must not rely on %%b for GPU totals: %q", queueCommand)
 	}
 }
 
 func TestFillPendingGPURequestCachePrunesStaleRoots(t *testing.T) {
 	c := &Collector{
-		pendingGPUCountByJobRoot: map[string]int{
-			"1001": 2,
-			"2002": 0,
+		pendingByJobRoot: map[string]jobRequest{
+			"1001": {GPUs: 2},
+			"2002": {},
 		},
 	}
 
 	queueRaw := "2002_1|PENDING|alice|1|4G|N/A|gpu|job|Priority"
 	c.fillPendingGPURequestCache(context.Background(), queueRaw)
 
-	if len(c.pendingGPUCountByJobRoot) != 1 {
-		t.Fatalf("expected exactly one cached root after prune, got %d", len(c.pendingGPUCountByJobRoot))
+	if len(c.pendingByJobRoot) != 1 {
+		t.Fatalf("expected exactly one cached root after prune, got %d", len(c.pendingByJobRoot))
 	}
-	if _, ok := c.pendingGPUCountByJobRoot["2002"]; !ok {
+	if _, ok := c.pendingByJobRoot["2002"]; !ok {
 		t.Fatalf("expected active root to remain cached")
 	}
-	if _, ok := c.pendingGPUCountByJobRoot["1001"]; ok {
+	if _, ok := c.pendingByJobRoot["1001"]; ok {
 		t.Fatalf("expected stale root to be pruned")
 	}
 }
+
+func TestFillPendingGPURequestCacheBatchesLookupsWithinBudget(t *testing.T) {
+	tr := &scriptedTransport{reply: lookupReply(func(root string) string {
+		if root == "103" {
+			// Left the queue between squeue and scontrol.
+			return "slurm_load_jobs error: Invalid job id specified\n"
+		}
+		return "JobId=" + root + "_1 ArrayJobId=" + root + " ArrayTaskId=1 JobState=PENDING ReqTRES=cpu=4,mem=32G,node=1,gres/gpu:h100=2\n" +
+			"JobId=" + root + "_2 ArrayJobId=" + root + " ArrayTaskId=2 JobState=RUNNING ReqTRES=cpu=4,mem=1G,node=1\n"
+	})}
+	c := NewCollector(tr, time.Second)
+	c.pendingLookupBudget = 2
+	queueRaw := strings.Join([]string{
+		"103_1|PENDING|alice|4|8G|N/A|gpu|job|Priority",
+		"1000_1|PENDING|alice|4|8G|N/A|gpu|job|Priority",
+		"101_1|PENDING|alice|4|8G|N/A|gpu|job|Priority",
+		"101_2|PENDING|alice|4|8G|N/A|gpu|job|Priority",
+		"102_1|PENDING|bob|4|8G|N/A|gpu|job|Priority",
+	}, "\n")
+
+	c.fillPendingGPURequestCache(context.Background(), queueRaw)
+	if len(tr.commands) != 1 || !strings.Contains(tr.commands[0], "-o 101 ") || !strings.Contains(tr.commands[0], "-o 102 ") {
+		t.Fatalf("expected one batch for the two oldest roots, got %q", tr.commands)
+	}
+	if req := c.pendingByJobRoot["101"]; req.GPUs != 2 || req.MemMB != 32768 || len(req.GPUTypes) != 1 || req.GPUTypes[0].Type != "h100" {
+		t.Fatalf("expected the first record to stand for root 101, got %+v", req)
+	}
+	if _, ok := c.pendingByJobRoot["1000"]; ok {
+		t.Fatalf("expected roots over the budget deferred")
+	}
+
+	c.fillPendingGPURequestCache(context.Background(), queueRaw)
+	if len(tr.commands) != 2 || !strings.Contains(tr.commands[1], "-o 103 ") || !strings.Contains(tr.commands[1], "-o 1000 ") {
+		t.Fatalf("expected the deferred roots in the next batch, got %q", tr.commands)
+	}
+	if req, ok := c.pendingByJobRoot["103"]; !ok || req.GPUs != 0 {
+		t.Fatalf("expected a root scontrol no longer knows cached as empty, got %+v ok=%v", req, ok)
+	}
+
+	c.fillPendingGPURequestCache(context.Background(), queueRaw)
+	if len(tr.commands) != 2 {
+		t.Fatalf("expected no lookup once every root is cached, got %q", tr.commands)
+	}
+}
+
+func TestFillPendingGPURequestCacheRetriesUnansweredRoots(t *testing.T) {
+	answer := false
+	tr := &scriptedTransport{reply: lookupReply(func(root string) string {
+		if root == "201" && !answer {
+			return "" // output lost, for example to a transient scontrol failure
+		}
+		return "JobId=" + root + " JobState=PENDING ReqTRES=cpu=4,mem=8G,node=1,gres/gpu=1\n"
+	})}
+	c := NewCollector(tr, time.Second)
+	queueRaw := "200|PENDING|alice|4|8G|N/A|gpu|job|Priority\n201|PENDING|alice|4|8G|N/A|gpu|job|Priority"
+
+	if err := c.fillPendingGPURequestCache(context.Background(), queueRaw); err == nil {
+		t.Fatalf("expected the unanswered root reported")
+	}
+	if _, ok := c.pendingByJobRoot["201"]; ok {
+		t.Fatalf("expected the unanswered root left uncached")
+	}
+	if req := c.pendingByJobRoot["200"]; req.GPUs != 1 {
+		t.Fatalf("expected the answered root cached, got %+v", req)
+	}
+
+	answer = true
+	if err := c.fillPendingGPURequestCache(context.Background(), queueRaw); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(tr.commands) != 2 || !strings.Contains(tr.commands[1], "-o 201 ") || strings.Contains(tr.commands[1], "-o 200 ") {
+		t.Fatalf("expected only the unanswered root looked up again, got %q", tr.commands)
+	}
+	if req := c.pendingByJobRoot["201"]; req.GPUs != 1 {
+		t.Fatalf("expected the retried root cached, got %+v", req)
+	}
+}
+
+// lookupReply answers a pending lookup batch the way the shell would, with
+// each root's marker followed by what scontrol prints for it.
+func lookupReply(show func(root string) string) func(string) string {
+	return func(command string) string {
+		var b strings.Builder
+		for _, part := range strings.Split(command, "; ") {
+			if marker, ok := strings.CutPrefix(part, "echo "); ok {
+				b.WriteString(marker + "\n")
+				continue
+			}
+			if root, ok := strings.CutPrefix(part, "scontrol show job -o "); ok {
+				b.WriteString(show(strings.TrimSuffix(root, " 2>&1")))
+			}
+		}
+		return b.String()
+	}
+}
+
+func TestCollectCarriesJobsOnSnapshot(t *testing.T) {
+	c := NewCollector(sourceTransport{
+		nodes: "NodeName=node001 State=MIXED CPUTot=64 CPUAlloc=8 RealMemory=256000 Partitions=main",
+		queue: strings.Join([]string{
+			"1001|RUNNING|alice|8|20G|cpu=8,mem=20G,gres/gpu=1|main|jobA|None|1001|N/A|node001|2026-02-25T09:00:00|2026-02-25T09:01:00|2:00:00",
+			"1002_3|PENDING|bob|4|10G|N/A|main|jobB|Priority|1002|3||2026-02-25T09:30:00|N/A|30:00",
+		}, "\n"),
+	}, time.Second)
+
+	snap, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(snap.Jobs) != 2 {
+		t.Fatalf("expected 2 jobs on snapshot, got %d", len(snap.Jobs))
+	}
+	if snap.Jobs[1].ArrayJobID != "1002" || snap.Jobs[1].ArrayTaskID != "3" {
+		t.Fatalf("expected array task identity, got %+v", snap.Jobs[1])
+	}
+	if snap.Queue.Running != 1 || snap.Queue.Pending != 1 || len(snap.Users) != 2 {
+		t.Fatalf("expected summaries derived from jobs, got queue=%+v users=%d", snap.Queue, len(snap.Users))
+	}
+}
+
+func TestCollectKeepsOtherSectionsWhenOneSourceFails(t *testing.T) {
+	tr := &sourceTransport{
+		nodes:      "NodeName=node001 State=MIXED CPUTot=64 CPUAlloc=8 RealMemory=256000 Partitions=main",
+		queue:      "1001|RUNNING|alice|8|20G|cpu=8,mem=20G|main|jobA|None|1001|N/A|node001|2026-02-25T09:00:00|2026-02-25T09:01:00|2:00:00",
+		partitions: "PartitionName=main State=UP Nodes=node001 TotalCPUs=64 TotalNodes=1",
+	}
+	c := NewCollector(tr, time.Second)
+	first, err := c.Collect(context.Background())
+	if err != nil || first.Partial() {
+		t.Fatalf("expected a complete snapshot, got %v sections=%+v", err, first.Sections)
+	}
+
+	tr.queueErr = true
+	tr.nodes = "NodeName=node001 State=MIXED CPUTot=64 CPUAlloc=16 RealMemory=256000 Partitions=main"
+	second, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("expected a partial snapshot instead of an error, got %v", err)
+	}
+	queue := second.SectionStatus(SectionQueue)
+	if queue.State != SectionStale || !queue.UpdatedAt.Equal(first.CollectedAt) || !strings.Contains(queue.Error, "collect queue") {
+		t.Fatalf("expected the queue stale since the first collection, got %+v", queue)
+	}
+	if len(second.Jobs) != 1 || second.Queue.Running != 1 {
+		t.Fatalf("expected the last queue carried over, got jobs=%d queue=%+v", len(second.Jobs), second.Queue)
+	}
+	if second.SectionStatus(SectionNodes).State != SectionFresh || second.Nodes[0].CPUAlloc != 16 {
+		t.Fatalf("expected fresh nodes, got %+v", second.Nodes)
+	}
+	if p := second.Partitions[0]; p.AllocCPUs != 16 {
+		t.Fatalf("expected partition usage from the fresh nodes only, got %+v", p)
+	}
+
+	tr.nodesErr, tr.partitionsErr = true, true
+	if _, err := c.Collect(context.Background()); err == nil || !transport.IsRetryable(err) {
+		t.Fatalf("expected a retryable error when every source fails, got %v", err)
+	}
+}
+
+func TestCollectCarriesLookupStatusWithTheQueue(t *testing.T) {
+	tr := &sourceTransport{
+		nodes:     "NodeName=node001 State=MIXED CPUTot=64 CPUAlloc=8 RealMemory=256000 Partitions=main",
+		queue:     "1002|PENDING|bob|4|10G|N/A|main|jobB|Priority|1002|N/A||2026-02-25T09:30:00|N/A|30:00",
+		lookupErr: true,
+	}
+	c := NewCollector(tr, time.Second)
+	first, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	lookups := first.SectionStatus(SectionPendingLookups)
+	if lookups.State != SectionFailed || !strings.Contains(lookups.Error, "look up pending jobs") {
+		t.Fatalf("expected failed pending lookups, got %+v", lookups)
+	}
+
+	tr.queueErr = true
+	second, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("expected a partial snapshot, got %v", err)
+	}
+	if got := second.SectionStatus(SectionPendingLookups); got != lookups {
+		t.Fatalf("expected the lookup status carried with the queue, got %+v", got)
+	}
+}
+
+func TestCollectSectionsCarriesSourcesThatAreNotDue(t *testing.T) {
+	tr := &sourceTransport{
+		nodes:      "NodeName=node001 State=MIXED CPUTot=64 CPUAlloc=8 RealMemory=256000 Partitions=main",
+		queue:      "1001|RUNNING|alice|8|20G|cpu=8,mem=20G|main|jobA|None|1001|N/A|node001|2026-02-25T09:00:00|2026-02-25T09:01:00|2:00:00",
+		partitions: "PartitionName=main State=UP Nodes=node001 TotalCPUs=64 TotalNodes=1",
+	}
+	c := NewCollector(tr, time.Second)
+	// The first collection reads every source even when asked for one.
+	first, err := c.CollectSections(context.Background(), []Section{SectionQueue})
+	if err != nil || len(first.Nodes) != 1 || len(first.Partitions) != 1 {
+		t.Fatalf("expected a full first snapshot, got %v nodes=%d partitions=%d", err, len(first.Nodes), len(first.Partitions))
+	}
+
+	// Sources that are not due are not run, so their failures do not show.
+	tr.nodesErr, tr.partitionsErr = true, true
+	tr.queue = ""
+	second, err := c.CollectSections(context.Background(), []Section{SectionQueue})
+	if err != nil || second.Partial() {
+		t.Fatalf("expected only the queue collected, got %v sections=%+v", err, second.Sections)
+	}
+	if len(second.Jobs) != 0 || len(second.Nodes) != 1 || second.Partitions[0].AllocCPUs != 8 {
+		t.Fatalf("expected a fresh queue with carried nodes and partitions, got jobs=%d nodes=%d partitions=%+v", len(second.Jobs), len(second.Nodes), second.Partitions)
+	}
+	if nodes := second.SectionStatus(SectionNodes); !nodes.UpdatedAt.Equal(first.CollectedAt) {
+		t.Fatalf("expected carried nodes to keep their collection time, got %+v", nodes)
+	}
+}
+
+func TestCollectSectionsKeepsPublishingWhenOnlyTheDueSourceFails(t *testing.T) {
+	tr := &sourceTransport{
+		nodes:      "NodeName=node001 State=MIXED CPUTot=64 CPUAlloc=8 RealMemory=256000 Partitions=main",
+		queue:      "1001|RUNNING|alice|8|20G|cpu=8,mem=20G|main|jobA|None|1001|N/A|node001|2026-02-25T09:00:00|2026-02-25T09:01:00|2:00:00",
+		partitions: "PartitionName=main State=UP Nodes=node001 TotalCPUs=64 TotalNodes=1",
+	}
+	c := NewCollector(tr, time.Second)
+	first, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+
+	tr.queueErr = true
+	second, err := c.CollectSections(context.Background(), []Section{SectionQueue})
+	if err != nil {
+		t.Fatalf("expected a partial snapshot when the only due source fails, got %v", err)
+	}
+	queue := second.SectionStatus(SectionQueue)
+	if queue.State != SectionStale || !queue.UpdatedAt.Equal(first.CollectedAt) || len(second.Jobs) != 1 {
+		t.Fatalf("expected the queue stale and carried, got %+v jobs=%d", queue, len(second.Jobs))
+	}
+	if second.SectionStatus(SectionNodes).State != SectionFresh || len(second.Nodes) != 1 {
+		t.Fatalf("expected carried nodes to keep their status, got %+v", second.Sections)
+	}
+}
+
+func TestCollectFromTransportFixture(t *testing.T) {
+	tr := writeFixture(t,
+		transport.Exchange{Command: nodesCommand, Stdout: "NodeName=gpu001 State=ALLOCATED CPUTot=64 CPUAlloc=64 RealMemory=512000 CfgTRES=cpu=64,mem=500G,gres/gpu=4 AllocTRES=cpu=64,gres/gpu=4 Partitions=gpu\n"},
+		transport.Exchange{Command: queueCommand, Stdout: "2001|RUNNING|carol|64|100G|cpu=64,mem=100G,gres/gpu=4|gpu|train|None|2001|N/A|gpu001|2026-02-25T09:00:00|2026-02-25T09:01:00|1-00:00:00\n"},
+		transport.Exchange{Command: partitionsCommand, Stdout: "PartitionName=gpu State=UP Nodes=gpu001 TotalCPUs=64 TotalNodes=1\n"},
+	)
+
+	snap, err := NewCollector(tr, time.Second).Collect(context.Background())
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(snap.Nodes) != 1 || snap.Nodes[0].GPUAlloc != 4 || len(snap.Jobs) != 1 || snap.Jobs[0].GPUs != 4 {
+		t.Fatalf("expected fixture output parsed like live output, got nodes=%+v jobs=%+v", snap.Nodes, snap.Jobs)
+	}
+}
+
+type scriptedTransport struct {
+	reply    func(command string) string
+	commands []string
+}
+
+func (s *scriptedTransport) Run(_ context.Context, command string) (transport.RunResult, error) {
+	s.commands = append(s.commands, command)
+	return transport.RunResult{Stdout: s.reply(command)}, nil
+}
+
+func (s *scriptedTransport) Describe() string {
+	return "scripted"
+}
+
+// sourceTransport answers each collect command with its section. An error
+// set for a section fails that command with a timeout.
+type sourceTransport struct {
+	nodes, queue, partitions          string
+	nodesErr, queueErr, partitionsErr bool
+	lookupErr                         bool
+}
+
+func (s sourceTransport) Run(_ context.Context, command string) (transport.RunResult, error) {
+	out, fail := "", false
+	switch {
+	case strings.HasPrefix(command, "scontrol show node"):
+		out, fail = s.nodes, s.nodesErr
+	case strings.HasPrefix(command, "squeue -h"):
+		out, fail = s.queue, s.queueErr
+	case strings.HasPrefix(command, "scontrol show partition"):
+		out, fail = s.partitions, s.partitionsErr
+	case strings.HasPrefix(command, "scontrol show job"):
+		fail = s.lookupErr
+	}
+	if fail {
+		return transport.RunResult{}, &transport.RunError{Command: command, Timeout: true, Err: context.DeadlineExceeded}
+	}
+	return transport.RunResult{Stdout: out}, nil
+}
+
+func (s sourceTransport) Describe() string {
+	return "sources"
+}
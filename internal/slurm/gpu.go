@@ -0,0 +1,232 @@
+package slurm
+
+import (
+	"regexp"
+	"sort"
+)
+
+// GPUCount is a count of GPUs of one model. Type is "" for GPUs Slurm does
+// not name a model for.
+type GPUCount struct {
+	Type  string
+	Count int
+}
+
+// GPUTypeUsage is allocated and configured GPUs of one model, on a node or
+// across the cluster.
+type GPUTypeUsage struct {
+	Type  string
+	Alloc int
+	Total int
+}
+
+// GPUTypeLoad is running and pending GPU demand for one model. Jobs count
+// under every model they hold or request.
+type GPUTypeLoad struct {
+	Type        string
+	RunningGPU  int
+	PendingGPU  int
+	RunningJobs int
+	PendingJobs int
+}
+
+// gpuReqRe matches TRES entries (gres/gpu=8, gres/gpu:a100=8) and gres
+// entries (gpu:2, gpu:a100:2(S:0-1)). The separator before the count tells
+// them apart. gres/gpumem and gres/gpuutil do not match.
+var gpuReqRe = regexp.MustCompile(`gpu(?::([a-zA-Z0-9_.-]+))?([:=])([0-9]+)`)
+
+// parseGPUs reads the GPU total and per-model counts from a TRES string, a
+// gres column or a mix. In TRES form Slurm reports typed GPUs under both
+// gres/gpu and gres/gpu:<type>, so the bare key is the total and the typed
+// keys break it down; gres entries are separate requests and add up. typed
+// holds only named models, so any remainder of total is untyped.
+func parseGPUs(raw string) (total int, typed []GPUCount) {
+	if raw == "" || raw == "N/A" {
+		return 0, nil
+	}
+	var tresUntyped, tresTyped, gresTotal int
+	for _, m := range gpuReqRe.FindAllStringSubmatch(raw, -1) {
+		gpuType, tres, n := m[1], m[2] == "=", parseInt(m[3])
+		switch {
+		case gpuType == "" && tres:
+			tresUntyped += n
+			continue
+		case tres:
+			tresTyped += n
+		default:
+			gresTotal += n
+		}
+		if gpuType != "" {
+			typed = addGPUCount(typed, gpuType, n)
+		}
+	}
+	return max(tresUntyped, tresTyped) + gresTotal, typed
+}
+
+func addGPUCount(counts []GPUCount, gpuType string, n int) []GPUCount {
+	for i := range counts {
+		if counts[i].Type == gpuType {
+			counts[i].Count += n
+			return counts
+		}
+	}
+	return append(counts, GPUCount{Type: gpuType, Count: n})
+}
+
+func parseGPUCount(tres string) int {
+	total, _ := parseGPUs(tres)
+	return total
+}
+
+func parseGPUReq(raw string) int {
+	total, _ := parseGPUs(raw)
+	return total
+}
+
+// withUntyped returns counts plus an untyped entry for whatever part of total
+// the typed counts do not cover.
+func withUntyped(total int, typed []GPUCount) []GPUCount {
+	rest := total
+	for _, c := range typed {
+		rest -= c.Count
+	}
+	if rest <= 0 {
+		return typed
+	}
+	return append(append(make([]GPUCount, 0, len(typed)+1), typed...), GPUCount{Count: rest})
+}
+
+// nodeGPUTypes breaks a node's GPUs down by model. Typed TRES keys are used
+// when the cluster accounts for them; otherwise the Gres and GresUsed fields,
+// which always name the model when one is configured.
+func nodeGPUTypes(fields map[string]string, alloc, total int) []GPUTypeUsage {
+	if total == 0 {
+		return nil
+	}
+	_, totals := parseGPUs(fields["CfgTRES"])
+	_, allocs := parseGPUs(fields["AllocTRES"])
+	if len(totals) == 0 {
+		_, totals = parseGPUs(nullableField(fields["Gres"]))
+		_, allocs = parseGPUs(nullableField(fields["GresUsed"]))
+	}
+	var out []GPUTypeUsage
+	for _, c := range withUntyped(total, totals) {
+		out = append(out, GPUTypeUsage{Type: c.Type, Total: c.Count})
+	}
+	for _, c := range withUntyped(alloc, allocs) {
+		i := 0
+		for i < len(out) && out[i].Type != c.Type {
+			i++
+		}
+		if i == len(out) {
+			out = append(out, GPUTypeUsage{Type: c.Type})
+		}
+		out[i].Alloc += c.Count
+	}
+	return out
+}
+
+// GPUsByType returns the job's GPUs per model, including an untyped entry
+// for GPUs requested without one.
+func (j Job) GPUsByType() []GPUCount {
+	return withUntyped(j.GPUs, j.GPUTypes)
+}
+
+// GPUTypes sums node GPUs per model, largest total first.
+func (s Snapshot) GPUTypes() []GPUTypeUsage {
+	byType := make(map[string]*GPUTypeUsage)
+	for _, n := range s.Nodes {
+		for _, u := range n.GPUTypes {
+			agg, ok := byType[u.Type]
+			if !ok {
+				agg = &GPUTypeUsage{Type: u.Type}
+				byType[u.Type] = agg
+			}
+			agg.Alloc += u.Alloc
+			agg.Total += u.Total
+		}
+	}
+	out := make([]GPUTypeUsage, 0, len(byType))
+	for _, u := range byType {
+		out = append(out, *u)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Total != out[j].Total {
+			return out[i].Total > out[j].Total
+		}
+		return out[i].Type < out[j].Type
+	})
+	return out
+}
+
+// addGPULoad folds a running or pending job's GPUs into per-model demand, n
+// times for a record standing for n array tasks.
+func addGPULoad(m map[string]*GPUTypeLoad, job Job, n int, running bool) {
+	for _, c := range job.GPUsByType() {
+		load, ok := m[c.Type]
+		if !ok {
+			load = &GPUTypeLoad{Type: c.Type}
+			m[c.Type] = load
+		}
+		if running {
+			load.RunningGPU += c.Count * n
+			load.RunningJobs += n
+		} else {
+			load.PendingGPU += c.Count * n
+			load.PendingJobs += n
+		}
+	}
+}
+
+func mapToGPUTypeLoads(m map[string]*GPUTypeLoad) []GPUTypeLoad {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make([]GPUTypeLoad, 0, len(m))
+	for _, l := range m {
+		out = append(out, *l)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i].RunningGPU+out[i].PendingGPU, out[j].RunningGPU+out[j].PendingGPU
+		if a != b {
+			return a > b
+		}
+		return out[i].Type < out[j].Type
+	})
+	return out
+}
+
+// GPUTypeStats is one GPU model's capacity next to its queue demand.
+type GPUTypeStats struct {
+	Type        string
+	Alloc       int
+	Total       int
+	RunningGPU  int
+	PendingGPU  int
+	RunningJobs int
+	PendingJobs int
+}
+
+// GPUTypeStats joins GPUTypes with Queue.ByGPUType: models with capacity
+// first, largest first, then models only jobs ask for.
+func (s Snapshot) GPUTypeStats() []GPUTypeStats {
+	capacity := s.GPUTypes()
+	out := make([]GPUTypeStats, 0, len(capacity)+len(s.Queue.ByGPUType))
+	index := make(map[string]int, len(capacity))
+	for _, u := range capacity {
+		index[u.Type] = len(out)
+		out = append(out, GPUTypeStats{Type: u.Type, Alloc: u.Alloc, Total: u.Total})
+	}
+	for _, l := range s.Queue.ByGPUType {
+		i, ok := index[l.Type]
+		if !ok {
+			i = len(out)
+			out = append(out, GPUTypeStats{Type: l.Type})
+		}
+		out[i].RunningGPU = l.RunningGPU
+		out[i].PendingGPU = l.PendingGPU
+		out[i].RunningJobs = l.RunningJobs
+		out[i].PendingJobs = l.PendingJobs
+	}
+	return out
+}
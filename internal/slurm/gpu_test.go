@@ -0,0 +1,119 @@
+package slurm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGPUsSeparatesTypes(t *testing.T) {
+	tests := []struct {
+		in    string
+		total int
+		typed []GPUCount
+	}{
+		{in: "cpu=64,mem=500G,gres/gpu=8,gres/gpu:a100=8", total: 8, typed: []GPUCount{{Type: "a100", Count: 8}}},
+		{in: "gres/gpu=8,gres/gpu:a100=4,gres/gpu:h100=4", total: 8, typed: []GPUCount{{Type: "a100", Count: 4}, {Type: "h100", Count: 4}}},
+		{in: "gres/gpu=4,gres/gpumem=160G,gres/gpuutil=40", total: 4},
+		{in: "gpu:l40s:2(S:0-1)", total: 2, typed: []GPUCount{{Type: "l40s", Count: 2}}},
+		{in: "gpu:a100:2(S:0),gpu:h100:1(S:1)", total: 3, typed: []GPUCount{{Type: "a100", Count: 2}, {Type: "h100", Count: 1}}},
+		{in: "gres/gpu:1g.10gb=2", total: 2, typed: []GPUCount{{Type: "1g.10gb", Count: 2}}},
+		{in: "N/A", total: 0},
+	}
+	for _, tt := range tests {
+		total, typed := parseGPUs(tt.in)
+		if total != tt.total || !reflect.DeepEqual(typed, tt.typed) {
+			t.Fatalf("parseGPUs(%q) = %d %v, want %d %v", tt.in, total, typed, tt.total, tt.typed)
+		}
+	}
+}
+
+func TestNodeGPUTypesFallsBackToGres(t *testing.T) {
+	typed, err := parseNodeLine("NodeName=g1 State=MIXED CPUTot=64 CfgTRES=cpu=64,gres/gpu=8,gres/gpu:a100=4,gres/gpu:h100=4 AllocTRES=cpu=8,gres/gpu=3,gres/gpu:h100=3")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []GPUTypeUsage{{Type: "a100", Total: 4}, {Type: "h100", Alloc: 3, Total: 4}}
+	if typed.GPUTotal != 8 || typed.GPUAlloc != 3 || !reflect.DeepEqual(typed.GPUTypes, want) {
+		t.Fatalf("unexpected typed node: total=%d alloc=%d types=%+v", typed.GPUTotal, typed.GPUAlloc, typed.GPUTypes)
+	}
+
+	// Clusters that only account gres/gpu still name the model in Gres.
+	gres, err := parseNodeLine("NodeName=g2 State=MIXED CPUTot=64 Gres=gpu:l40s:4(S:0-1) GresUsed=gpu:l40s:1(IDX:0) CfgTRES=cpu=64,gres/gpu=4 AllocTRES=cpu=8,gres/gpu=1")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if want := []GPUTypeUsage{{Type: "l40s", Alloc: 1, Total: 4}}; !reflect.DeepEqual(gres.GPUTypes, want) {
+		t.Fatalf("expected types from Gres, got %+v", gres.GPUTypes)
+	}
+
+	untyped, err := parseNodeLine("NodeName=g3 State=IDLE CPUTot=64 Gres=gpu:2 CfgTRES=cpu=64,gres/gpu=2")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if want := []GPUTypeUsage{{Total: 2}}; !reflect.DeepEqual(untyped.GPUTypes, want) {
+		t.Fatalf("expected one untyped entry, got %+v", untyped.GPUTypes)
+	}
+}
+
+func TestSummarizeJobsSplitsGPUDemandByType(t *testing.T) {
+	jobs := []Job{
+		{ID: "1", State: "RUNNING", User: "alice", GPUs: 4, GPUTypes: []GPUCount{{Type: "a100", Count: 4}}},
+		{ID: "2", State: "PENDING", User: "alice", GPUs: 8, GPUTypes: []GPUCount{{Type: "h100", Count: 8}}},
+		{ID: "3", State: "PENDING", User: "bob", GPUs: 2},
+		{ID: "4", State: "PENDING", User: "bob", GPUs: 3, GPUTypes: []GPUCount{{Type: "a100", Count: 1}}},
+		{ID: "5", State: "COMPLETING", User: "bob", GPUs: 1, GPUTypes: []GPUCount{{Type: "a100", Count: 1}}},
+		{ID: "6", State: "CANCELLED", User: "bob", GPUs: 1, GPUTypes: []GPUCount{{Type: "a100", Count: 1}}},
+	}
+	queue, users := SummarizeJobs(jobs)
+
+	want := []GPUTypeLoad{
+		{Type: "h100", PendingGPU: 8, PendingJobs: 1},
+		{Type: "a100", RunningGPU: 5, PendingGPU: 1, RunningJobs: 2, PendingJobs: 1},
+		{Type: "", PendingGPU: 4, PendingJobs: 2},
+	}
+	if !reflect.DeepEqual(queue.ByGPUType, want) {
+		t.Fatalf("unexpected queue gpu types:\n got %+v\nwant %+v", queue.ByGPUType, want)
+	}
+	for _, u := range users {
+		if u.User != "bob" {
+			continue
+		}
+		want := []GPUTypeLoad{
+			{Type: "", PendingGPU: 4, PendingJobs: 2},
+			{Type: "a100", RunningGPU: 1, PendingGPU: 1, RunningJobs: 1, PendingJobs: 1},
+		}
+		if !reflect.DeepEqual(u.ByGPUType, want) {
+			t.Fatalf("unexpected bob gpu types:\n got %+v\nwant %+v", u.ByGPUType, want)
+		}
+	}
+}
+
+func TestSnapshotGPUTypesSumsNodes(t *testing.T) {
+	snap := Snapshot{Nodes: []Node{
+		{GPUTypes: []GPUTypeUsage{{Type: "a100", Alloc: 2, Total: 4}}},
+		{GPUTypes: []GPUTypeUsage{{Type: "h100", Alloc: 8, Total: 8}}},
+		{GPUTypes: []GPUTypeUsage{{Type: "a100", Alloc: 1, Total: 4}}},
+		{},
+	}}
+	want := []GPUTypeUsage{{Type: "a100", Alloc: 3, Total: 8}, {Type: "h100", Alloc: 8, Total: 8}}
+	if got := snap.GPUTypes(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected cluster gpu types: %+v", got)
+	}
+}
+
+func TestSnapshotGPUTypeStatsJoinsCapacityAndDemand(t *testing.T) {
+	snap := Snapshot{
+		Nodes: []Node{{GPUTypes: []GPUTypeUsage{{Type: "a100", Alloc: 2, Total: 4}}}},
+		Queue: QueueSummary{ByGPUType: []GPUTypeLoad{
+			{Type: "h100", PendingGPU: 8, PendingJobs: 1},
+			{Type: "a100", RunningGPU: 2, RunningJobs: 1},
+		}},
+	}
+	want := []GPUTypeStats{
+		{Type: "a100", Alloc: 2, Total: 4, RunningGPU: 2, RunningJobs: 1},
+		{Type: "h100", PendingGPU: 8, PendingJobs: 1},
+	}
+	if got := snap.GPUTypeStats(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected stats:\n got %+v\nwant %+v", got, want)
+	}
+}
@@ -0,0 +1,162 @@
+package slurm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxHostlistExpansion bounds ExpandHostlist so a malformed or huge range
+// cannot allocate without limit.
+const maxHostlistExpansion = 1 << 16
+
+// ExpandHostlist expands a Slurm hostlist expression such as
+// "gpu-a[01-03,07],cpu[1-2]-ib" into individual host names. Zero padding in
+// range bounds is preserved. Malformed segments are returned as written.
+func ExpandHostlist(expr string) []string {
+	expr = strings.TrimSpace(expr)
+	if expr == "" || expr == "(null)" || expr == "None" {
+		return nil
+	}
+	var out []string
+	for _, item := range splitHostlist(expr) {
+		hosts, err := expandHostlistItem(item)
+		if err != nil {
+			out = append(out, item)
+			continue
+		}
+		out = append(out, hosts...)
+		if len(out) > maxHostlistExpansion {
+			return out[:maxHostlistExpansion]
+		}
+	}
+	return out
+}
+
+// HostlistContains reports whether expr names host.
+func HostlistContains(expr, host string) bool {
+	for _, h := range ExpandHostlist(expr) {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
+// JobsOnNode returns jobs whose node list includes the node.
+func JobsOnNode(jobs []Job, node string) []Job {
+	var out []Job
+	for _, j := range jobs {
+		if j.NodeList == "" || !strings.Contains(j.NodeList, hostlistPrefix(node)) {
+			continue
+		}
+		if HostlistContains(j.NodeList, node) {
+			out = append(out, j)
+		}
+	}
+	return out
+}
+
+// hostlistPrefix is the leading non-digit part of a host name; any hostlist
+// naming the host must contain it literally, which makes a cheap pre-check.
+func hostlistPrefix(host string) string {
+	end := strings.IndexAny(host, "0123456789")
+	if end < 0 {
+		return host
+	}
+	return host[:end]
+}
+
+// splitHostlist splits on commas that are outside brackets.
+func splitHostlist(expr string) []string {
+	var out []string
+	depth := 0
+	start := 0
+	for i, r := range expr {
+		switch r {
+		case '[':
+			depth++
+		case ']':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				if item := strings.TrimSpace(expr[start:i]); item != "" {
+					out = append(out, item)
+				}
+				start = i + 1
+			}
+		}
+	}
+	if item := strings.TrimSpace(expr[start:]); item != "" {
+		out = append(out, item)
+	}
+	return out
+}
+
+// expandHostlistItem expands the first bracket group and recurses on the
+// remainder so names with several groups (rack[1-2]-n[01-04]) expand fully.
+func expandHostlistItem(item string) ([]string, error) {
+	open := strings.IndexByte(item, '[')
+	if open < 0 {
+		return []string{item}, nil
+	}
+	closeIdx := strings.IndexByte(item[open:], ']')
+	if closeIdx < 0 {
+		return nil, fmt.Errorf("unbalanced bracket in %q", item)
+	}
+	closeIdx += open
+	prefix := item[:open]
+	body := item[open+1 : closeIdx]
+
+	suffixes, err := expandHostlistItem(item[closeIdx+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	for _, part := range strings.Split(body, ",") {
+		values, err := expandRange(part)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range values {
+			for _, suffix := range suffixes {
+				out = append(out, prefix+v+suffix)
+				if len(out) > maxHostlistExpansion {
+					return nil, fmt.Errorf("hostlist %q expands past %d hosts", item, maxHostlistExpansion)
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+func expandRange(part string) ([]string, error) {
+	part = strings.TrimSpace(part)
+	lo, hi, isRange := strings.Cut(part, "-")
+	if !isRange {
+		if _, err := strconv.Atoi(part); err != nil {
+			return nil, fmt.Errorf("invalid hostlist index %q", part)
+		}
+		return []string{part}, nil
+	}
+	start, err := strconv.Atoi(lo)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hostlist range %q", part)
+	}
+	end, err := strconv.Atoi(hi)
+	if err != nil || end < start {
+		return nil, fmt.Errorf("invalid hostlist range %q", part)
+	}
+	if end-start >= maxHostlistExpansion {
+		return nil, fmt.Errorf("hostlist range %q is too large", part)
+	}
+	width := len(lo)
+	out := make([]string, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		out = append(out, fmt.Sprintf("%0*d", width, i))
+	}
+	return out, nil
+}
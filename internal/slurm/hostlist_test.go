@@ -0,0 +1,41 @@
+package slurm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandHostlist(t *testing.T) {
+	cases := []struct {
+		expr string
+		want []string
+	}{
+		{"node001", []string{"node001"}},
+		{"gpu-a[01-03,07]", []string{"gpu-a01", "gpu-a02", "gpu-a03", "gpu-a07"}},
+		{"cpu[8-10],login1", []string{"cpu8", "cpu9", "cpu10", "login1"}},
+		{"rack[1-2]-n[01-02]", []string{"rack1-n01", "rack1-n02", "rack2-n01", "rack2-n02"}},
+		{"bad[3-1]", []string{"bad[3-1]"}},
+		{"(null)", nil},
+	}
+	for _, tc := range cases {
+		if got := ExpandHostlist(tc.expr); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("ExpandHostlist(%q) = %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestJobsOnNode(t *testing.T) {
+	jobs := []Job{
+		{ID: "1", NodeList: "gpu-a[01-02]"},
+		{ID: "2", NodeList: "gpu-a03"},
+		{ID: "3", Reason: "Priority"},
+		{ID: "4", NodeList: "gpu-a[010-012]"},
+	}
+	got := JobsOnNode(jobs, "gpu-a02")
+	if len(got) != 1 || got[0].ID != "1" {
+		t.Fatalf("expected only job 1 on gpu-a02, got %+v", got)
+	}
+	if got := JobsOnNode(jobs, "gpu-a011"); len(got) != 1 || got[0].ID != "4" {
+		t.Fatalf("expected padded range to match gpu-a011, got %+v", got)
+	}
+}
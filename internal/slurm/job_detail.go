@@ -0,0 +1,153 @@
+package slurm
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jobIDRe accepts plain, array-task (123_4) and heterogeneous (123+1) job IDs.
+// The ID is interpolated into a shell command, so nothing else is allowed.
+var jobIDRe = regexp.MustCompile(`^[0-9]+([_+][0-9]+)?$`)
+
+// taskRangeIDRe matches the record standing for array tasks past the
+// expansion cap, such as 123_[65537-90000].
+var taskRangeIDRe = regexp.MustCompile(`^([0-9]+)_\[([0-9]+)-[0-9]+\]$`)
+
+// lookupJobID returns the ID to ask Slurm for. A task range is looked up by
+// its first task, which Slurm answers with the pending range's record.
+func lookupJobID(id string) (string, error) {
+	if jobIDRe.MatchString(id) {
+		return id, nil
+	}
+	if m := taskRangeIDRe.FindStringSubmatch(id); m != nil {
+		return m[1] + "_" + m[2], nil
+	}
+	return "", fmt.Errorf("invalid job id %q", id)
+}
+
+// JobDetail is one `scontrol show job -o` record.
+type JobDetail struct {
+	ID        string
+	Fields    []KeyValue
+	FetchedAt time.Time
+}
+
+// Field returns the value for key, or "" when scontrol did not report it or
+// reported a null placeholder such as (null).
+func (d JobDetail) Field(key string) string {
+	for _, kv := range d.Fields {
+		if kv.Key == key {
+			return nullableField(kv.Value)
+		}
+	}
+	return ""
+}
+
+// JobDetail returns the scontrol record for a job, fetching it through the
+// transport on first use. Results are cached per job ID until the job leaves
+// the queue or changes state, so reopening a job does not reach slurmctld
+// again. It is safe to call while Collect runs.
+func (c *Collector) JobDetail(ctx context.Context, id string) (JobDetail, error) {
+	id = strings.TrimSpace(id)
+	lookup, err := lookupJobID(id)
+	if err != nil {
+		return JobDetail{}, err
+	}
+
+	if cached, ok := c.jobDetails.get(id); ok {
+		return cached, nil
+	}
+
+	raw, err := c.runWithTimeout(ctx, fmt.Sprintf("scontrol show job -o %s", lookup))
+	if err != nil {
+		return JobDetail{}, fmt.Errorf("show job %s: %w", id, err)
+	}
+	detail, err := parseJobDetail(id, raw)
+	if err != nil {
+		return JobDetail{}, err
+	}
+	detail.FetchedAt = time.Now()
+
+	c.jobDetails.put(detail)
+	return detail, nil
+}
+
+// jobDetailCache holds job details until their job leaves the queue or
+// changes state, so a job opened while pending is refetched once it starts.
+// The zero value is ready to use and it is safe for concurrent use.
+type jobDetailCache struct {
+	mu   sync.Mutex
+	byID map[string]cachedJobDetail
+}
+
+type cachedJobDetail struct {
+	detail JobDetail
+	// state is the job's state when the detail was fetched: its JobState
+	// field, or the queue state at the next prune when that is missing.
+	state string
+}
+
+func (c *jobDetailCache) get(id string) (JobDetail, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.byID[id]
+	return e.detail, ok
+}
+
+func (c *jobDetailCache) put(d JobDetail) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.byID == nil {
+		c.byID = make(map[string]cachedJobDetail)
+	}
+	c.byID[d.ID] = cachedJobDetail{detail: d, state: d.Field("JobState")}
+}
+
+// prune drops cached details for jobs that are no longer queued or whose
+// state changed since the detail was fetched.
+func (c *jobDetailCache) prune(jobs []Job) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.byID) == 0 {
+		return
+	}
+	states := make(map[string]string, len(c.byID))
+	for _, j := range jobs {
+		if _, ok := c.byID[j.ID]; ok {
+			states[j.ID] = j.State
+		}
+	}
+	for id, e := range c.byID {
+		state, ok := states[id]
+		switch {
+		case !ok:
+			delete(c.byID, id)
+		case e.state == "":
+			e.state = state
+			c.byID[id] = e
+		case e.state != state:
+			delete(c.byID, id)
+		}
+	}
+}
+
+// parseJobDetail takes the first record of `scontrol show job -o` output. An
+// array root prints one line per task; the first one describes the root.
+func parseJobDetail(id, raw string) (JobDetail, error) {
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		pairs := parseKVPairs(line)
+		if len(pairs) == 0 {
+			continue
+		}
+		return JobDetail{ID: id, Fields: pairs}, nil
+	}
+	return JobDetail{}, fmt.Errorf("show job %s: no job record in output", id)
+}
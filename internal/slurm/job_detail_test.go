@@ -0,0 +1,134 @@
+package slurm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"slurm_monitor/internal/transport"
+)
+
+type countingTransport struct {
+	stdout   string
+	commands []string
+}
+
+func (c *countingTransport) Run(_ context.Context, command string) (transport.RunResult, error) {
+	c.commands = append(c.commands, command)
+	return transport.RunResult{Stdout: c.stdout}, nil
+}
+
+func (c *countingTransport) Describe() string {
+	return "counting"
+}
+
+func TestJobDetailFetchesOnceAndCaches(t *testing.T) {
+	tr := &countingTransport{stdout: "JobId=42 JobName=train run UserId=alice(1000) Dependency=(null) " +
+		"ReqTRES=cpu=8,mem=32G,node=1,gres/gpu=2 NodeList=gpu-a01 WorkDir=/home/alice Command=/home/alice/run.sh --epochs 3\n"}
+	c := NewCollector(tr, time.Second)
+
+	d, err := c.JobDetail(context.Background(), "42")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(tr.commands) != 1 || tr.commands[0] != "scontrol show job -o 42" {
+		t.Fatalf("unexpected commands: %v", tr.commands)
+	}
+	if got := d.Field("JobName"); got != "train run" {
+		t.Fatalf("expected folded job name, got %q", got)
+	}
+	if got := d.Field("ReqTRES"); got != "cpu=8,mem=32G,node=1,gres/gpu=2" {
+		t.Fatalf("unexpected ReqTRES %q", got)
+	}
+	if got := d.Field("Command"); got != "/home/alice/run.sh --epochs 3" {
+		t.Fatalf("unexpected Command %q", got)
+	}
+	if got := d.Field("Dependency"); got != "" {
+		t.Fatalf("expected (null) dependency to read as empty, got %q", got)
+	}
+
+	if _, err := c.JobDetail(context.Background(), "42"); err != nil {
+		t.Fatalf("expected cached lookup to succeed, got %v", err)
+	}
+	if len(tr.commands) != 1 {
+		t.Fatalf("expected cached lookup not to run scontrol again, got %v", tr.commands)
+	}
+
+	c.jobDetails.prune([]Job{{ID: "43"}})
+	if _, err := c.JobDetail(context.Background(), "42"); err != nil {
+		t.Fatalf("expected refetch to succeed, got %v", err)
+	}
+	if len(tr.commands) != 2 {
+		t.Fatalf("expected pruned job to be fetched again, got %v", tr.commands)
+	}
+}
+
+func TestJobDetailRefetchesAfterStateChange(t *testing.T) {
+	tr := &countingTransport{stdout: "JobId=42 JobState=PENDING Reason=Priority\n"}
+	c := NewCollector(tr, time.Second)
+	c.jobDetails.prune([]Job{{ID: "42", State: "PENDING"}})
+
+	if _, err := c.JobDetail(context.Background(), "42"); err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	c.jobDetails.prune([]Job{{ID: "42", State: "PENDING"}})
+	if _, err := c.JobDetail(context.Background(), "42"); err != nil {
+		t.Fatalf("expected cached lookup to succeed, got %v", err)
+	}
+	if len(tr.commands) != 1 {
+		t.Fatalf("expected unchanged job to stay cached, got %v", tr.commands)
+	}
+
+	tr.stdout = "JobId=42 JobState=RUNNING Reason=None NodeList=gpu-a01\n"
+	c.jobDetails.prune([]Job{{ID: "42", State: "RUNNING"}})
+	d, err := c.JobDetail(context.Background(), "42")
+	if err != nil {
+		t.Fatalf("expected refetch to succeed, got %v", err)
+	}
+	if len(tr.commands) != 2 {
+		t.Fatalf("expected state change to evict the cached detail, got %v", tr.commands)
+	}
+	if got := d.Field("JobState"); got != "RUNNING" {
+		t.Fatalf("expected fresh job state, got %q", got)
+	}
+}
+
+func TestJobDetailRejectsUnsafeIDs(t *testing.T) {
+	tr := &countingTransport{}
+	c := NewCollector(tr, time.Second)
+	for _, id := range []string{"", "42; rm -rf /", "abc", "42_"} {
+		if _, err := c.JobDetail(context.Background(), id); err == nil {
+			t.Fatalf("expected error for id %q", id)
+		}
+	}
+	for _, id := range []string{"42_7", "42+1"} {
+		if _, err := c.JobDetail(context.Background(), id); err == nil {
+			t.Fatalf("expected empty output to fail for %q", id)
+		}
+	}
+	if len(tr.commands) != 2 {
+		t.Fatalf("expected only valid ids to reach the transport, got %v", tr.commands)
+	}
+}
+
+func TestJobDetailLooksUpTaskRangeByFirstTask(t *testing.T) {
+	tr := &countingTransport{stdout: "JobId=77 ArrayJobId=77 ArrayTaskId=65537-90000 JobState=PENDING\n"}
+	c := NewCollector(tr, time.Second)
+
+	d, err := c.JobDetail(context.Background(), "77_[65537-90000]")
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if len(tr.commands) != 1 || tr.commands[0] != "scontrol show job -o 77_65537" {
+		t.Fatalf("expected the range looked up by its first task, got %v", tr.commands)
+	}
+	if d.ID != "77_[65537-90000]" || d.Field("ArrayTaskId") != "65537-90000" {
+		t.Fatalf("unexpected detail %+v", d)
+	}
+
+	for _, id := range []string{"77_[1-2];rm", "77_[1-]", "77_[a-b]"} {
+		if _, err := c.JobDetail(context.Background(), id); err == nil {
+			t.Fatalf("expected %q rejected", id)
+		}
+	}
+}
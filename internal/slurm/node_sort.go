@@ -0,0 +1,155 @@
+package slurm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type NodeSortKey string
+
+const (
+	NodeSortName      NodeSortKey = "name"
+	NodeSortState     NodeSortKey = "state"
+	NodeSortPartition NodeSortKey = "partition"
+	NodeSortCPU       NodeSortKey = "cpu"
+	NodeSortCPUUtil   NodeSortKey = "cpu%"
+	NodeSortMem       NodeSortKey = "mem"
+	NodeSortMemUtil   NodeSortKey = "mem%"
+	NodeSortGPU       NodeSortKey = "gpu"
+	NodeSortGPUUtil   NodeSortKey = "gpu%"
+)
+
+// NodeSortKeys lists node sort keys in the order the TUI cycles through them.
+var NodeSortKeys = []NodeSortKey{
+	NodeSortName,
+	NodeSortState,
+	NodeSortPartition,
+	NodeSortCPU,
+	NodeSortCPUUtil,
+	NodeSortMem,
+	NodeSortMemUtil,
+	NodeSortGPU,
+	NodeSortGPUUtil,
+}
+
+// NodeSort is a node ordering. Desc reverses the key's ascending order; ties
+// always fall back to node name ascending so rows do not jump between frames.
+type NodeSort struct {
+	Key  NodeSortKey
+	Desc bool
+}
+
+// DefaultNodeSort matches the name ordering the collector produces.
+var DefaultNodeSort = NodeSort{Key: NodeSortName}
+
+// DefaultDesc reports the natural direction for a key: text keys ascend and
+// numeric keys put the largest values first.
+func (k NodeSortKey) DefaultDesc() bool {
+	switch k {
+	case NodeSortName, NodeSortState, NodeSortPartition:
+		return false
+	default:
+		return true
+	}
+}
+
+func ParseNodeSortKey(v string) (NodeSortKey, error) {
+	key := NodeSortKey(strings.ToLower(strings.TrimSpace(v)))
+	for _, k := range NodeSortKeys {
+		if k == key {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("unknown node sort key %q", v)
+}
+
+func (s NodeSort) String() string {
+	return sortLabel(string(s.Key), s.Desc)
+}
+
+// SortNodes orders nodes in place. Memory sorts by allocated MB and the
+// utilization keys sort nodes without the metric last in either direction.
+func SortNodes(nodes []Node, s NodeSort) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		a, b := nodes[i], nodes[j]
+		if c := compareNodes(a, b, s.Key); c != 0 {
+			if missingI, missingJ := nodeMetricMissing(a, s.Key), nodeMetricMissing(b, s.Key); missingI != missingJ {
+				return missingJ
+			}
+			if s.Desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return a.Name < b.Name
+	})
+}
+
+func compareNodes(a, b Node, key NodeSortKey) int {
+	switch key {
+	case NodeSortState:
+		return strings.Compare(a.State, b.State)
+	case NodeSortPartition:
+		return strings.Compare(a.Partition, b.Partition)
+	case NodeSortCPU:
+		return compareInts(a.CPUAlloc, b.CPUAlloc)
+	case NodeSortCPUUtil:
+		return compareMetric(a.CPUUtil, a.HasCPU, b.CPUUtil, b.HasCPU)
+	case NodeSortMem:
+		return compareInts(a.MemAllocMB, b.MemAllocMB)
+	case NodeSortMemUtil:
+		return compareMetric(a.MemUtil, a.HasMem, b.MemUtil, b.HasMem)
+	case NodeSortGPU:
+		return compareInts(a.GPUAlloc, b.GPUAlloc)
+	case NodeSortGPUUtil:
+		return compareMetric(a.GPUUtil, a.HasGPU, b.GPUUtil, b.HasGPU)
+	default:
+		return strings.Compare(a.Name, b.Name)
+	}
+}
+
+func nodeMetricMissing(n Node, key NodeSortKey) bool {
+	switch key {
+	case NodeSortCPUUtil:
+		return !n.HasCPU
+	case NodeSortMemUtil:
+		return !n.HasMem
+	case NodeSortGPUUtil:
+		return !n.HasGPU
+	default:
+		return false
+	}
+}
+
+func compareMetric(a float64, hasA bool, b float64, hasB bool) int {
+	switch {
+	case hasA != hasB:
+		// Any non-zero result; SortNodes places the missing side last.
+		return 1
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func sortLabel(key string, desc bool) string {
+	if desc {
+		return key + "↓"
+	}
+	return key + "↑"
+}
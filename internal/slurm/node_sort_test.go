@@ -0,0 +1,55 @@
+package slurm
+
+import "testing"
+
+func nodeNames(nodes []Node) []string {
+	out := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		out = append(out, n.Name)
+	}
+	return out
+}
+
+func TestSortNodesByGPUAllocDescendingBreaksTiesByName(t *testing.T) {
+	nodes := []Node{
+		{Name: "c", GPUAlloc: 2},
+		{Name: "a", GPUAlloc: 8},
+		{Name: "b", GPUAlloc: 2},
+	}
+	SortNodes(nodes, NodeSort{Key: NodeSortGPU, Desc: true})
+	got := nodeNames(nodes)
+	if got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Fatalf("expected a,b,c, got %v", got)
+	}
+
+	SortNodes(nodes, NodeSort{Key: NodeSortGPU})
+	got = nodeNames(nodes)
+	if got[0] != "b" || got[1] != "c" || got[2] != "a" {
+		t.Fatalf("expected ascending b,c,a, got %v", got)
+	}
+}
+
+func TestSortNodesPutsMissingMetricsLastInBothDirections(t *testing.T) {
+	nodes := []Node{
+		{Name: "nometric"},
+		{Name: "low", CPUUtil: 10, HasCPU: true},
+		{Name: "high", CPUUtil: 90, HasCPU: true},
+	}
+	SortNodes(nodes, NodeSort{Key: NodeSortCPUUtil, Desc: true})
+	if got := nodeNames(nodes); got[0] != "high" || got[2] != "nometric" {
+		t.Fatalf("expected high first and nometric last, got %v", got)
+	}
+	SortNodes(nodes, NodeSort{Key: NodeSortCPUUtil})
+	if got := nodeNames(nodes); got[0] != "low" || got[2] != "nometric" {
+		t.Fatalf("expected low first and nometric last, got %v", got)
+	}
+}
+
+func TestParseNodeSortKey(t *testing.T) {
+	if k, err := ParseNodeSortKey(" CPU% "); err != nil || k != NodeSortCPUUtil {
+		t.Fatalf("expected cpu%% key, got %q, %v", k, err)
+	}
+	if _, err := ParseNodeSortKey("pending-gpu"); err == nil {
+		t.Fatalf("expected user key to be rejected as a node key")
+	}
+}
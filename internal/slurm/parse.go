@@ -7,10 +7,10 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var numPrefixRe = regexp.MustCompile(`^-?\d+`)
-var gpuReqRe = regexp.MustCompile(`gpu(?::[a-zA-Z0-9_-]+)?[:=]([0-9]+)`)
 
 func parseNodeLines(raw string) ([]Node, error) {
 	lines := strings.Split(raw, "\n")
@@ -33,11 +33,18 @@ func parseNodeLines(raw string) ([]Node, error) {
 }
 
 func parseNodeLine(line string) (Node, error) {
-	fields := parseKVLine(line)
-	name := fields["NodeName"]
-	if name == "" {
+	pairs := parseKVPairs(line)
+	if kvMap(pairs)["NodeName"] == "" {
 		return Node{}, fmt.Errorf("missing NodeName in line: %s", line)
 	}
+	return nodeFromFields(pairs), nil
+}
+
+// nodeFromFields builds a Node from scontrol's key=value pairs. The REST
+// collector maps its JSON onto the same keys so both backends agree.
+func nodeFromFields(pairs []KeyValue) Node {
+	fields := kvMap(pairs)
+	name := fields["NodeName"]
 
 	cpuAlloc := parseInt(fields["CPUAlloc"])
 	cpuTotal := parseInt(fields["CPUTot"])
@@ -64,6 +71,7 @@ func parseNodeLine(line string) (Node, error) {
 		Name:       name,
 		State:      state,
 		Partition:  fields["Partitions"],
+		Partitions: splitNonEmpty(nullableField(fields["Partitions"]), ","),
 		CPUAlloc:   cpuAlloc,
 		CPUTotal:   cpuTotal,
 		CPUUtil:    cpuUtil,
@@ -76,111 +84,228 @@ func parseNodeLine(line string) (Node, error) {
 		GPUTotal:   gpuTotal,
 		GPUUtil:    gpuUtil,
 		HasGPU:     hasGPU,
-	}, nil
+		GPUTypes:   nodeGPUTypes(fields, gpuAlloc, gpuTotal),
+
+		Features:       nullableField(fields["AvailableFeatures"]),
+		ActiveFeatures: nullableField(fields["ActiveFeatures"]),
+		Gres:           nullableField(fields["Gres"]),
+		GresUsed:       nullableField(fields["GresUsed"]),
+		Reason:         nullableField(fields["Reason"]),
+		BootTime:       parseSlurmTime(fields["BootTime"]),
+		Weight:         parseInt(fields["Weight"]),
+		Owner:          nullableField(fields["Owner"]),
+		Fields:         pairs,
+	}
+}
+
+// The squeue -O layout in queueCommand and gresQueueCommand is a fixed head,
+// the job name, then a fixed tail. Name is the only free-text column, so
+// splitting on every "|" and taking the head and tail from both ends keeps a
+// name that contains "|" intact instead of shifting later columns. Older
+// fixtures carry only Reason in the tail.
+const (
+	queueHeadFields   = 7 // JobID, State, UserName, NumCPUs, MinMemory, tres-alloc, Partition
+	queueLegacyTail   = 1 // Reason
+	queueExtendedTail = 7 // Reason, ArrayJobID, ArrayTaskID, NodeList, SubmitTime, StartTime, TimeLimit
+)
+
+const slurmTimeLayout = "2006-01-02T15:04:05"
+
+// jobRequest is what `scontrol show job` ReqTRES says a pending job root
+// asks for. squeue often leaves tres-alloc empty until a job starts, so
+// pending GPUs and memory fall back to it.
+type jobRequest struct {
+	GPUs     int
+	GPUTypes []GPUCount
+	MemMB    int
 }
 
-func parseQueueLines(raw string, pendingGPUCountByJobRoot map[string]int) (QueueSummary, []UserSummary) {
+func parseJobRequest(reqTRES string) jobRequest {
+	gpus, gpuTypes := parseGPUs(reqTRES)
+	return jobRequest{GPUs: gpus, GPUTypes: gpuTypes, MemMB: parseMemMBFromTRES(reqTRES)}
+}
+
+func parseJobLines(raw string, pendingByJobRoot map[string]jobRequest) []Job {
 	lines := strings.Split(raw, "\n")
+	out := make([]Job, 0, len(lines))
+	for _, line := range lines {
+		job, ok := parseJobLine(line)
+		if !ok {
+			continue
+		}
+		if classifyQueueState(job.State) == "pending" {
+			req := pendingByJobRoot[job.RootID()]
+			if job.GPUs == 0 && req.GPUs > 0 {
+				job.GPUs = req.GPUs
+				job.GPUTypes = req.GPUTypes
+			}
+			if req.MemMB > 0 {
+				job.MemMB = req.MemMB
+			}
+		}
+		out = append(out, job)
+	}
+	return out
+}
+
+func parseJobLine(line string) (Job, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return Job{}, false
+	}
+	parts := strings.Split(line, "|")
+	if len(parts) < queueHeadFields+1+queueLegacyTail {
+		return Job{}, false
+	}
+	tailLen := queueLegacyTail
+	if len(parts) >= queueHeadFields+1+queueExtendedTail {
+		tailLen = queueExtendedTail
+	}
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	tail := parts[len(parts)-tailLen:]
+	name := strings.TrimSpace(strings.Join(parts[queueHeadFields:len(parts)-tailLen], "|"))
+
+	gpus, gpuTypes := parseGPUs(parts[5])
+	job := Job{
+		ID:        parts[0],
+		State:     strings.ToUpper(parts[1]),
+		User:      parts[2],
+		CPUs:      parseInt(parts[3]),
+		MemMB:     jobMemMB(parts[4], parts[5], parseInt(parts[3])),
+		GPUs:      gpus,
+		GPUTypes:  gpuTypes,
+		Partition: parts[6],
+		Name:      name,
+		Reason:    tail[0],
+	}
+	if job.User == "" {
+		job.User = "<unknown>"
+	}
+	if job.Partition == "" {
+		job.Partition = "<unknown>"
+	}
+	if job.Name == "" || job.Name == "N/A" {
+		job.Name = "<unnamed>"
+	}
+	if tailLen == queueExtendedTail {
+		if taskID := tail[2]; taskID != "" && taskID != "N/A" {
+			job.ArrayJobID = tail[1]
+			job.ArrayTaskID = taskID
+		}
+		if nodes := tail[3]; nodes != "N/A" && nodes != "(null)" {
+			job.NodeList = nodes
+		}
+		job.SubmitTime = parseSlurmTime(tail[4])
+		job.StartTime = parseSlurmTime(tail[5])
+		job.TimeLimit = parseTimeLimit(tail[6])
+	}
+	return job, true
+}
+
+// SummarizeJobs folds individual jobs into queue and per-user summaries. A job
+// counts as a GPU job when it holds or requests at least one GPU, and a record
+// standing for several array tasks counts once per task. A job submitted to
+// several partitions counts toward each of them and is marked Shared there.
+func SummarizeJobs(jobs []Job) (QueueSummary, []UserSummary) {
 	users := make(map[string]*UserSummary)
 	partitionMap := make(map[string]*PartitionCount)
 	stateMap := make(map[string]int)
 	jobNameMap := make(map[string]int)
 	pendingReasonMap := make(map[string]int)
+	gpuTypeMap := make(map[string]*GPUTypeLoad)
+	userGPUTypes := make(map[string]map[string]*GPUTypeLoad)
 	var queue QueueSummary
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		parts := strings.SplitN(line, "|", 9)
-		if len(parts) < 9 {
-			continue
-		}
-		jobID := strings.TrimSpace(parts[0])
-		state := strings.ToUpper(strings.TrimSpace(parts[1]))
-		user := strings.TrimSpace(parts[2])
-		cpuReq := parseInt(strings.TrimSpace(parts[3]))
-		memReqMB := parseMemRequestMB(strings.TrimSpace(parts[4]))
-		gresReq := strings.TrimSpace(parts[5])
-		partition := strings.TrimSpace(parts[6])
-		jobName := strings.TrimSpace(parts[7])
-		reason := strings.TrimSpace(parts[8])
-		if user == "" {
-			user = "<unknown>"
-		}
-		if partition == "" {
-			partition = "<unknown>"
-		}
-		if jobName == "" || jobName == "N/A" {
-			jobName = "<unnamed>"
-		}
+	for _, job := range jobs {
+		user := job.User
+		n := job.Count()
 
 		if _, ok := users[user]; !ok {
 			users[user] = &UserSummary{User: user}
 		}
-		if _, ok := partitionMap[partition]; !ok {
-			partitionMap[partition] = &PartitionCount{Partition: partition}
+		partitions := make([]*PartitionCount, 0, 1)
+		names := job.PartitionNames()
+		for _, name := range names {
+			pc, ok := partitionMap[name]
+			if !ok {
+				pc = &PartitionCount{Partition: name}
+				partitionMap[name] = pc
+			}
+			if len(names) > 1 {
+				pc.Shared += n
+			}
+			partitions = append(partitions, pc)
 		}
 
-		stateClass := classifyQueueState(state)
-		stateMap[state]++
-		jobNameMap[jobName]++
-		gpuReq := parseGPUReq(gresReq)
-		isGPUJob := gpuReq > 0
+		stateMap[job.State] += n
+		jobNameMap[job.Name] += n
+		isGPUJob := job.GPUs > 0
+
+		class := classifyQueueState(job.State)
+		if isGPUJob && class != "other" {
+			if userGPUTypes[user] == nil {
+				userGPUTypes[user] = make(map[string]*GPUTypeLoad)
+			}
+			addGPULoad(gpuTypeMap, job, n, class == "running")
+			addGPULoad(userGPUTypes[user], job, n, class == "running")
+		}
 
-		switch stateClass {
+		switch class {
 		case "running":
-			queue.Running++
-			users[user].Running++
-			users[user].RunningCPU += cpuReq
-			users[user].RunningGPU += gpuReq
+			queue.Running += n
+			users[user].Running += n
+			users[user].RunningCPU += job.CPUs * n
+			users[user].RunningGPU += job.GPUs * n
 			if isGPUJob {
-				queue.RunningGPUJobs++
-				users[user].RunningGPUJobs++
+				queue.RunningGPUJobs += n
+				users[user].RunningGPUJobs += n
 			} else {
-				queue.RunningCPUJobs++
-				users[user].RunningCPUJobs++
+				queue.RunningCPUJobs += n
+				users[user].RunningCPUJobs += n
 			}
-			partitionMap[partition].Running++
-			queue.ResourceLoad.RunningCPU += cpuReq
-			queue.ResourceLoad.RunningMemMB += memReqMB
-			queue.ResourceLoad.RunningGPU += gpuReq
-		case "pending":
-			queue.Pending++
-			users[user].Pending++
-			if !isGPUJob {
-				if fallbackGPUCount := pendingGPUCountByJobRoot[rootJobID(jobID)]; fallbackGPUCount > 0 {
-					isGPUJob = true
-					gpuReq = fallbackGPUCount
-				}
+			for _, pc := range partitions {
+				pc.Running += n
 			}
+			queue.ResourceLoad.RunningCPU += job.CPUs * n
+			queue.ResourceLoad.RunningMemMB += job.MemMB * n
+			queue.ResourceLoad.RunningGPU += job.GPUs * n
+		case "pending":
+			queue.Pending += n
+			users[user].Pending += n
 			if isGPUJob {
-				queue.PendingGPUJobs++
-				users[user].PendingGPUJobs++
+				queue.PendingGPUJobs += n
+				users[user].PendingGPUJobs += n
 			} else {
-				queue.PendingCPUJobs++
-				users[user].PendingCPUJobs++
+				queue.PendingCPUJobs += n
+				users[user].PendingCPUJobs += n
 			}
-			users[user].PendingCPU += cpuReq
-			users[user].PendingMemMB += memReqMB
-			users[user].PendingGPU += gpuReq
-			partitionMap[partition].Pending++
-			queue.ResourceLoad.PendingCPU += cpuReq
-			queue.ResourceLoad.PendingMemMB += memReqMB
-			queue.ResourceLoad.PendingGPU += gpuReq
+			users[user].PendingCPU += job.CPUs * n
+			users[user].PendingMemMB += job.MemMB * n
+			users[user].PendingGPU += job.GPUs * n
+			for _, pc := range partitions {
+				pc.Pending += n
+			}
+			queue.ResourceLoad.PendingCPU += job.CPUs * n
+			queue.ResourceLoad.PendingMemMB += job.MemMB * n
+			queue.ResourceLoad.PendingGPU += job.GPUs * n
+			reason := job.Reason
 			if reason == "" {
 				reason = "<unknown>"
 			}
-			pendingReasonMap[reason]++
+			pendingReasonMap[reason] += n
 		default:
-			queue.Other++
-			partitionMap[partition].Other++
+			queue.Other += n
+			for _, pc := range partitions {
+				pc.Other += n
+			}
 		}
 	}
 
 	outUsers := make([]UserSummary, 0, len(users))
 	for _, v := range users {
+		v.ByGPUType = mapToGPUTypeLoads(userGPUTypes[v.User])
 		outUsers = append(outUsers, *v)
 	}
 	SortUsersForDisplay(outUsers)
@@ -189,6 +314,7 @@ func parseQueueLines(raw string, pendingGPUCountByJobRoot map[string]int) (Queue
 	queue.ByPartition = mapToPartitionCounts(partitionMap)
 	queue.ByJobName = mapToNameCounts(jobNameMap)
 	queue.PendingCause = mapToNameCounts(pendingReasonMap)
+	queue.ByGPUType = mapToGPUTypeLoads(gpuTypeMap)
 
 	return queue, outUsers
 }
@@ -222,18 +348,105 @@ func classifyQueueState(state string) string {
 	}
 }
 
-func parseKVLine(line string) map[string]string {
-	out := make(map[string]string)
+func parseSlurmTime(v string) time.Time {
+	v = strings.TrimSpace(v)
+	switch v {
+	case "", "N/A", "Unknown", "None", "(null)":
+		return time.Time{}
+	}
+	t, err := time.ParseInLocation(slurmTimeLayout, v, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// parseTimeLimit accepts Slurm's [days-]hours:minutes:seconds and
+// minutes:seconds forms. UNLIMITED and unparsable values map to zero.
+func parseTimeLimit(v string) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" || !strings.ContainsAny(v, "0123456789") {
+		return 0
+	}
+	var days int
+	if dayPart, rest, ok := strings.Cut(v, "-"); ok {
+		d, err := strconv.Atoi(dayPart)
+		if err != nil {
+			return 0
+		}
+		days = d
+		v = rest
+	}
+	fields := strings.Split(v, ":")
+	nums := make([]int, len(fields))
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return 0
+		}
+		nums[i] = n
+	}
+	var hours, minutes, seconds int
+	switch len(nums) {
+	case 3:
+		hours, minutes, seconds = nums[0], nums[1], nums[2]
+	case 2:
+		if days > 0 {
+			hours, minutes = nums[0], nums[1]
+		} else {
+			minutes, seconds = nums[0], nums[1]
+		}
+	case 1:
+		if days > 0 {
+			hours = nums[0]
+		} else {
+			minutes = nums[0]
+		}
+	default:
+		return 0
+	}
+	return time.Duration(days)*24*time.Hour +
+		time.Duration(hours)*time.Hour +
+		time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second
+}
+
+// parseKVPairs splits a one-line scontrol record into ordered key=value pairs.
+// Tokens without "=" continue the previous value, so values with spaces such
+// as Reason=Not responding [slurm@...] or OS=Linux 5.14 ... stay whole.
+func parseKVPairs(line string) []KeyValue {
+	var out []KeyValue
 	for _, token := range strings.Fields(line) {
-		parts := strings.SplitN(token, "=", 2)
-		if len(parts) != 2 {
+		key, value, ok := strings.Cut(token, "=")
+		if !ok || key == "" {
+			if len(out) > 0 {
+				out[len(out)-1].Value += " " + token
+			}
 			continue
 		}
-		out[parts[0]] = parts[1]
+		out = append(out, KeyValue{Key: key, Value: value})
 	}
 	return out
 }
 
+func kvMap(pairs []KeyValue) map[string]string {
+	out := make(map[string]string, len(pairs))
+	for _, kv := range pairs {
+		out[kv.Key] = kv.Value
+	}
+	return out
+}
+
+// nullableField maps scontrol's placeholders for unset values to "".
+func nullableField(v string) string {
+	switch v {
+	case "(null)", "N/A", "none", "None":
+		return ""
+	default:
+		return v
+	}
+}
+
 func cleanNodeState(v string) string {
 	if v == "" {
 		return ""
@@ -323,29 +536,6 @@ func allocUtilPct(alloc, total int) (float64, bool) {
 	return pct, true
 }
 
-func parseGPUCount(tres string) int {
-	if tres == "" {
-		return 0
-	}
-	total := 0
-	for _, part := range strings.Split(tres, ",") {
-		part = strings.TrimSpace(part)
-		if part == "" {
-			continue
-		}
-		kv := strings.SplitN(part, "=", 2)
-		if len(kv) != 2 {
-			continue
-		}
-		key := strings.TrimSpace(kv[0])
-		if !strings.HasPrefix(key, "gres/gpu") {
-			continue
-		}
-		total += parseInt(strings.TrimSpace(kv[1]))
-	}
-	return total
-}
-
 func parseMemMBFromTRES(tres string) int {
 	if tres == "" {
 		return 0
@@ -381,23 +571,24 @@ func parseMemMBFromTRES(tres string) int {
 	return 0
 }
 
-func parseMemRequestMB(raw string) int {
+// parseMemRequest reads a squeue MinMemory value in MB. A trailing c marks a
+// per-CPU amount and n a per-node one; jobMemMB scales both.
+func parseMemRequest(raw string) (mb int, perCPU bool) {
 	if raw == "" || raw == "N/A" {
-		return 0
+		return 0, false
 	}
-	// Slurm may append c/n for per-cpu/per-node semantics; we treat value as MB-equivalent scalar.
-	last := raw[len(raw)-1]
-	unit := byte(0)
 	numPart := raw
-	switch last {
-	case 'c', 'C', 'n', 'N':
+	switch raw[len(raw)-1] {
+	case 'c', 'C':
+		numPart, perCPU = raw[:len(raw)-1], true
+	case 'n', 'N':
 		numPart = raw[:len(raw)-1]
 	}
 	if len(numPart) == 0 {
-		return 0
+		return 0, perCPU
 	}
-	last = numPart[len(numPart)-1]
-	switch last {
+	unit := byte(0)
+	switch last := numPart[len(numPart)-1]; last {
 	case 'K', 'k', 'M', 'm', 'G', 'g', 'T', 't':
 		unit = last
 		numPart = numPart[:len(numPart)-1]
@@ -405,31 +596,41 @@ func parseMemRequestMB(raw string) int {
 	value := parseInt(numPart)
 	switch unit {
 	case 'K', 'k':
-		return value / 1024
-	case 'M', 'm':
-		return value
+		return value / 1024, perCPU
 	case 'G', 'g':
-		return value * 1024
+		return value * 1024, perCPU
 	case 'T', 't':
-		return value * 1024 * 1024
+		return value * 1024 * 1024, perCPU
 	default:
-		return value
+		return value, perCPU
 	}
 }
 
-func parseGPUReq(raw string) int {
-	if raw == "" || raw == "N/A" {
-		return 0
+// jobMemMB returns a job's total memory. The mem TRES is already the job
+// total and is the only place a --mem-per-gpu request shows up, so it wins;
+// otherwise MinMemory is multiplied by CPUs for a per-CPU request and by
+// nodes (the node TRES, or one) for a per-node request.
+func jobMemMB(minMemory, tres string, cpus int) int {
+	if mb := parseMemMBFromTRES(tres); mb > 0 {
+		return mb
 	}
-	matches := gpuReqRe.FindAllStringSubmatch(raw, -1)
-	total := 0
-	for _, m := range matches {
-		if len(m) < 2 {
-			continue
+	mb, perCPU := parseMemRequest(minMemory)
+	if perCPU {
+		return mb * max(cpus, 1)
+	}
+	return mb * max(parseTRESCount(tres, "node"), 1)
+}
+
+// parseTRESCount returns the plain count for key in a TRES string, such as
+// node=2.
+func parseTRESCount(tres, key string) int {
+	for _, part := range strings.Split(tres, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok && k == key {
+			return parseInt(v)
 		}
-		total += parseInt(m[1])
 	}
-	return total
+	return 0
 }
 
 func mapToStateCounts(m map[string]int) []StateCount {
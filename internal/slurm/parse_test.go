@@ -1,6 +1,9 @@
 package slurm
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestParseNodeLineBasic(t *testing.T) {
 	line := "NodeName=node001 State=IDLE CPUTot=64 CPUAlloc=32 CPULoad=16.00 RealMemory=256000 AllocMem=128000 FreeMem=96000 Partitions=main CfgTRES=cpu=64,mem=256000M,billing=64,gres/gpu=4 AllocTRES=cpu=32,mem=128000M,billing=32,gres/gpu=2"
@@ -31,7 +34,7 @@ func TestParseQueueLines(t *testing.T) {
 		"1002|PENDING|alice|4|10G|N/A|train|jobB|Priority\n" +
 		"1003|COMPLETING|bob|2|5000M|cpu=2,mem=5000M,gres/gpu=2|dev|jobC|None\n" +
 		"1004|PENDING|carol|1|4G|N/A|dev|jobD|Resources\n"
-	queue, users := parseQueueLines(raw, nil)
+	queue, users := SummarizeJobs(parseJobLines(raw, nil))
 	if queue.Running != 2 || queue.Pending != 2 {
 		t.Fatalf("unexpected queue counts: running=%d pending=%d", queue.Running, queue.Pending)
 	}
@@ -108,15 +111,64 @@ func TestParseMemFromTRES(t *testing.T) {
 	}
 }
 
-func TestParseMemRequestMB(t *testing.T) {
-	if got := parseMemRequestMB("20G"); got != 20480 {
-		t.Fatalf("unexpected mem request: %d", got)
+func TestParseMemRequest(t *testing.T) {
+	tests := []struct {
+		in     string
+		mb     int
+		perCPU bool
+	}{
+		{in: "20G", mb: 20480},
+		{in: "245090M", mb: 245090},
+		{in: "500Mc", mb: 500, perCPU: true},
+		{in: "64Gn", mb: 65536},
+		{in: "N/A", mb: 0},
+	}
+	for _, tt := range tests {
+		mb, perCPU := parseMemRequest(tt.in)
+		if mb != tt.mb || perCPU != tt.perCPU {
+			t.Fatalf("parseMemRequest(%q)=%d,%v want=%d,%v", tt.in, mb, perCPU, tt.mb, tt.perCPU)
+		}
+	}
+}
+
+func TestJobMemMBScalesPerCPUAndPerNodeRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		minMemory string
+		tres      string
+		cpus      int
+		want      int
+	}{
+		{"per-cpu", "4000Mc", "N/A", 64, 256000},
+		{"per-node", "64Gn", "N/A", 8, 65536},
+		{"per-node on two nodes", "64G", "cpu=16,node=2", 16, 131072},
+		{"per-gpu via tres", "0", "cpu=8,mem=160G,node=1,gres/gpu=4", 8, 163840},
+		{"tres wins over per-cpu", "4000Mc", "cpu=4,mem=16000M,node=1", 4, 16000},
+		{"no request", "N/A", "N/A", 4, 0},
+	}
+	for _, tt := range tests {
+		if got := jobMemMB(tt.minMemory, tt.tres, tt.cpus); got != tt.want {
+			t.Fatalf("%s: jobMemMB(%q, %q, %d)=%d want=%d", tt.name, tt.minMemory, tt.tres, tt.cpus, got, tt.want)
+		}
 	}
-	if got := parseMemRequestMB("245090M"); got != 245090 {
-		t.Fatalf("unexpected mem request: %d", got)
+}
+
+func TestPendingMemoryDemandUsesPerCPURequest(t *testing.T) {
+	raw := "" +
+		"4001|PENDING|alice|64|4000Mc|N/A|cpu|wide|Resources\n" +
+		"4002_1|PENDING|bob|8|0|N/A|gpu|pergpu|Priority\n"
+
+	queue, users := SummarizeJobs(parseJobLines(raw, map[string]jobRequest{"4002": {GPUs: 4, MemMB: 163840}}))
+	if queue.ResourceLoad.PendingMemMB != 256000+163840 {
+		t.Fatalf("expected per-cpu and scontrol memory in pending demand, got %d", queue.ResourceLoad.PendingMemMB)
 	}
-	if got := parseMemRequestMB("500Mc"); got != 500 {
-		t.Fatalf("unexpected mem request with suffix: %d", got)
+	for _, u := range users {
+		if u.User == "alice" && u.PendingMemMB != 256000 {
+			t.Fatalf("expected alice pending memory 256000, got %d", u.PendingMemMB)
+		}
+		if u.User == "bob" && u.PendingMemMB != 163840 {
+			t.Fatalf("expected bob pending memory from ReqTRES, got %d", u.PendingMemMB)
+		}
 	}
 }
 
@@ -127,7 +179,8 @@ func TestParseGPUReq(t *testing.T) {
 	if got := parseGPUReq("gres/gpu:a100:4,gres/gpu:1"); got != 5 {
 		t.Fatalf("unexpected gpu req composite: %d", got)
 	}
-	if got := parseGPUReq("cpu=8,mem=32G,gres/gpu=2,gres/gpu:a100=4"); got != 6 {
+	// TRES lists typed GPUs under both gres/gpu and gres/gpu:<type>.
+	if got := parseGPUReq("cpu=8,mem=32G,gres/gpu=4,gres/gpu:a100=4"); got != 4 {
 		t.Fatalf("unexpected gpu req from tres style string: %d", got)
 	}
 }
@@ -136,7 +189,7 @@ func TestPendingGPUJobsClassifiedByGPURequest(t *testing.T) {
 	raw := "" +
 		"2001|PENDING|alice|8|20G|cpu=8,mem=20G,gres/gpu=2|train|gpuJob|Resources\n" +
 		"2002|PENDING|alice|4|10G|N/A|train|cpuJob|Priority\n"
-	_, users := parseQueueLines(raw, nil)
+	_, users := SummarizeJobs(parseJobLines(raw, nil))
 	if len(users) != 1 {
 		t.Fatalf("expected one user, got %d", len(users))
 	}
@@ -158,7 +211,7 @@ func TestPendingGPUJobsFallbackByRootJobMap(t *testing.T) {
 		"37820_2|PENDING|alice|4|64G|N/A|train|mercantile|Priority\n" +
 		"37821_1|PENDING|alice|4|64G|N/A|train|cpuJob|Priority\n"
 
-	queue, users := parseQueueLines(raw, map[string]int{"37820": 2})
+	queue, users := SummarizeJobs(parseJobLines(raw, map[string]jobRequest{"37820": {GPUs: 2}}))
 	if len(users) != 1 {
 		t.Fatalf("expected one user, got %d", len(users))
 	}
@@ -191,6 +244,24 @@ func TestParseMemUtil(t *testing.T) {
 	}
 }
 
+func TestParseNodeLineSplitsPartitions(t *testing.T) {
+	node, err := parseNodeLine("NodeName=gpu01 State=IDLE CPUTot=64 Partitions=gpu,gpu-long,debug")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if node.Partition != "gpu,gpu-long,debug" || len(node.Partitions) != 3 || node.PrimaryPartition() != "gpu" {
+		t.Fatalf("unexpected partitions: %q %v", node.Partition, node.Partitions)
+	}
+
+	node, err = parseNodeLine("NodeName=spare01 State=IDLE CPUTot=64 Partitions=(null)")
+	if err != nil {
+		t.Fatalf("expected nil err, got %v", err)
+	}
+	if len(node.Partitions) != 0 || node.PrimaryPartition() != "" {
+		t.Fatalf("expected no partitions for (null), got %v", node.Partitions)
+	}
+}
+
 func TestCleanNodeStatePreservesDrainAndDownFlags(t *testing.T) {
 	tests := []struct {
 		in   string
@@ -206,3 +277,170 @@ func TestCleanNodeStatePreservesDrainAndDownFlags(t *testing.T) {
 		}
 	}
 }
+
+func TestParseJobLineExtendedLayout(t *testing.T) {
+	line := "3001_4|RUNNING|alice|16|64G|cpu=16,mem=64G,gres/gpu=2|gpu|train|None|3001|4|gpu-[01-02]|2026-02-25T09:00:00|2026-02-25T09:05:00|1-12:00:00"
+	job, ok := parseJobLine(line)
+	if !ok {
+		t.Fatalf("expected extended line to parse")
+	}
+	if job.ID != "3001_4" || job.ArrayJobID != "3001" || job.ArrayTaskID != "4" || job.RootID() != "3001" {
+		t.Fatalf("unexpected array identity: %+v", job)
+	}
+	if job.CPUs != 16 || job.MemMB != 65536 || job.GPUs != 2 {
+		t.Fatalf("unexpected resources: cpu=%d mem=%d gpu=%d", job.CPUs, job.MemMB, job.GPUs)
+	}
+	if job.NodeList != "gpu-[01-02]" || job.Reason != "None" || job.Name != "train" {
+		t.Fatalf("unexpected text fields: %+v", job)
+	}
+	wantSubmit := time.Date(2026, 2, 25, 9, 0, 0, 0, time.Local)
+	if !job.SubmitTime.Equal(wantSubmit) || job.StartTime.Sub(job.SubmitTime) != 5*time.Minute {
+		t.Fatalf("unexpected times: submit=%v start=%v", job.SubmitTime, job.StartTime)
+	}
+	if job.TimeLimit != 36*time.Hour {
+		t.Fatalf("unexpected time limit: %v", job.TimeLimit)
+	}
+}
+
+func TestParseJobLineKeepsPipeInJobName(t *testing.T) {
+	line := "3002|PENDING|bob|4|8G|N/A|cpu|prep|stage|two|Priority|3002|N/A||2026-02-25T09:00:00|N/A|UNLIMITED"
+	job, ok := parseJobLine(line)
+	if !ok {
+		t.Fatalf("expected line to parse")
+	}
+	if job.Name != "prep|stage|two" {
+		t.Fatalf("expected pipes to stay in job name, got %q", job.Name)
+	}
+	if job.Reason != "Priority" {
+		t.Fatalf("expected reason column to stay aligned, got %q", job.Reason)
+	}
+	if job.ArrayJobID != "" || job.ArrayTaskID != "" || job.RootID() != "3002" {
+		t.Fatalf("expected non-array job identity, got %+v", job)
+	}
+	if !job.StartTime.IsZero() || job.TimeLimit != 0 {
+		t.Fatalf("expected unknown start and unlimited time limit, got %v %v", job.StartTime, job.TimeLimit)
+	}
+}
+
+func TestParseTimeLimit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{in: "30:00", want: 30 * time.Minute},
+		{in: "2:00:00", want: 2 * time.Hour},
+		{in: "2-00:00:00", want: 48 * time.Hour},
+		{in: "1-06", want: 30 * time.Hour},
+		{in: "UNLIMITED", want: 0},
+		{in: "", want: 0},
+	}
+	for _, tt := range tests {
+		if got := parseTimeLimit(tt.in); got != tt.want {
+			t.Fatalf("parseTimeLimit(%q)=%v want=%v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSummarizeJobsCountsParsedJobs(t *testing.T) {
+	raw := "" +
+		"1001|RUNNING|alice|8|20G|cpu=8,mem=20G,gres/gpu=1|train|jobA|None\n" +
+		"1002|PENDING|alice|4|10G|N/A|train|jobB|Priority\n"
+	jobs := parseJobLines(raw, nil)
+	if len(jobs) != 2 {
+		t.Fatalf("expected 2 jobs, got %d", len(jobs))
+	}
+	queue, users := SummarizeJobs(jobs)
+	if queue.Running != 1 || queue.Pending != 1 || queue.RunningGPUJobs != 1 || queue.PendingCPUJobs != 1 {
+		t.Fatalf("unexpected queue counts: %+v", queue)
+	}
+	wantLoad := ResourceTotals{
+		RunningCPU:   8,
+		PendingCPU:   4,
+		RunningMemMB: 20480,
+		PendingMemMB: 10240,
+		RunningGPU:   1,
+	}
+	if queue.ResourceLoad != wantLoad {
+		t.Fatalf("unexpected resource load: got %+v want %+v", queue.ResourceLoad, wantLoad)
+	}
+	if len(users) != 1 {
+		t.Fatalf("expected one user, got %+v", users)
+	}
+	alice := users[0]
+	if alice.User != "alice" || alice.Running != 1 || alice.Pending != 1 {
+		t.Fatalf("unexpected user counts: %+v", alice)
+	}
+	if alice.RunningCPU != 8 || alice.RunningGPU != 1 || alice.PendingCPU != 4 || alice.PendingMemMB != 10240 || alice.PendingGPU != 0 {
+		t.Fatalf("unexpected user resources: %+v", alice)
+	}
+}
+
+func TestParseJobLinesAppliesPendingGPUFallback(t *testing.T) {
+	raw := "37820_1|PENDING|alice|4|64G|N/A|train|mercantile|Priority"
+	jobs := parseJobLines(raw, map[string]jobRequest{"37820": {GPUs: 2}})
+	if len(jobs) != 1 || jobs[0].GPUs != 2 {
+		t.Fatalf("expected fallback gpu count on job, got %+v", jobs)
+	}
+}
+
+func TestParseNodeLineKeepsDetailFields(t *testing.T) {
+	line := "NodeName=gpu-a01 Arch=x86_64 CoresPerSocket=32 CPUAlloc=8 CPUTot=64 AvailableFeatures=a100,ib ActiveFeatures=a100,ib " +
+		"Gres=gpu:a100:4(S:0-1) GresUsed=gpu:a100:1(IDX:0) OS=Linux 5.14.0-362.el9.x86_64 #1 SMP RealMemory=256000 AllocMem=0 " +
+		"Owner=N/A Weight=10 BootTime=2026-02-20T08:30:00 State=MIXED+DRAIN Partitions=gpu " +
+		"Reason=Not responding [slurm@2026-02-25T09:00:00]"
+	n, err := parseNodeLine(line)
+	if err != nil {
+		t.Fatalf("expected nil error, got %v", err)
+	}
+	if n.Features != "a100,ib" || n.Gres != "gpu:a100:4(S:0-1)" || n.GresUsed != "gpu:a100:1(IDX:0)" {
+		t.Fatalf("unexpected features/gres: %+v", n)
+	}
+	if n.Reason != "Not responding [slurm@2026-02-25T09:00:00]" {
+		t.Fatalf("expected multi-word reason kept whole, got %q", n.Reason)
+	}
+	if n.Owner != "" || n.Weight != 10 {
+		t.Fatalf("unexpected owner/weight: %q %d", n.Owner, n.Weight)
+	}
+	if n.BootTime.IsZero() || n.BootTime.Day() != 20 {
+		t.Fatalf("expected boot time parsed, got %v", n.BootTime)
+	}
+	if n.State != "MIXED+DRAIN" || n.CPUAlloc != 8 {
+		t.Fatalf("expected existing fields unaffected, got %+v", n)
+	}
+	var os string
+	for _, kv := range n.Fields {
+		if kv.Key == "OS" {
+			os = kv.Value
+		}
+	}
+	if os != "Linux 5.14.0-362.el9.x86_64 #1 SMP" {
+		t.Fatalf("expected OS value with spaces in Fields, got %q", os)
+	}
+	if n.Fields[0].Key != "NodeName" || n.Fields[1].Key != "Arch" {
+		t.Fatalf("expected fields in output order, got %+v", n.Fields[:2])
+	}
+}
+
+func TestSummarizeJobsSplitsMultiPartitionJobs(t *testing.T) {
+	jobs := []Job{
+		{ID: "1", State: "PENDING", User: "alice", Partition: "gpu,gpu-long"},
+		{ID: "2", State: "RUNNING", User: "alice", Partition: "gpu"},
+	}
+	queue, _ := SummarizeJobs(jobs)
+	if queue.Pending != 1 || queue.Running != 1 {
+		t.Fatalf("expected each job counted once in the queue, got %+v", queue)
+	}
+	byName := make(map[string]PartitionCount)
+	for _, p := range queue.ByPartition {
+		byName[p.Partition] = p
+	}
+	if _, ok := byName["gpu,gpu-long"]; ok || len(byName) != 2 {
+		t.Fatalf("expected only real partitions, got %+v", queue.ByPartition)
+	}
+	if gpu := byName["gpu"]; gpu.Pending != 1 || gpu.Running != 1 || gpu.Shared != 1 {
+		t.Fatalf("unexpected gpu counts: %+v", gpu)
+	}
+	if long := byName["gpu-long"]; long.Pending != 1 || long.Running != 0 || long.Shared != 1 {
+		t.Fatalf("unexpected gpu-long counts: %+v", long)
+	}
+}
@@ -0,0 +1,174 @@
+package slurm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Partition is one `scontrol show partition -o` record. Capacity and
+// allocation are summed from the partition's nodes when the snapshot has
+// them, so they agree with the node table; otherwise they come from the
+// partition's own TotalCPUs and TRES.
+type Partition struct {
+	Name    string
+	State   string
+	Default bool
+	// Nodes is the hostlist expression, such as gpu[01-16].
+	Nodes      string
+	TotalNodes int
+	// SharedNodes counts the partition's nodes that also belong to another
+	// partition. Their capacity is counted in full in each partition, so
+	// partition totals do not add up to the cluster total when it is non-zero.
+	SharedNodes int
+
+	TotalCPUs int
+	AllocCPUs int
+	// IdleCPUs counts unallocated CPUs on nodes that can take work, so
+	// drained and down nodes do not show up as free capacity.
+	IdleCPUs int
+
+	TotalMemMB int
+	AllocMemMB int
+
+	TotalGPUs int
+	AllocGPUs int
+
+	// Limits are zero when Slurm reports them as UNLIMITED or unset.
+	MaxTime         time.Duration
+	DefaultTime     time.Duration
+	MinNodes        int
+	MaxNodes        int
+	MaxCPUsPerNode  int
+	DefMemPerCPUMB  int
+	DefMemPerNodeMB int
+	MaxMemPerCPUMB  int
+	MaxMemPerNodeMB int
+
+	PriorityTier      int
+	PriorityJobFactor int
+	PreemptMode       string
+
+	// Fields holds every key=value pair scontrol reported, in output order.
+	Fields []KeyValue
+}
+
+func parsePartitionLines(raw string) ([]Partition, error) {
+	lines := strings.Split(raw, "\n")
+	out := make([]Partition, 0, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		pairs := parseKVPairs(line)
+		if kvMap(pairs)["PartitionName"] == "" {
+			return nil, fmt.Errorf("missing PartitionName in line: %s", line)
+		}
+		out = append(out, partitionFromFields(pairs))
+	}
+	return out, nil
+}
+
+// partitionFromFields builds a Partition from scontrol's key=value pairs. The
+// JSON paths map onto the same keys. Memory limits are in MB, as scontrol
+// prints them.
+func partitionFromFields(pairs []KeyValue) Partition {
+	fields := kvMap(pairs)
+	tres := fields["TRES"]
+	state := strings.ToUpper(fields["State"])
+	if state == "" {
+		state = "UNKNOWN"
+	}
+	return Partition{
+		Name:       fields["PartitionName"],
+		State:      state,
+		Default:    strings.EqualFold(fields["Default"], "YES"),
+		Nodes:      nullableField(fields["Nodes"]),
+		TotalNodes: parseInt(fields["TotalNodes"]),
+		TotalCPUs:  parseInt(fields["TotalCPUs"]),
+		TotalMemMB: parseMemMBFromTRES(tres),
+		TotalGPUs:  parseGPUCount(tres),
+
+		MaxTime:         parseTimeLimit(fields["MaxTime"]),
+		DefaultTime:     parseTimeLimit(fields["DefaultTime"]),
+		MinNodes:        parseInt(fields["MinNodes"]),
+		MaxNodes:        parseInt(fields["MaxNodes"]),
+		MaxCPUsPerNode:  parseInt(fields["MaxCPUsPerNode"]),
+		DefMemPerCPUMB:  parseInt(fields["DefMemPerCPU"]),
+		DefMemPerNodeMB: parseInt(fields["DefMemPerNode"]),
+		MaxMemPerCPUMB:  parseInt(fields["MaxMemPerCPU"]),
+		MaxMemPerNodeMB: parseInt(fields["MaxMemPerNode"]),
+
+		PriorityTier:      parseInt(fields["PriorityTier"]),
+		PriorityJobFactor: parseInt(fields["PriorityJobFactor"]),
+		PreemptMode:       nullableField(fields["PreemptMode"]),
+		Fields:            pairs,
+	}
+}
+
+// applyNodeUsage fills partition capacity and allocation from the nodes that
+// list each partition. A node in several partitions counts fully in each and
+// is counted in SharedNodes; a partition listed twice on a node counts once.
+// Earlier usage is replaced, so partitions carried over from a previous
+// snapshot can be applied to fresh nodes.
+func applyNodeUsage(parts []Partition, nodes []Node) {
+	index := make(map[string]int, len(parts))
+	for i := range parts {
+		index[parts[i].Name] = i
+		p := &parts[i]
+		p.SharedNodes, p.AllocCPUs, p.IdleCPUs, p.AllocMemMB, p.AllocGPUs = 0, 0, 0, 0, 0
+	}
+	seen := make([]bool, len(parts))
+	for _, n := range nodes {
+		names := uniqueStrings(n.Partitions)
+		shared := len(names) > 1
+		for _, name := range names {
+			i, ok := index[name]
+			if !ok {
+				continue
+			}
+			p := &parts[i]
+			if !seen[i] {
+				seen[i] = true
+				p.TotalNodes, p.TotalCPUs, p.TotalMemMB, p.TotalGPUs = 0, 0, 0, 0
+			}
+			p.TotalNodes++
+			if shared {
+				p.SharedNodes++
+			}
+			p.TotalCPUs += n.CPUTotal
+			p.AllocCPUs += n.CPUAlloc
+			p.TotalMemMB += n.MemTotalMB
+			p.AllocMemMB += n.MemAllocMB
+			p.TotalGPUs += n.GPUTotal
+			p.AllocGPUs += n.GPUAlloc
+			if nodeTakesWork(n.State) {
+				p.IdleCPUs += max(0, n.CPUTotal-n.CPUAlloc)
+			}
+		}
+	}
+}
+
+func uniqueStrings(in []string) []string {
+	out := make([]string, 0, len(in))
+	seen := make(map[string]bool, len(in))
+	for _, s := range in {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// nodeTakesWork reports whether the scheduler can start jobs on a node in
+// this state.
+func nodeTakesWork(state string) bool {
+	for _, flag := range []string{"DOWN", "DRAIN", "FAIL", "MAINT", "INVAL", "NOT_RESPONDING", "POWERED_DOWN", "UNKNOWN"} {
+		if strings.Contains(state, flag) {
+			return false
+		}
+	}
+	return true
+}
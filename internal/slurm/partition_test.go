@@ -0,0 +1,92 @@
+package slurm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePartitionLines(t *testing.T) {
+	raw := "PartitionName=gpu AllowGroups=ALL Default=YES DefaultTime=01:00:00 MaxNodes=4 MaxTime=2-00:00:00 MinNodes=0 MaxCPUsPerNode=UNLIMITED Nodes=gpu[01-02] PriorityJobFactor=1 PriorityTier=10 PreemptMode=OFF State=UP TotalCPUs=128 TotalNodes=2 DefMemPerCPU=4000 MaxMemPerNode=UNLIMITED TRES=cpu=128,mem=1000G,node=2,billing=128,gres/gpu=8\n" +
+		"PartitionName=debug Default=NO MaxTime=UNLIMITED Nodes=(null) State=DOWN TotalCPUs=0 TotalNodes=0\n"
+
+	parts, err := parsePartitionLines(raw)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 partitions, got %d", len(parts))
+	}
+	gpu := parts[0]
+	if gpu.Name != "gpu" || !gpu.Default || gpu.State != "UP" || gpu.Nodes != "gpu[01-02]" {
+		t.Fatalf("unexpected identity: %+v", gpu)
+	}
+	if gpu.TotalCPUs != 128 || gpu.TotalNodes != 2 || gpu.TotalGPUs != 8 || gpu.TotalMemMB != 1000*1024 {
+		t.Fatalf("unexpected capacity: %+v", gpu)
+	}
+	if gpu.MaxTime != 48*time.Hour || gpu.DefaultTime != time.Hour || gpu.MaxNodes != 4 || gpu.MaxCPUsPerNode != 0 || gpu.DefMemPerCPUMB != 4000 {
+		t.Fatalf("unexpected limits: %+v", gpu)
+	}
+	if gpu.PriorityTier != 10 || gpu.PreemptMode != "OFF" {
+		t.Fatalf("unexpected priority: %+v", gpu)
+	}
+	if debug := parts[1]; debug.Default || debug.Nodes != "" || debug.MaxTime != 0 || debug.State != "DOWN" {
+		t.Fatalf("unexpected debug partition: %+v", debug)
+	}
+
+	if _, err := parsePartitionLines("AllowGroups=ALL State=UP"); err == nil {
+		t.Fatalf("expected an error for a record without PartitionName")
+	}
+}
+
+func TestApplyNodeUsageSumsNodesPerPartition(t *testing.T) {
+	parts := []Partition{
+		{Name: "gpu", TotalCPUs: 999},
+		{Name: "all"},
+		{Name: "empty", TotalCPUs: 16, TotalNodes: 1},
+	}
+	nodes := []Node{
+		{Name: "gpu01", State: "MIXED", Partitions: []string{"gpu", "all"}, CPUTotal: 64, CPUAlloc: 16, MemTotalMB: 512000, MemAllocMB: 128000, GPUTotal: 4, GPUAlloc: 2},
+		{Name: "gpu02", State: "IDLE+DRAIN", Partitions: []string{"gpu", "all", "gpu"}, CPUTotal: 64, GPUTotal: 4},
+		{Name: "cpu01", State: "IDLE", Partitions: []string{"all"}, CPUTotal: 32},
+	}
+
+	applyNodeUsage(parts, nodes)
+
+	gpu := parts[0]
+	if gpu.TotalNodes != 2 || gpu.TotalCPUs != 128 || gpu.AllocCPUs != 16 || gpu.TotalGPUs != 8 || gpu.AllocGPUs != 2 || gpu.AllocMemMB != 128000 {
+		t.Fatalf("unexpected gpu usage: %+v", gpu)
+	}
+	if gpu.IdleCPUs != 48 {
+		t.Fatalf("expected the drained node excluded from idle CPUs, got %d", gpu.IdleCPUs)
+	}
+	if gpu.SharedNodes != 2 {
+		t.Fatalf("expected both gpu nodes marked shared, got %d", gpu.SharedNodes)
+	}
+	if all := parts[1]; all.TotalNodes != 3 || all.TotalCPUs != 160 || all.IdleCPUs != 80 || all.SharedNodes != 2 {
+		t.Fatalf("expected shared nodes counted in both partitions, got %+v", all)
+	}
+	if empty := parts[2]; empty.TotalCPUs != 16 || empty.TotalNodes != 1 {
+		t.Fatalf("expected scontrol totals kept without matching nodes, got %+v", empty)
+	}
+}
+
+func TestParsePartitionsJSONReadsFlatShape(t *testing.T) {
+	raw := `{"partitions": [{"name": "cpu", "nodes": "cpu[01-04]", "node_count": 4, "total_cpus": 128,
+		"flags": ["DEFAULT"], "state": "UP", "max_time_limit": 720, "default_time_limit": 30,
+		"priority_tier": 5, "preemption_mode": "requeue", "tres": "cpu=128,mem=1000G"}], "errors": []}`
+
+	parts, err := parsePartitionsJSON(raw)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(parts) != 1 {
+		t.Fatalf("expected 1 partition, got %d", len(parts))
+	}
+	p := parts[0]
+	if p.Name != "cpu" || !p.Default || p.State != "UP" || p.Nodes != "cpu[01-04]" || p.TotalNodes != 4 || p.TotalCPUs != 128 {
+		t.Fatalf("unexpected partition: %+v", p)
+	}
+	if p.MaxTime != 12*time.Hour || p.DefaultTime != 30*time.Minute || p.PriorityTier != 5 || p.PreemptMode != "REQUEUE" {
+		t.Fatalf("unexpected limits: %+v", p)
+	}
+}
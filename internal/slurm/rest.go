@@ -0,0 +1,288 @@
+package slurm
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultRESTVersion is the slurmrestd API version used when none is given.
+// The JSON models accept the v0.0.39 through v0.0.42 shapes.
+const DefaultRESTVersion = "v0.0.40"
+
+// RESTOptions configures a RESTCollector.
+type RESTOptions struct {
+	// BaseURL is the slurmrestd root, such as https://slurm.example.org:6820.
+	BaseURL string
+	// Version is the API path segment, such as v0.0.40.
+	Version string
+	// TokenFile holds a JWT. When empty the SLURM_JWT environment variable is
+	// used. The file is read on every request so a rotated token takes effect
+	// without a restart.
+	TokenFile string
+	// Timeout bounds each HTTP request.
+	Timeout time.Duration
+	Client  *http.Client
+}
+
+// RESTCollector builds snapshots from slurmrestd instead of running Slurm
+// commands, for clusters that expose the REST API but not interactive SSH.
+type RESTCollector struct {
+	opts   RESTOptions
+	client *http.Client
+
+	jobDetails jobDetailCache
+	sections   sectionCarry
+	// version is the last release slurmrestd reported, kept for snapshots
+	// whose node and job requests both failed.
+	version Version
+}
+
+func NewRESTCollector(opts RESTOptions) *RESTCollector {
+	opts.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+	if opts.Version == "" {
+		opts.Version = DefaultRESTVersion
+	}
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &RESTCollector{opts: opts, client: client}
+}
+
+func (c *RESTCollector) Describe() string {
+	return c.opts.BaseURL
+}
+
+// Ping checks that slurmrestd answers and accepts the token.
+func (c *RESTCollector) Ping(ctx context.Context) error {
+	_, err := c.DetectCapabilities(ctx)
+	return err
+}
+
+// DetectCapabilities pings slurmrestd and reports the release it runs on.
+// The REST API always returns structured data, so JSON and TRESAlloc hold.
+func (c *RESTCollector) DetectCapabilities(ctx context.Context) (Capabilities, error) {
+	var resp restResponse
+	if err := c.get(ctx, "ping", &resp); err != nil {
+		return Capabilities{}, err
+	}
+	version, _ := ParseVersion(resp.Meta.Slurm.Release)
+	return Capabilities{Version: version, JSON: true, TRESAlloc: true}, nil
+}
+
+// Collect reads nodes, jobs and partitions. As with Collector.Collect, an
+// endpoint that fails leaves its section stale or failed, and Collect returns
+// an error only when all three fail.
+func (c *RESTCollector) Collect(ctx context.Context) (Snapshot, error) {
+	return c.CollectSections(ctx, nil)
+}
+
+// CollectSections requests only the listed sources' endpoints, like
+// Collector.CollectSections.
+func (c *RESTCollector) CollectSections(ctx context.Context, sections []Section) (Snapshot, error) {
+	due := c.sections.due(sections)
+	snap := Snapshot{}
+	var version Version
+
+	var nodesResp restNodesResponse
+	var nodesErr error
+	if due[SectionNodes] {
+		nodesErr = c.get(ctx, "nodes", &nodesResp)
+	}
+	if due[SectionNodes] && nodesErr == nil {
+		snap.Nodes = make([]Node, 0, len(nodesResp.Nodes))
+		for _, n := range nodesResp.Nodes {
+			if n.Name == "" {
+				continue
+			}
+			snap.Nodes = append(snap.Nodes, nodeFromFields(n.fields()))
+		}
+		sort.Slice(snap.Nodes, func(i, j int) bool {
+			return snap.Nodes[i].Name < snap.Nodes[j].Name
+		})
+		version, _ = ParseVersion(nodesResp.Meta.Slurm.Release)
+	}
+
+	var jobsResp restJobsResponse
+	var jobsErr error
+	if due[SectionQueue] {
+		jobsErr = c.get(ctx, "jobs", &jobsResp)
+	}
+	if due[SectionQueue] && jobsErr == nil {
+		snap.Jobs = restJobsToQueue(jobsResp.Jobs)
+		snap.Queue, snap.Users = SummarizeJobs(snap.Jobs)
+		c.jobDetails.prune(snap.Jobs)
+		if version.IsZero() {
+			version, _ = ParseVersion(jobsResp.Meta.Slurm.Release)
+		}
+	}
+
+	var partitionsResp restPartitionsResponse
+	var partitionsErr error
+	if due[SectionPartitions] {
+		partitionsErr = c.get(ctx, "partitions", &partitionsResp)
+	}
+	if due[SectionPartitions] && partitionsErr == nil {
+		snap.Partitions = restPartitionsToModel(partitionsResp.Partitions)
+	}
+
+	snap.CollectedAt = time.Now()
+	err := c.sections.merge(&snap, []sectionResult{
+		{section: SectionNodes, err: wrapCollectErr("nodes", nodesErr), skipped: !due[SectionNodes]},
+		{section: SectionQueue, err: wrapCollectErr("jobs", jobsErr), skipped: !due[SectionQueue]},
+		{section: SectionPartitions, err: wrapCollectErr("partitions", partitionsErr), skipped: !due[SectionPartitions]},
+	})
+	if err != nil {
+		return Snapshot{}, err
+	}
+	applyNodeUsage(snap.Partitions, snap.Nodes)
+	if !version.IsZero() {
+		c.version = version
+	}
+	snap.SlurmVersion = c.version.String()
+	return snap, nil
+}
+
+func wrapCollectErr(what string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("collect %s: %w", what, err)
+}
+
+// JobDetail returns a job's record with scontrol's field names, so the detail
+// pane reads the same for both backends. Results are cached like
+// Collector.JobDetail.
+func (c *RESTCollector) JobDetail(ctx context.Context, id string) (JobDetail, error) {
+	id = strings.TrimSpace(id)
+	lookup, err := lookupJobID(id)
+	if err != nil {
+		return JobDetail{}, err
+	}
+	if cached, ok := c.jobDetails.get(id); ok {
+		return cached, nil
+	}
+
+	var resp restJobsResponse
+	if err := c.get(ctx, "job/"+lookup, &resp); err != nil {
+		return JobDetail{}, fmt.Errorf("show job %s: %w", id, err)
+	}
+	if len(resp.Jobs) == 0 {
+		return JobDetail{}, fmt.Errorf("show job %s: no job record in response", id)
+	}
+	detail := JobDetail{ID: id, Fields: resp.Jobs[0].fields(time.Now()), FetchedAt: time.Now()}
+	c.jobDetails.put(detail)
+	return detail, nil
+}
+
+// get fetches /slurm/<version>/<endpoint> and decodes it into out. Responses
+// are decoded as they stream in, since a large queue runs to hundreds of MB.
+func (c *RESTCollector) get(ctx context.Context, endpoint string, out restEnvelope) error {
+	path := "/slurm/" + c.opts.Version + "/" + endpoint
+	token, err := c.token()
+	if err != nil {
+		return err
+	}
+
+	if c.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.opts.BaseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("build slurmrestd request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-SLURM-USER-TOKEN", token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return &RESTError{Endpoint: path, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body restResponse
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body)
+		return &RESTError{Endpoint: path, Status: resp.StatusCode, Message: body.message()}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return &RESTError{Endpoint: path, Status: resp.StatusCode, Err: fmt.Errorf("decode response: %w", err)}
+	}
+	if msg := out.message(); msg != "" {
+		return &RESTError{Endpoint: path, Status: resp.StatusCode, Message: msg}
+	}
+	return nil
+}
+
+// token reads the JWT from the token file or SLURM_JWT. Both accept the
+// SLURM_JWT=<token> line that `scontrol token` prints.
+func (c *RESTCollector) token() (string, error) {
+	raw := os.Getenv("SLURM_JWT")
+	if c.opts.TokenFile != "" {
+		b, err := os.ReadFile(c.opts.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("read slurmrestd token: %w", err)
+		}
+		raw = string(b)
+	}
+	token := strings.TrimPrefix(strings.TrimSpace(raw), "SLURM_JWT=")
+	if token == "" {
+		return "", errors.New("no slurmrestd token: set SLURM_JWT or use a token file")
+	}
+	return token, nil
+}
+
+// RESTError is a failed slurmrestd request. Status is zero when no response
+// arrived.
+type RESTError struct {
+	Endpoint string
+	Status   int
+	Message  string
+	Err      error
+}
+
+func (e *RESTError) Error() string {
+	base := "slurmrestd " + e.Endpoint
+	if e.Status != 0 {
+		base += fmt.Sprintf(" [http %d]", e.Status)
+	}
+	if e.Message != "" {
+		base += ": " + e.Message
+	}
+	if e.Err != nil {
+		base += fmt.Sprintf(": %v", e.Err)
+	}
+	return base
+}
+
+func (e *RESTError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the failure may clear on its own: connection
+// problems other than certificate errors, rate limiting and server errors.
+// Authentication and schema errors need the user to act.
+func (e *RESTError) Retryable() bool {
+	if e.Status == 0 {
+		var unknownAuthority x509.UnknownAuthorityError
+		var hostname x509.HostnameError
+		var verify *tls.CertificateVerificationError
+		if errors.As(e.Err, &unknownAuthority) || errors.As(e.Err, &hostname) || errors.As(e.Err, &verify) {
+			return false
+		}
+		return !errors.Is(e.Err, context.Canceled)
+	}
+	return e.Status == http.StatusTooManyRequests || e.Status >= 500
+}
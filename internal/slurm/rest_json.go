@@ -0,0 +1,647 @@
+package slurm
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// restEnvelope is implemented by every slurmrestd response body. slurmrestd
+// can report errors in a 200 response, so message is checked after decoding.
+type restEnvelope interface {
+	message() string
+}
+
+type restProblem struct {
+	Description string `json:"description"`
+	Error       string `json:"error"`
+}
+
+type restResponse struct {
+	Meta   restMeta      `json:"meta"`
+	Errors []restProblem `json:"errors"`
+}
+
+// restMeta identifies the Slurm release behind the response. Older API
+// versions spell the key "Slurm", which the case-insensitive decoder accepts.
+type restMeta struct {
+	Slurm struct {
+		Release string `json:"release"`
+	} `json:"slurm"`
+}
+
+func (r *restResponse) message() string {
+	var parts []string
+	for _, p := range r.Errors {
+		switch {
+		case p.Description != "" && p.Error != "" && p.Description != p.Error:
+			parts = append(parts, p.Description+" ("+p.Error+")")
+		case p.Description != "":
+			parts = append(parts, p.Description)
+		case p.Error != "":
+			parts = append(parts, p.Error)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+type restNodesResponse struct {
+	restResponse
+	Nodes []restNode `json:"nodes"`
+}
+
+type restJobsResponse struct {
+	restResponse
+	Jobs []restJob `json:"jobs"`
+}
+
+// restPartitionsResponse keeps partitions as generic JSON because the record
+// changed shape across API versions: v0.0.38 and older flatten it (a "nodes"
+// string, "total_cpus"), while v0.0.39 and later nest it ("nodes.configured",
+// "cpus.total"). restPartition.fields reads either.
+type restPartitionsResponse struct {
+	restResponse
+	Partitions []restPartition `json:"partitions"`
+}
+
+type restPartition map[string]any
+
+// fields maps the partition onto the keys `scontrol show partition -o`
+// prints.
+func (p restPartition) fields() []KeyValue {
+	var out []KeyValue
+	add := func(key, value string) {
+		if value != "" {
+			out = append(out, KeyValue{Key: key, Value: value})
+		}
+	}
+	limit := func(n restNumber, format func(int) string) string {
+		if n.Infinite {
+			return "UNLIMITED"
+		}
+		if !n.Set {
+			return ""
+		}
+		return format(n.Int())
+	}
+	minutes := func(v int) string { return formatSlurmDuration(time.Duration(v) * time.Minute) }
+
+	flags := append(jsonStrings(p.lookup("flags")), jsonStrings(p.lookup("partition", "flags"))...)
+	isDefault := "NO"
+	for _, f := range flags {
+		if strings.EqualFold(f, "DEFAULT") {
+			isDefault = "YES"
+		}
+	}
+
+	add("PartitionName", jsonString(p.lookup("name")))
+	add("Default", isDefault)
+	add("DefaultTime", limit(p.number("defaults", "time", "default_time_limit"), minutes))
+	add("MaxNodes", limit(p.number("maximums", "nodes", "max_nodes"), strconv.Itoa))
+	add("MaxTime", limit(p.number("maximums", "time", "max_time_limit"), minutes))
+	add("MinNodes", limit(p.number("minimums", "nodes", "min_nodes"), strconv.Itoa))
+	add("MaxCPUsPerNode", limit(p.number("maximums", "cpus_per_node", "max_cpus_per_node"), strconv.Itoa))
+	add("Nodes", p.text("nodes", "configured", "nodes"))
+	add("PriorityJobFactor", limit(p.number("priority", "job_factor", "priority_job_factor"), strconv.Itoa))
+	add("PriorityTier", limit(p.number("priority", "tier", "priority_tier"), strconv.Itoa))
+	preempt := jsonStrings(p.lookup("preempt_mode"))
+	if len(preempt) == 0 {
+		preempt = jsonStrings(p.lookup("preemption_mode"))
+	}
+	add("PreemptMode", strings.ToUpper(strings.Join(preempt, ",")))
+	add("State", strings.ToUpper(strings.Join(p.list("partition", "state", "state"), "+")))
+	add("TotalCPUs", limit(p.number("cpus", "total", "total_cpus"), strconv.Itoa))
+	add("TotalNodes", limit(p.number("nodes", "total", "node_count"), strconv.Itoa))
+	add("DefMemPerCPU", limit(p.number("defaults", "partition_memory_per_cpu", "default_memory_per_cpu"), strconv.Itoa))
+	add("DefMemPerNode", limit(p.number("defaults", "partition_memory_per_node", "default_memory_per_node"), strconv.Itoa))
+	add("MaxMemPerCPU", limit(p.number("maximums", "partition_memory_per_cpu", "maximum_memory_per_cpu"), strconv.Itoa))
+	add("MaxMemPerNode", limit(p.number("maximums", "partition_memory_per_node", "maximum_memory_per_node"), strconv.Itoa))
+	add("TRES", p.text("tres", "configured", "tres"))
+	return out
+}
+
+func (p restPartition) lookup(path ...string) any {
+	var cur any = map[string]any(p)
+	for _, key := range path {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = m[key]
+	}
+	return cur
+}
+
+// number reads the nested group.key form and falls back to the flat key.
+func (p restPartition) number(group, key, flat string) restNumber {
+	if v := p.lookup(group, key); v != nil {
+		return jsonNumber(v)
+	}
+	return jsonNumber(p.lookup(flat))
+}
+
+func (p restPartition) text(group, key, flat string) string {
+	if v := p.lookup(group, key); v != nil {
+		return jsonString(v)
+	}
+	return jsonString(p.lookup(flat))
+}
+
+func (p restPartition) list(group, key, flat string) []string {
+	if v := p.lookup(group, key); v != nil {
+		return jsonStrings(v)
+	}
+	return jsonStrings(p.lookup(flat))
+}
+
+// jsonNumber converts a decoded number or {"set","infinite","number"} object.
+func jsonNumber(v any) restNumber {
+	switch v := v.(type) {
+	case float64:
+		return restNumber{Set: true, Number: v}
+	case map[string]any:
+		set, _ := v["set"].(bool)
+		infinite, _ := v["infinite"].(bool)
+		number, _ := v["number"].(float64)
+		return restNumber{Set: set, Infinite: infinite, Number: number}
+	default:
+		return restNumber{}
+	}
+}
+
+func jsonString(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+// jsonStrings converts a decoded string list or comma-separated string.
+func jsonStrings(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return splitNonEmpty(v, ",")
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// restNumber decodes both plain JSON numbers, as older API versions send
+// them, and the {"set","infinite","number"} objects of v0.0.40 and later.
+type restNumber struct {
+	Set      bool
+	Infinite bool
+	Number   float64
+}
+
+func (n *restNumber) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		*n = restNumber{}
+		return nil
+	case len(data) > 0 && data[0] == '{':
+		var obj struct {
+			Set      bool    `json:"set"`
+			Infinite bool    `json:"infinite"`
+			Number   float64 `json:"number"`
+		}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		*n = restNumber(obj)
+		return nil
+	}
+	var v float64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*n = restNumber{Set: true, Number: v}
+	return nil
+}
+
+// Int returns the value, or 0 when it is unset or infinite.
+func (n restNumber) Int() int {
+	if !n.Set || n.Infinite {
+		return 0
+	}
+	return int(n.Number)
+}
+
+func (n restNumber) String() string {
+	if !n.Set || n.Infinite {
+		return ""
+	}
+	return strconv.Itoa(n.Int())
+}
+
+// Time returns the value as Unix seconds, or the zero time when unset.
+func (n restNumber) Time() time.Time {
+	if n.Int() <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(n.Int()), 0)
+}
+
+// restStrings decodes a list that older API versions send as a
+// comma-separated string and newer ones as a JSON array.
+type restStrings []string
+
+func (s *restStrings) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var list []string
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+		*s = list
+		return nil
+	}
+	var v string
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*s = splitNonEmpty(v, ",")
+	return nil
+}
+
+type restNode struct {
+	Name            string      `json:"name"`
+	Architecture    string      `json:"architecture"`
+	OperatingSystem string      `json:"operating_system"`
+	Address         string      `json:"address"`
+	Hostname        string      `json:"hostname"`
+	State           restStrings `json:"state"`
+	Cores           restNumber  `json:"cores"`
+	Sockets         restNumber  `json:"sockets"`
+	Threads         restNumber  `json:"threads"`
+	CPUs            restNumber  `json:"cpus"`
+	AllocCPUs       restNumber  `json:"alloc_cpus"`
+	CPULoad         restNumber  `json:"cpu_load"`
+	RealMemory      restNumber  `json:"real_memory"`
+	AllocMemory     restNumber  `json:"alloc_memory"`
+	FreeMem         restNumber  `json:"free_mem"`
+	Features        restStrings `json:"features"`
+	ActiveFeatures  restStrings `json:"active_features"`
+	Gres            string      `json:"gres"`
+	GresUsed        string      `json:"gres_used"`
+	TRES            string      `json:"tres"`
+	TRESUsed        string      `json:"tres_used"`
+	Partitions      restStrings `json:"partitions"`
+	Reason          string      `json:"reason"`
+	ReasonSetBy     string      `json:"reason_set_by_user"`
+	ReasonChangedAt restNumber  `json:"reason_changed_at"`
+	BootTime        restNumber  `json:"boot_time"`
+	Weight          restNumber  `json:"weight"`
+	Owner           string      `json:"owner"`
+}
+
+// fields maps the node onto the keys `scontrol show node -o` prints, in the
+// same order, with Reason last as scontrol puts it.
+func (n restNode) fields() []KeyValue {
+	var out []KeyValue
+	add := func(key, value string) {
+		if value != "" {
+			out = append(out, KeyValue{Key: key, Value: value})
+		}
+	}
+	cpuLoad := "N/A"
+	if n.CPULoad.Set {
+		// slurmrestd reports load in hundredths.
+		cpuLoad = strconv.FormatFloat(n.CPULoad.Number/100, 'f', 2, 64)
+	}
+	freeMem := "N/A"
+	if n.FreeMem.Set {
+		freeMem = n.FreeMem.String()
+	}
+
+	add("NodeName", n.Name)
+	add("Arch", n.Architecture)
+	add("CoresPerSocket", n.Cores.String())
+	add("CPUAlloc", n.AllocCPUs.String())
+	add("CPUTot", n.CPUs.String())
+	add("CPULoad", cpuLoad)
+	add("AvailableFeatures", strings.Join(n.Features, ","))
+	add("ActiveFeatures", strings.Join(n.ActiveFeatures, ","))
+	add("Gres", n.Gres)
+	add("GresUsed", n.GresUsed)
+	add("NodeAddr", n.Address)
+	add("NodeHostName", n.Hostname)
+	add("OS", n.OperatingSystem)
+	add("RealMemory", n.RealMemory.String())
+	add("AllocMem", n.AllocMemory.String())
+	add("FreeMem", freeMem)
+	add("Sockets", n.Sockets.String())
+	add("State", strings.ToUpper(strings.Join(n.State, "+")))
+	add("ThreadsPerCore", n.Threads.String())
+	add("Weight", n.Weight.String())
+	add("Owner", n.Owner)
+	add("Partitions", strings.Join(n.Partitions, ","))
+	add("BootTime", formatSlurmTime(n.BootTime.Time()))
+	add("CfgTRES", n.TRES)
+	add("AllocTRES", n.TRESUsed)
+	if n.Reason != "" {
+		reason := n.Reason
+		if n.ReasonSetBy != "" {
+			reason += " [" + n.ReasonSetBy + "@" + formatSlurmTime(n.ReasonChangedAt.Time()) + "]"
+		}
+		add("Reason", reason)
+	}
+	return out
+}
+
+type restJob struct {
+	JobID           restNumber  `json:"job_id"`
+	ArrayJobID      restNumber  `json:"array_job_id"`
+	ArrayTaskID     restNumber  `json:"array_task_id"`
+	ArrayTaskString string      `json:"array_task_string"`
+	Name            string      `json:"name"`
+	UserName        string      `json:"user_name"`
+	Account         string      `json:"account"`
+	QOS             string      `json:"qos"`
+	Partition       string      `json:"partition"`
+	JobState        restStrings `json:"job_state"`
+	StateReason     string      `json:"state_reason"`
+	Dependency      string      `json:"dependency"`
+	Priority        restNumber  `json:"priority"`
+	CPUs            restNumber  `json:"cpus"`
+	NodeCount       restNumber  `json:"node_count"`
+	MemoryPerNode   restNumber  `json:"memory_per_node"`
+	MemoryPerCPU    restNumber  `json:"memory_per_cpu"`
+	TRESReq         string      `json:"tres_req_str"`
+	TRESAlloc       string      `json:"tres_alloc_str"`
+	Nodes           string      `json:"nodes"`
+	SubmitTime      restNumber  `json:"submit_time"`
+	EligibleTime    restNumber  `json:"eligible_time"`
+	StartTime       restNumber  `json:"start_time"`
+	EndTime         restNumber  `json:"end_time"`
+	TimeLimit       restNumber  `json:"time_limit"`
+	Command         string      `json:"command"`
+	WorkDir         string      `json:"current_working_directory"`
+	StdOut          string      `json:"standard_output"`
+	StdErr          string      `json:"standard_error"`
+}
+
+func (j restJob) state() string {
+	if len(j.JobState) == 0 {
+		return ""
+	}
+	return strings.ToUpper(j.JobState[0])
+}
+
+// memMB returns the job's total memory the way jobMemMB does for squeue:
+// the mem TRES first, then the per-node or per-CPU request scaled up.
+func (j restJob) memMB(tres string) int {
+	if mem := parseMemMBFromTRES(tres); mem > 0 {
+		return mem
+	}
+	if mem := j.MemoryPerNode.Int(); mem > 0 {
+		return mem * max(j.NodeCount.Int(), 1)
+	}
+	return j.MemoryPerCPU.Int() * j.CPUs.Int()
+}
+
+// jobs converts the record into squeue -r rows. slurmrestd lists the pending
+// part of an array as one record with a task range, which is expanded here
+// so counts match the command backend.
+func (j restJob) jobs() []Job {
+	tres := j.TRESAlloc
+	if tres == "" {
+		tres = j.TRESReq
+	}
+	gpus, gpuTypes := parseGPUs(tres)
+	job := Job{
+		ID:         j.JobID.String(),
+		State:      j.state(),
+		User:       j.UserName,
+		Partition:  j.Partition,
+		Name:       j.Name,
+		CPUs:       j.CPUs.Int(),
+		MemMB:      j.memMB(tres),
+		GPUs:       gpus,
+		GPUTypes:   gpuTypes,
+		Reason:     j.StateReason,
+		NodeList:   j.Nodes,
+		SubmitTime: j.SubmitTime.Time(),
+		StartTime:  j.StartTime.Time(),
+		TimeLimit:  time.Duration(j.TimeLimit.Int()) * time.Minute,
+	}
+	if job.User == "" {
+		job.User = "<unknown>"
+	}
+	if job.Partition == "" {
+		job.Partition = "<unknown>"
+	}
+	if job.Name == "" {
+		job.Name = "<unnamed>"
+	}
+
+	root := j.ArrayJobID.Int()
+	if root == 0 {
+		return []Job{job}
+	}
+	job.ArrayJobID = strconv.Itoa(root)
+	if j.ArrayTaskID.Set {
+		job.ArrayTaskID = j.ArrayTaskID.String()
+		job.ID = job.ArrayJobID + "_" + job.ArrayTaskID
+		return []Job{job}
+	}
+	tasks, rest, first, last := expandArrayTasks(j.ArrayTaskString)
+	if len(tasks) == 0 {
+		return []Job{job}
+	}
+	out := make([]Job, len(tasks), len(tasks)+1)
+	for i, task := range tasks {
+		out[i] = job
+		out[i].ArrayTaskID = task
+		out[i].ID = job.ArrayJobID + "_" + task
+	}
+	if rest > 0 {
+		// The tasks past the expansion cap share one record that counts
+		// for all of them, named the way squeue prints a pending range.
+		agg := job
+		agg.ArrayTaskID = "[" + strconv.Itoa(first) + "-" + strconv.Itoa(last) + "]"
+		agg.ID = job.ArrayJobID + "_" + agg.ArrayTaskID
+		agg.Tasks = rest
+		out = append(out, agg)
+	}
+	return out
+}
+
+// restJobsToQueue expands job records from slurmrestd /jobs or squeue --json
+// into squeue -r rows. Both list jobs that finished within MinJobAge, which
+// the text squeue hides, so those are dropped to keep counts identical across
+// collectors.
+func restJobsToQueue(records []restJob) []Job {
+	jobs := make([]Job, 0, len(records))
+	for _, r := range records {
+		if finishedJobState(r.state()) {
+			continue
+		}
+		jobs = append(jobs, r.jobs()...)
+	}
+	return jobs
+}
+
+func finishedJobState(state string) bool {
+	switch state {
+	case "COMPLETED", "CANCELLED", "FAILED", "TIMEOUT", "NODE_FAIL", "PREEMPTED",
+		"BOOT_FAIL", "DEADLINE", "OUT_OF_MEMORY", "REVOKED", "SPECIAL_EXIT":
+		return true
+	default:
+		return false
+	}
+}
+
+// fields maps the job onto the keys `scontrol show job -o` prints.
+func (j restJob) fields(now time.Time) []KeyValue {
+	var out []KeyValue
+	add := func(key, value string) {
+		if value != "" {
+			out = append(out, KeyValue{Key: key, Value: value})
+		}
+	}
+	timeLimit := "UNLIMITED"
+	if !j.TimeLimit.Infinite && j.TimeLimit.Set {
+		timeLimit = formatSlurmDuration(time.Duration(j.TimeLimit.Int()) * time.Minute)
+	}
+	runTime := ""
+	if start := j.StartTime.Time(); !start.IsZero() && start.Before(now) && j.state() == "RUNNING" {
+		runTime = formatSlurmDuration(now.Sub(start).Truncate(time.Second))
+	}
+	reqMemNode, reqMemCPU := "", ""
+	if mem := j.MemoryPerNode.Int(); mem > 0 {
+		reqMemNode = strconv.Itoa(mem) + "M"
+	} else if mem := j.MemoryPerCPU.Int(); mem > 0 {
+		reqMemCPU = strconv.Itoa(mem) + "M"
+	}
+
+	add("JobId", j.JobID.String())
+	if j.ArrayJobID.Int() != 0 {
+		add("ArrayJobId", j.ArrayJobID.String())
+		if j.ArrayTaskID.Set {
+			add("ArrayTaskId", j.ArrayTaskID.String())
+		} else {
+			add("ArrayTaskId", j.ArrayTaskString)
+		}
+	}
+	add("JobName", j.Name)
+	add("UserId", j.UserName)
+	add("Account", j.Account)
+	add("QOS", j.QOS)
+	add("Priority", j.Priority.String())
+	add("JobState", j.state())
+	add("Reason", j.StateReason)
+	add("Dependency", j.Dependency)
+	add("RunTime", runTime)
+	add("TimeLimit", timeLimit)
+	add("SubmitTime", formatSlurmTime(j.SubmitTime.Time()))
+	add("EligibleTime", formatSlurmTime(j.EligibleTime.Time()))
+	add("StartTime", formatSlurmTime(j.StartTime.Time()))
+	add("EndTime", formatSlurmTime(j.EndTime.Time()))
+	add("Partition", j.Partition)
+	add("NodeList", j.Nodes)
+	add("NumNodes", j.NodeCount.String())
+	add("NumCPUs", j.CPUs.String())
+	add("ReqTRES", j.TRESReq)
+	add("AllocTRES", j.TRESAlloc)
+	add("MinMemoryNode", reqMemNode)
+	add("MinMemoryCPU", reqMemCPU)
+	add("Command", j.Command)
+	add("WorkDir", j.WorkDir)
+	add("StdErr", j.StdErr)
+	add("StdOut", j.StdOut)
+	return out
+}
+
+// maxArrayTaskExpansion bounds expandArrayTasks so a huge or malformed task
+// range cannot create a record per task every cycle.
+const maxArrayTaskExpansion = 1 << 16
+
+// expandArrayTasks expands a Slurm task list such as 1-5,8,10-20:5%4 into
+// task IDs. The %N throttle suffix is ignored. At most maxArrayTaskExpansion
+// IDs are returned; rest counts the tasks past the cap and first and last
+// name the lowest and highest of them.
+func expandArrayTasks(spec string) (tasks []string, rest, first, last int) {
+	spec, _, _ = strings.Cut(spec, "%")
+	for _, part := range splitNonEmpty(spec, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, ":")
+		step := 1
+		if hasStep {
+			if n, err := strconv.Atoi(stepPart); err == nil && n > 0 {
+				step = n
+			}
+		}
+		lo, hi, isRange := strings.Cut(rangePart, "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			continue
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(hi); err != nil {
+				continue
+			}
+		}
+		i := start
+		for ; i <= end && len(tasks) < maxArrayTaskExpansion; i += step {
+			tasks = append(tasks, strconv.Itoa(i))
+		}
+		if i > end {
+			continue
+		}
+		n := (end-i)/step + 1
+		top := i + (n-1)*step
+		if rest == 0 || i < first {
+			first = i
+		}
+		if rest == 0 || top > last {
+			last = top
+		}
+		rest += n
+	}
+	return tasks, rest, first, last
+}
+
+func formatSlurmTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(time.Local).Format(slurmTimeLayout)
+}
+
+// formatSlurmDuration renders d as Slurm's [days-]hours:minutes:seconds.
+func formatSlurmDuration(d time.Duration) string {
+	total := int(d / time.Second)
+	days, rest := total/86400, total%86400
+	hms := fmt2(rest/3600) + ":" + fmt2(rest%3600/60) + ":" + fmt2(rest%60)
+	if days > 0 {
+		return strconv.Itoa(days) + "-" + hms
+	}
+	return hms
+}
+
+func fmt2(n int) string {
+	if n < 10 {
+		return "0" + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
+
+func splitNonEmpty(s, sep string) []string {
+	var out []string
+	for _, part := range strings.Split(s, sep) {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
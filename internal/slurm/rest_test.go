@@ -0,0 +1,297 @@
+package slurm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"slurm_monitor/internal/transport"
+)
+
+const restNodesJSON = `{
+  "meta": {"Slurm": {"version": {"major": 23, "minor": 11, "micro": 4}, "release": "23.11.4"}},
+  "nodes": [
+    {"name": "gpu01", "state": ["MIXED"], "cpus": 64, "alloc_cpus": 16,
+     "cpu_load": 1250, "real_memory": 512000, "alloc_memory": 128000,
+     "free_mem": {"set": true, "infinite": false, "number": 300000},
+     "features": ["a100", "ib"], "active_features": ["a100", "ib"],
+     "gres": "gpu:a100:4(S:0-1)", "tres": "cpu=64,mem=500G,billing=64,gres/gpu=4",
+     "tres_used": "cpu=16,mem=128000M,gres/gpu=2", "partitions": ["gpu", "scavenge"],
+     "boot_time": {"set": true, "infinite": false, "number": 1771576200}, "weight": 10},
+    {"name": "cpu01", "state": ["IDLE", "DRAIN"], "cpus": 32, "alloc_cpus": 0,
+     "cpu_load": {"set": false, "infinite": false, "number": 0}, "real_memory": 256000,
+     "alloc_memory": 0, "partitions": "cpu", "features": "icelake",
+     "reason": "bad DIMM", "reason_set_by_user": "root",
+     "reason_changed_at": {"set": true, "infinite": false, "number": 1771576200}}
+  ],
+  "errors": [], "warnings": []
+}`
+
+const restJobsJSON = `{
+  "jobs": [
+    {"job_id": 101, "array_job_id": {"set": true, "infinite": false, "number": 0},
+     "array_task_id": {"set": false, "infinite": false, "number": 0},
+     "name": "train", "user_name": "alice", "partition": "gpu", "job_state": ["RUNNING"],
+     "state_reason": "None", "cpus": {"set": true, "infinite": false, "number": 16},
+     "memory_per_node": {"set": true, "infinite": false, "number": 128000},
+     "tres_alloc_str": "cpu=16,mem=125G,node=1,gres/gpu=2", "nodes": "gpu01",
+     "submit_time": {"set": true, "infinite": false, "number": 1771576200},
+     "start_time": {"set": true, "infinite": false, "number": 1771576260},
+     "time_limit": {"set": true, "infinite": false, "number": 240}},
+    {"job_id": 200, "array_job_id": {"set": true, "infinite": false, "number": 200},
+     "array_task_id": {"set": false, "infinite": false, "number": 0},
+     "array_task_string": "2-4,7%2", "name": "sweep", "user_name": "bob",
+     "partition": "gpu", "job_state": ["PENDING"], "state_reason": "Priority",
+     "cpus": {"set": true, "infinite": false, "number": 4},
+     "memory_per_cpu": {"set": true, "infinite": false, "number": 4000},
+     "tres_req_str": "cpu=4,mem=16000M,node=1,billing=4,gres/gpu=1",
+     "time_limit": {"set": false, "infinite": true, "number": 0}},
+    {"job_id": 201, "array_job_id": {"set": true, "infinite": false, "number": 200},
+     "array_task_id": {"set": true, "infinite": false, "number": 1},
+     "name": "sweep", "user_name": "bob", "partition": "gpu", "job_state": ["RUNNING"],
+     "cpus": {"set": true, "infinite": false, "number": 4},
+     "tres_alloc_str": "cpu=4,mem=16000M,node=1,gres/gpu=1", "nodes": "gpu01"},
+    {"job_id": 102, "name": "done", "user_name": "alice", "partition": "gpu",
+     "job_state": ["COMPLETED"], "cpus": {"set": true, "infinite": false, "number": 8}}
+  ],
+  "errors": [], "warnings": []
+}`
+
+// restPartitionsJSON uses the nested v0.0.39+ partition shape.
+const restPartitionsJSON = `{
+  "partitions": [
+    {"name": "gpu", "nodes": {"configured": "gpu[01-02]", "total": 2},
+     "cpus": {"total": 128}, "tres": {"configured": "cpu=128,mem=1000G,gres/gpu=8"},
+     "partition": {"state": ["UP"]}, "flags": ["DEFAULT"],
+     "maximums": {"time": {"set": true, "infinite": false, "number": 2880},
+                  "nodes": {"set": true, "infinite": false, "number": 2}},
+     "defaults": {"time": {"set": true, "infinite": false, "number": 60}},
+     "priority": {"tier": 10, "job_factor": 1}, "preempt_mode": ["OFF"]},
+    {"name": "scavenge", "nodes": {"configured": "gpu01,cpu01", "total": 2},
+     "partition": {"state": ["UP"]},
+     "maximums": {"time": {"set": false, "infinite": true, "number": 0}},
+     "priority": {"tier": 1, "job_factor": 1}, "preempt_mode": ["REQUEUE"]}
+  ],
+  "errors": [], "warnings": []
+}`
+
+func newRESTServer(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-SLURM-USER-TOKEN") != token {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errors": [{"description": "Authentication failure", "error": "Invalid authentication"}]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/slurm/v0.0.40/nodes":
+			w.Write([]byte(restNodesJSON))
+		case "/slurm/v0.0.40/jobs":
+			w.Write([]byte(restJobsJSON))
+		case "/slurm/v0.0.40/partitions":
+			w.Write([]byte(restPartitionsJSON))
+		case "/slurm/v0.0.40/job/101":
+			w.Write([]byte(`{"jobs": [{"job_id": 101, "name": "train", "job_state": ["RUNNING"],
+				"tres_req_str": "cpu=16,gres/gpu=2", "nodes": "gpu01", "command": "/home/alice/train.sh",
+				"time_limit": {"set": true, "infinite": false, "number": 1500}}]}`))
+		case "/slurm/v0.0.40/ping":
+			w.Write([]byte(`{"meta": {"slurm": {"release": "23.11.4"}}, "pings": [{"hostname": "ctl", "pinged": "UP"}], "errors": []}`))
+		case "/slurm/v0.0.40/diag":
+			w.Write([]byte(`{"errors": [{"description": "Unable to contact slurmctld"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRESTCollectorBuildsSnapshot(t *testing.T) {
+	srv := newRESTServer(t, "tok")
+	t.Setenv("SLURM_JWT", "tok")
+	c := NewRESTCollector(RESTOptions{BaseURL: srv.URL + "/", Timeout: time.Second})
+
+	snap, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	if snap.SlurmVersion != "23.11.4" {
+		t.Fatalf("expected the release from response meta, got %q", snap.SlurmVersion)
+	}
+	if len(snap.Nodes) != 2 || snap.Nodes[0].Name != "cpu01" {
+		t.Fatalf("expected nodes sorted by name, got %+v", snap.Nodes)
+	}
+	gpu := snap.Nodes[1]
+	if gpu.CPUAlloc != 16 || gpu.GPUAlloc != 2 || gpu.GPUTotal != 4 || gpu.MemAllocMB != 128000 {
+		t.Fatalf("unexpected gpu node: %+v", gpu)
+	}
+	if !gpu.HasCPU || gpu.CPUUtil < 19 || gpu.CPUUtil > 20 || gpu.Partition != "gpu,scavenge" {
+		t.Fatalf("unexpected load or partitions: util=%v partition=%q", gpu.CPUUtil, gpu.Partition)
+	}
+	cpu := snap.Nodes[0]
+	if cpu.State != "IDLE+DRAIN" || cpu.HasCPU || cpu.Reason == "" || cpu.Features != "icelake" {
+		t.Fatalf("unexpected drained node: %+v", cpu)
+	}
+	if len(snap.Partitions) != 2 {
+		t.Fatalf("expected 2 partitions, got %+v", snap.Partitions)
+	}
+	part := snap.Partitions[0]
+	if part.Name != "gpu" || !part.Default || part.MaxTime != 48*time.Hour || part.PriorityTier != 10 || part.PreemptMode != "OFF" {
+		t.Fatalf("unexpected gpu partition: %+v", part)
+	}
+	// Capacity comes from the nodes that list the partition: only gpu01 is
+	// in the snapshot.
+	if part.TotalNodes != 1 || part.TotalCPUs != 64 || part.AllocCPUs != 16 || part.TotalGPUs != 4 || part.AllocGPUs != 2 {
+		t.Fatalf("expected node-derived capacity, got %+v", part)
+	}
+	if scav := snap.Partitions[1]; scav.MaxTime != 0 || scav.PreemptMode != "REQUEUE" || scav.IdleCPUs != 48 {
+		t.Fatalf("expected drained cpu01 excluded from idle CPUs, got %+v", scav)
+	}
+
+	// One running job, four expanded pending tasks and one running task; the
+	// completed job is dropped.
+	if len(snap.Jobs) != 6 || snap.Queue.Running != 2 || snap.Queue.Pending != 4 {
+		t.Fatalf("unexpected jobs: %d running=%d pending=%d", len(snap.Jobs), snap.Queue.Running, snap.Queue.Pending)
+	}
+	byID := make(map[string]Job)
+	for _, j := range snap.Jobs {
+		byID[j.ID] = j
+	}
+	task, ok := byID["200_7"]
+	if !ok || task.ArrayJobID != "200" || task.GPUs != 1 || task.MemMB != 16000 || task.TimeLimit != 0 {
+		t.Fatalf("unexpected expanded task: %+v", task)
+	}
+	if running := byID["200_1"]; running.NodeList != "gpu01" || running.State != "RUNNING" {
+		t.Fatalf("unexpected running task: %+v", running)
+	}
+	if job := byID["101"]; job.TimeLimit != 4*time.Hour || job.StartTime.IsZero() || job.GPUs != 2 {
+		t.Fatalf("unexpected plain job: %+v", job)
+	}
+	if snap.Queue.ResourceLoad.PendingGPU != 4 {
+		t.Fatalf("expected 4 pending GPUs, got %d", snap.Queue.ResourceLoad.PendingGPU)
+	}
+
+	caps, err := c.DetectCapabilities(context.Background())
+	if err != nil || caps.Version != (Version{23, 11, 4}) || !caps.JSON {
+		t.Fatalf("unexpected capabilities %+v: %v", caps, err)
+	}
+}
+
+func TestRESTCollectorJobDetailUsesScontrolKeys(t *testing.T) {
+	srv := newRESTServer(t, "tok")
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("SLURM_JWT=tok\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SLURM_JWT", "wrong")
+	c := NewRESTCollector(RESTOptions{BaseURL: srv.URL, TokenFile: path, Timeout: time.Second})
+
+	d, err := c.JobDetail(context.Background(), "101")
+	if err != nil {
+		t.Fatalf("job detail: %v", err)
+	}
+	if d.Field("ReqTRES") != "cpu=16,gres/gpu=2" || d.Field("NodeList") != "gpu01" || d.Field("TimeLimit") != "1-01:00:00" {
+		t.Fatalf("unexpected detail fields: %+v", d.Fields)
+	}
+	if _, err := c.JobDetail(context.Background(), "101;rm"); err == nil {
+		t.Fatalf("expected an unsafe job id to be rejected")
+	}
+}
+
+func TestRESTErrorsClassifyRetries(t *testing.T) {
+	srv := newRESTServer(t, "tok")
+	t.Setenv("SLURM_JWT", "bad")
+	c := NewRESTCollector(RESTOptions{BaseURL: srv.URL, Timeout: time.Second})
+
+	_, err := c.Collect(context.Background())
+	var restErr *RESTError
+	if !errors.As(err, &restErr) || restErr.Status != http.StatusUnauthorized || transport.IsRetryable(err) {
+		t.Fatalf("expected a permanent 401, got %v", err)
+	}
+	if restErr.Message != "Authentication failure (Invalid authentication)" {
+		t.Fatalf("unexpected message %q", restErr.Message)
+	}
+
+	t.Setenv("SLURM_JWT", "tok")
+	var resp restResponse
+	if err := c.get(context.Background(), "diag", &resp); err == nil || transport.IsRetryable(err) {
+		t.Fatalf("expected errors in a 200 body to fail, got %v", err)
+	}
+
+	srv.Close()
+	if err := c.Ping(context.Background()); !transport.IsRetryable(err) {
+		t.Fatalf("expected a connection failure to be retryable, got %v", err)
+	}
+
+	t.Setenv("SLURM_JWT", "")
+	if err := c.Ping(context.Background()); err == nil || transport.IsRetryable(err) {
+		t.Fatalf("expected a missing token to fail permanently, got %v", err)
+	}
+}
+
+func TestExpandArrayTasks(t *testing.T) {
+	got, rest, _, _ := expandArrayTasks("1-3,8,10-20:5%4")
+	want := []string{"1", "2", "3", "8", "10", "15", "20"}
+	if rest != 0 {
+		t.Fatalf("expected every task expanded, got %d left over", rest)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestExpandArrayTasksStopsAtCap(t *testing.T) {
+	got, rest, first, last := expandArrayTasks("0-4000000:2")
+	if len(got) != maxArrayTaskExpansion {
+		t.Fatalf("expected %d expanded tasks, got %d", maxArrayTaskExpansion, len(got))
+	}
+	if rest != 2000001-maxArrayTaskExpansion || first != 2*maxArrayTaskExpansion || last != 4000000 {
+		t.Fatalf("unexpected remainder: rest=%d first=%d last=%d", rest, first, last)
+	}
+}
+
+func TestRESTJobCountsTasksPastCap(t *testing.T) {
+	j := restJob{
+		JobID:           restNumber{Set: true, Number: 300},
+		ArrayJobID:      restNumber{Set: true, Number: 300},
+		ArrayTaskString: "0-99999",
+		JobState:        restStrings{"PENDING"},
+		CPUs:            restNumber{Set: true, Number: 2},
+	}
+	jobs := j.jobs()
+	if len(jobs) != maxArrayTaskExpansion+1 {
+		t.Fatalf("expected capped records plus one aggregate, got %d", len(jobs))
+	}
+	agg := jobs[len(jobs)-1]
+	if agg.Tasks != 100000-maxArrayTaskExpansion || agg.ID != "300_[65536-99999]" {
+		t.Fatalf("unexpected aggregate record: %+v", agg)
+	}
+	queue, _ := SummarizeJobs(jobs)
+	if queue.Pending != 100000 || queue.ResourceLoad.PendingCPU != 200000 {
+		t.Fatalf("expected every task counted, got pending=%d cpu=%d", queue.Pending, queue.ResourceLoad.PendingCPU)
+	}
+}
+
+func TestRESTJobMemoryScalesPerNodeRequest(t *testing.T) {
+	j := restJob{
+		CPUs:          restNumber{Set: true, Number: 32},
+		NodeCount:     restNumber{Set: true, Number: 2},
+		MemoryPerNode: restNumber{Set: true, Number: 64000},
+	}
+	if got := j.memMB(""); got != 128000 {
+		t.Fatalf("expected per-node memory times nodes, got %d", got)
+	}
+	if got := j.memMB("cpu=32,mem=100G,node=2"); got != 102400 {
+		t.Fatalf("expected the mem TRES to win, got %d", got)
+	}
+}
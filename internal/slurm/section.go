@@ -0,0 +1,168 @@
+package slurm
+
+import "time"
+
+// Section names one independently collected part of a snapshot.
+type Section string
+
+const (
+	SectionNodes      Section = "nodes"
+	SectionQueue      Section = "queue"
+	SectionPartitions Section = "partitions"
+	// SectionPendingLookups is the scontrol lookup of pending job requests
+	// that fills in GPUs and memory squeue leaves out.
+	SectionPendingLookups Section = "pending-lookups"
+)
+
+// SourceSections returns the sections a collector reads with a command or
+// request of its own, and so can refresh on separate schedules. Pending
+// lookups follow the queue.
+func SourceSections() []Section {
+	return []Section{SectionNodes, SectionQueue, SectionPartitions}
+}
+
+// SectionState says where a section's data came from.
+type SectionState string
+
+const (
+	SectionFresh SectionState = "fresh"
+	// SectionStale carries the data of the last collection that succeeded.
+	SectionStale SectionState = "stale"
+	// SectionFailed has no data: the section never succeeded.
+	SectionFailed SectionState = "failed"
+)
+
+// SectionStatus reports how one section of a snapshot was collected.
+type SectionStatus struct {
+	Section Section
+	State   SectionState
+	// UpdatedAt is when the section's data was collected: CollectedAt when
+	// fresh, the last success when stale and zero when failed.
+	UpdatedAt time.Time
+	// Error is the failure that made the section stale or failed.
+	Error string
+}
+
+// SectionStatus returns the status of one section. Snapshots without section
+// statuses, such as recordings made before they existed, are fresh
+// throughout.
+func (s Snapshot) SectionStatus(section Section) SectionStatus {
+	if st, ok := s.findSection(section); ok {
+		return st
+	}
+	return SectionStatus{Section: section, State: SectionFresh, UpdatedAt: s.CollectedAt}
+}
+
+func (s Snapshot) findSection(section Section) (SectionStatus, bool) {
+	for _, st := range s.Sections {
+		if st.Section == section {
+			return st, true
+		}
+	}
+	return SectionStatus{}, false
+}
+
+// Partial reports whether any section is stale or failed.
+func (s Snapshot) Partial() bool {
+	for _, st := range s.Sections {
+		if st.State != SectionFresh {
+			return true
+		}
+	}
+	return false
+}
+
+// sectionResult is the outcome of collecting one section; err is nil on
+// success. A skipped section was not due this round and keeps its data and
+// status from the last snapshot.
+type sectionResult struct {
+	section Section
+	err     error
+	skipped bool
+}
+
+// sectionCarry remembers the last published snapshot so a collection where
+// some sources fail can still publish one, with the failed sections carried
+// over and marked stale.
+type sectionCarry struct {
+	last Snapshot
+}
+
+// due returns the sources to collect when sections are requested: all of them
+// when sections is nil or nothing has been published yet, since skipped
+// sources are carried from the last snapshot.
+func (c *sectionCarry) due(sections []Section) map[Section]bool {
+	if sections == nil || c.last.CollectedAt.IsZero() {
+		sections = SourceSections()
+	}
+	out := make(map[Section]bool, len(sections))
+	for _, s := range sections {
+		out[s] = true
+	}
+	return out
+}
+
+// merge sets snap.Sections from results and fills failed and skipped
+// sections from the last snapshot. It returns an error, and publishes
+// nothing, only when no source has usable data: none was collected fresh and
+// none that was not due carries earlier data. The error is the first failed
+// section's. A round that reads only the queue therefore leaves it stale when
+// it fails, as a full collection would, instead of failing the snapshot.
+func (c *sectionCarry) merge(snap *Snapshot, results []sectionResult) error {
+	snap.Sections = make([]SectionStatus, 0, len(results))
+	var firstErr error
+	usable := false
+	for _, r := range results {
+		if r.skipped {
+			if prev, ok := c.last.findSection(r.section); ok {
+				snap.Sections = append(snap.Sections, prev)
+				carrySection(snap, c.last, r.section)
+				usable = usable || (isSourceSection(r.section) && prev.State != SectionFailed)
+			}
+			continue
+		}
+		st := SectionStatus{Section: r.section, State: SectionFresh, UpdatedAt: snap.CollectedAt}
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			st.Error = r.err.Error()
+			prev := c.last.SectionStatus(r.section)
+			if c.last.CollectedAt.IsZero() || prev.State == SectionFailed {
+				st.State, st.UpdatedAt = SectionFailed, time.Time{}
+			} else {
+				st.State, st.UpdatedAt = SectionStale, prev.UpdatedAt
+				carrySection(snap, c.last, r.section)
+			}
+		}
+		usable = usable || (isSourceSection(r.section) && st.State == SectionFresh)
+		snap.Sections = append(snap.Sections, st)
+	}
+	if !usable && firstErr != nil {
+		return firstErr
+	}
+	c.last = *snap
+	return nil
+}
+
+func isSourceSection(section Section) bool {
+	for _, s := range SourceSections() {
+		if s == section {
+			return true
+		}
+	}
+	return false
+}
+
+// carrySection copies one section's data from an earlier snapshot. The
+// partition slice is copied because applyNodeUsage rewrites it in place.
+func carrySection(snap *Snapshot, from Snapshot, section Section) {
+	switch section {
+	case SectionNodes:
+		snap.Nodes = from.Nodes
+	case SectionQueue:
+		snap.Jobs, snap.Queue, snap.Users = from.Jobs, from.Queue, from.Users
+	case SectionPartitions:
+		snap.Partitions = append([]Partition(nil), from.Partitions...)
+	}
+}
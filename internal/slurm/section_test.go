@@ -0,0 +1,49 @@
+package slurm
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSectionCarryMarksFailedThenStale(t *testing.T) {
+	var carry sectionCarry
+	t0 := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	boom := errors.New("boom")
+
+	first := Snapshot{Nodes: []Node{{Name: "n1"}}, CollectedAt: t0}
+	if err := carry.merge(&first, []sectionResult{{section: SectionNodes}, {section: SectionPartitions, err: boom}}); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if st := first.SectionStatus(SectionPartitions); st.State != SectionFailed || !st.UpdatedAt.IsZero() || st.Error != "boom" {
+		t.Fatalf("expected partitions failed without data, got %+v", st)
+	}
+	if !first.Partial() || first.SectionStatus(SectionNodes).State != SectionFresh {
+		t.Fatalf("expected a partial snapshot with fresh nodes, got %+v", first.Sections)
+	}
+
+	second := Snapshot{Partitions: []Partition{{Name: "gpu"}}, CollectedAt: t0.Add(time.Minute)}
+	if err := carry.merge(&second, []sectionResult{{section: SectionNodes, err: boom}, {section: SectionPartitions}}); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if st := second.SectionStatus(SectionNodes); st.State != SectionStale || !st.UpdatedAt.Equal(t0) {
+		t.Fatalf("expected nodes stale since the first collection, got %+v", st)
+	}
+	if len(second.Nodes) != 1 || second.Nodes[0].Name != "n1" {
+		t.Fatalf("expected nodes carried from the first collection, got %+v", second.Nodes)
+	}
+
+	third := Snapshot{CollectedAt: t0.Add(2 * time.Minute)}
+	err := carry.merge(&third, []sectionResult{{section: SectionNodes, err: boom}, {section: SectionPartitions, err: errors.New("second")}})
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected the first section's error when every section fails, got %v", err)
+	}
+}
+
+func TestSnapshotWithoutSectionsIsFresh(t *testing.T) {
+	at := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	s := Snapshot{CollectedAt: at}
+	if st := s.SectionStatus(SectionQueue); st.State != SectionFresh || !st.UpdatedAt.Equal(at) || s.Partial() {
+		t.Fatalf("expected an old snapshot to read as fresh, got %+v", st)
+	}
+}
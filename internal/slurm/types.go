@@ -3,9 +3,13 @@ package slurm
 import "time"
 
 type Node struct {
-	Name      string
-	State     string
+	Name  string
+	State string
+	// Partition is the Partitions value as Slurm prints it, comma-joined.
 	Partition string
+	// Partitions is Partition split into names in Slurm's order. The first is
+	// the node's primary partition.
+	Partitions []string
 
 	CPUAlloc int
 	CPUTotal int
@@ -21,6 +25,36 @@ type Node struct {
 	GPUTotal int
 	GPUUtil  float64
 	HasGPU   bool
+	// GPUTypes breaks GPUAlloc and GPUTotal down by model, with an untyped
+	// entry for GPUs Slurm does not name a model for. Nil without GPUs.
+	GPUTypes []GPUTypeUsage
+
+	Features       string
+	ActiveFeatures string
+	Gres           string
+	GresUsed       string
+	Reason         string
+	BootTime       time.Time
+	Weight         int
+	Owner          string
+
+	// Fields holds every key=value pair scontrol reported for the node, in
+	// output order, for detail views.
+	Fields []KeyValue
+}
+
+// PrimaryPartition returns the first partition the node belongs to, or ""
+// when it is in none.
+func (n Node) PrimaryPartition() string {
+	if len(n.Partitions) == 0 {
+		return ""
+	}
+	return n.Partitions[0]
+}
+
+type KeyValue struct {
+	Key   string
+	Value string
 }
 
 type QueueSummary struct {
@@ -38,6 +72,8 @@ type QueueSummary struct {
 	ByJobName    []NameCount
 	PendingCause []NameCount
 	ResourceLoad ResourceTotals
+	// ByGPUType is running and pending GPU demand per model, largest first.
+	ByGPUType []GPUTypeLoad
 }
 
 type UserSummary struct {
@@ -57,13 +93,83 @@ type UserSummary struct {
 	PendingCPU   int
 	PendingMemMB int
 	PendingGPU   int
+
+	ByGPUType []GPUTypeLoad
+}
+
+// Job is one squeue row. With squeue -r every array task is its own Job, so
+// ID carries the task suffix (for example 1234_7) while ArrayJobID names the
+// array root.
+type Job struct {
+	ID          string
+	ArrayJobID  string
+	ArrayTaskID string
+	State       string
+	User        string
+	Partition   string
+	Name        string
+
+	CPUs  int
+	MemMB int
+	GPUs  int
+	// GPUTypes holds the named models among GPUs; the rest are untyped. See
+	// GPUsByType.
+	GPUTypes []GPUCount
+
+	Reason   string
+	NodeList string
+
+	SubmitTime time.Time
+	StartTime  time.Time
+	// TimeLimit is zero when the limit is UNLIMITED or was not reported.
+	TimeLimit time.Duration
+
+	// Tasks is set when the record stands for several array tasks, as for a
+	// pending task range too large to expand into one record per task. See
+	// Count.
+	Tasks int
+}
+
+// Count returns how many jobs the record stands for: Tasks when set,
+// otherwise one.
+func (j Job) Count() int {
+	if j.Tasks > 1 {
+		return j.Tasks
+	}
+	return 1
+}
+
+// PartitionNames splits Partition, which lists every partition a pending job
+// was submitted to (for example gpu,gpu-long), into names in Slurm's order
+// without repeats.
+func (j Job) PartitionNames() []string {
+	names := uniqueStrings(splitNonEmpty(j.Partition, ","))
+	if len(names) == 0 {
+		return []string{j.Partition}
+	}
+	return names
+}
+
+// RootID returns the array root for array tasks and the job ID otherwise.
+func (j Job) RootID() string {
+	if j.ArrayJobID != "" && j.ArrayJobID != "N/A" {
+		return j.ArrayJobID
+	}
+	return rootJobID(j.ID)
 }
 
 type Snapshot struct {
 	Nodes       []Node
+	Partitions  []Partition
+	Jobs        []Job
 	Queue       QueueSummary
 	Users       []UserSummary
 	CollectedAt time.Time
+	// SlurmVersion is the cluster's release, such as 23.02.7, when known.
+	SlurmVersion string
+	// Sections reports, per data source, whether its data is fresh, carried
+	// over from an earlier collection or missing. Empty means all fresh.
+	Sections []SectionStatus
 }
 
 type StateCount struct {
@@ -76,6 +182,10 @@ type PartitionCount struct {
 	Running   int
 	Pending   int
 	Other     int
+	// Shared counts the jobs above that were submitted to several partitions
+	// and so also count toward another one. Partition counts do not add up to
+	// the queue total when it is non-zero.
+	Shared int
 }
 
 type NameCount struct {
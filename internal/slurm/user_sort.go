@@ -1,6 +1,10 @@
 package slurm
 
-import "sort"
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
 
 // SortUsersForDisplay keeps the biggest current holders near the top while
 // still using pending demand as a tie-breaker.
@@ -36,3 +40,102 @@ func SortUsersForDisplay(users []UserSummary) {
 		return users[i].User < users[j].User
 	})
 }
+
+type UserSortKey string
+
+const (
+	// UserSortDefault is the SortUsersForDisplay cascade.
+	UserSortDefault    UserSortKey = "default"
+	UserSortName       UserSortKey = "user"
+	UserSortHeldGPU    UserSortKey = "held-gpu"
+	UserSortHeldCPU    UserSortKey = "held-cpu"
+	UserSortPendingGPU UserSortKey = "pending-gpu"
+	UserSortPendingCPU UserSortKey = "pending-cpu"
+	UserSortPendingMem UserSortKey = "pending-mem"
+	UserSortRunning    UserSortKey = "running"
+	UserSortPending    UserSortKey = "pending"
+)
+
+// UserSortKeys lists user sort keys in the order the TUI cycles through them.
+var UserSortKeys = []UserSortKey{
+	UserSortDefault,
+	UserSortHeldGPU,
+	UserSortHeldCPU,
+	UserSortPendingGPU,
+	UserSortPendingCPU,
+	UserSortPendingMem,
+	UserSortRunning,
+	UserSortPending,
+	UserSortName,
+}
+
+// UserSort is a user ordering. For UserSortDefault, Desc keeps the display
+// cascade as is and ascending reverses it.
+type UserSort struct {
+	Key  UserSortKey
+	Desc bool
+}
+
+var DefaultUserSort = UserSort{Key: UserSortDefault, Desc: true}
+
+func (k UserSortKey) DefaultDesc() bool {
+	return k != UserSortName
+}
+
+func ParseUserSortKey(v string) (UserSortKey, error) {
+	key := UserSortKey(strings.ToLower(strings.TrimSpace(v)))
+	for _, k := range UserSortKeys {
+		if k == key {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("unknown user sort key %q", v)
+}
+
+func (s UserSort) String() string {
+	return sortLabel(string(s.Key), s.Desc)
+}
+
+// SortUsers orders users in place. Ties on the chosen key fall back to the
+// display cascade so equal rows keep a familiar order.
+func SortUsers(users []UserSummary, s UserSort) {
+	SortUsersForDisplay(users)
+	if s.Key == UserSortDefault {
+		if !s.Desc {
+			for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+				users[i], users[j] = users[j], users[i]
+			}
+		}
+		return
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		c := compareUsers(users[i], users[j], s.Key)
+		if s.Desc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+func compareUsers(a, b UserSummary, key UserSortKey) int {
+	switch key {
+	case UserSortName:
+		return strings.Compare(a.User, b.User)
+	case UserSortHeldGPU:
+		return compareInts(a.RunningGPU, b.RunningGPU)
+	case UserSortHeldCPU:
+		return compareInts(a.RunningCPU, b.RunningCPU)
+	case UserSortPendingGPU:
+		return compareInts(a.PendingGPU, b.PendingGPU)
+	case UserSortPendingCPU:
+		return compareInts(a.PendingCPU, b.PendingCPU)
+	case UserSortPendingMem:
+		return compareInts(a.PendingMemMB, b.PendingMemMB)
+	case UserSortRunning:
+		return compareInts(a.Running, b.Running)
+	case UserSortPending:
+		return compareInts(a.Pending, b.Pending)
+	default:
+		return 0
+	}
+}
@@ -21,3 +21,31 @@ func TestSortUsersForDisplay(t *testing.T) {
 		t.Fatalf("expected bob ahead of pure-pending user by held cpu, got %s", users[2].User)
 	}
 }
+
+func TestSortUsersByPendingGPU(t *testing.T) {
+	users := []UserSummary{
+		{User: "alice", RunningGPU: 11, PendingGPU: 2},
+		{User: "bob", PendingGPU: 0},
+		{User: "carol", RunningGPU: 8, PendingGPU: 6},
+	}
+	SortUsers(users, UserSort{Key: UserSortPendingGPU, Desc: true})
+	if users[0].User != "carol" || users[1].User != "alice" || users[2].User != "bob" {
+		t.Fatalf("expected carol, alice, bob by pending gpu, got %s, %s, %s", users[0].User, users[1].User, users[2].User)
+	}
+}
+
+func TestSortUsersDefaultAscendingReversesDisplayOrder(t *testing.T) {
+	users := []UserSummary{
+		{User: "bob", RunningGPU: 1},
+		{User: "alice", RunningGPU: 4},
+		{User: "carol"},
+	}
+	SortUsers(users, DefaultUserSort)
+	if users[0].User != "alice" || users[2].User != "carol" {
+		t.Fatalf("expected display order, got %s, %s, %s", users[0].User, users[1].User, users[2].User)
+	}
+	SortUsers(users, UserSort{Key: UserSortDefault})
+	if users[0].User != "carol" || users[2].User != "alice" {
+		t.Fatalf("expected reversed display order, got %s, %s, %s", users[0].User, users[1].User, users[2].User)
+	}
+}
@@ -0,0 +1,198 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// FixtureScheme prefixes a target that replays a transport fixture instead of
+// running commands, as in fixture://capture.jsonl.
+const FixtureScheme = "fixture://"
+
+// Exchange is one recorded Run call. Fixtures are plain JSON lines so they
+// can be read and redacted before being attached to a bug report.
+type Exchange struct {
+	Command   string    `json:"command"`
+	Stdout    string    `json:"stdout"`
+	Stderr    string    `json:"stderr,omitempty"`
+	ExitCode  int       `json:"exit_code"`
+	Error     string    `json:"error,omitempty"`
+	Timeout   bool      `json:"timeout,omitempty"`
+	LatencyMS int64     `json:"latency_ms"`
+	At        time.Time `json:"at"`
+}
+
+// RecordingTransport passes every command to the wrapped transport and
+// appends the exchange to a fixture file.
+type RecordingTransport struct {
+	inner Transport
+
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+	err  error
+}
+
+// NewRecordingTransport wraps inner and appends exchanges to path.
+func NewRecordingTransport(inner Transport, path string) (*RecordingTransport, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open transport recording: %w", err)
+	}
+	return &RecordingTransport{inner: inner, file: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (t *RecordingTransport) Describe() string {
+	return t.inner.Describe()
+}
+
+func (t *RecordingTransport) Run(ctx context.Context, command string) (RunResult, error) {
+	start := time.Now()
+	res, err := t.inner.Run(ctx, command)
+	ex := Exchange{
+		Command:   command,
+		Stdout:    res.Stdout,
+		Stderr:    res.Stderr,
+		ExitCode:  res.ExitCode,
+		LatencyMS: time.Since(start).Milliseconds(),
+		At:        start,
+	}
+	if err != nil {
+		ex.Error = err.Error()
+		var runErr *RunError
+		if errors.As(err, &runErr) {
+			ex.Stderr = runErr.Stderr
+			ex.ExitCode = runErr.ExitCode
+			ex.Timeout = runErr.Timeout
+			if runErr.Err != nil {
+				ex.Error = runErr.Err.Error()
+			}
+		}
+	}
+
+	t.mu.Lock()
+	if t.err == nil {
+		if encErr := t.enc.Encode(ex); encErr != nil {
+			t.err = fmt.Errorf("write transport recording: %w", encErr)
+		}
+	}
+	t.mu.Unlock()
+	return res, err
+}
+
+// Close closes the fixture file and reports the first write error, if any.
+func (t *RecordingTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	closeErr := t.file.Close()
+	if t.err != nil {
+		return t.err
+	}
+	if closeErr != nil {
+		return fmt.Errorf("close transport recording: %w", closeErr)
+	}
+	return nil
+}
+
+// ReplayTransport serves recorded exchanges back. Each command replays its
+// recordings in order and then keeps repeating the last one, so a monitor
+// loop can poll a short capture indefinitely.
+type ReplayTransport struct {
+	path string
+	// SimulateLatency waits for each exchange's recorded latency, bounded by
+	// the context, before returning it.
+	SimulateLatency bool
+
+	mu       sync.Mutex
+	byCmd    map[string][]Exchange
+	position map[string]int
+}
+
+// LoadFixture reads a fixture written by RecordingTransport.
+func LoadFixture(path string) (*ReplayTransport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open transport fixture: %w", err)
+	}
+	defer f.Close()
+
+	t := &ReplayTransport{path: path, byCmd: make(map[string][]Exchange), position: make(map[string]int)}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 1<<30)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var ex Exchange
+		if err := json.Unmarshal(scanner.Bytes(), &ex); err != nil {
+			return nil, fmt.Errorf("parse transport fixture %s line %d: %w", path, line, err)
+		}
+		t.byCmd[ex.Command] = append(t.byCmd[ex.Command], ex)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read transport fixture: %w", err)
+	}
+	if len(t.byCmd) == 0 {
+		return nil, fmt.Errorf("transport fixture %s has no exchanges", path)
+	}
+	return t, nil
+}
+
+func (t *ReplayTransport) Describe() string {
+	return FixtureScheme + t.path
+}
+
+func (t *ReplayTransport) Run(ctx context.Context, command string) (RunResult, error) {
+	t.mu.Lock()
+	exchanges := t.byCmd[command]
+	if len(exchanges) == 0 {
+		t.mu.Unlock()
+		// Exit code 127 mirrors a missing command and is not retryable, so
+		// a fixture that lacks a command fails fast instead of looping.
+		return RunResult{}, &RunError{
+			Command:  command,
+			Target:   t.Describe(),
+			Stderr:   "transport fixture has no recording for this command",
+			ExitCode: 127,
+		}
+	}
+	i := t.position[command]
+	ex := exchanges[min(i, len(exchanges)-1)]
+	t.position[command] = i + 1
+	t.mu.Unlock()
+
+	if t.SimulateLatency && ex.LatencyMS > 0 {
+		timer := time.NewTimer(time.Duration(ex.LatencyMS) * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return RunResult{}, &RunError{Command: command, Target: t.Describe(), Timeout: true, Err: ctx.Err()}
+		case <-timer.C:
+		}
+	}
+
+	res := RunResult{Stdout: ex.Stdout, Stderr: ex.Stderr, ExitCode: ex.ExitCode}
+	if ex.Error == "" && ex.ExitCode == 0 && !ex.Timeout {
+		return res, nil
+	}
+	runErr := &RunError{
+		Command:  command,
+		Target:   t.Describe(),
+		Stdout:   ex.Stdout,
+		Stderr:   ex.Stderr,
+		ExitCode: ex.ExitCode,
+		Timeout:  ex.Timeout,
+	}
+	if ex.Error != "" {
+		runErr.Err = errors.New(ex.Error)
+	}
+	return res, runErr
+}
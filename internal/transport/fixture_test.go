@@ -0,0 +1,99 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+type scriptedTransport struct {
+	results map[string]RunResult
+	errs    map[string]error
+}
+
+func (s scriptedTransport) Run(_ context.Context, command string) (RunResult, error) {
+	return s.results[command], s.errs[command]
+}
+
+func (s scriptedTransport) Describe() string {
+	return "ssh:cluster"
+}
+
+func TestRecordingTransportRoundTripsThroughReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	inner := scriptedTransport{
+		results: map[string]RunResult{
+			"squeue": {Stdout: "1|RUNNING|alice\n"},
+			"sinfo":  {Stdout: "partial", Stderr: "Connection timed out"},
+		},
+		errs: map[string]error{
+			"sinfo": &RunError{Command: "sinfo", Target: "ssh:cluster", Stderr: "Connection timed out", ExitCode: 255, Err: errors.New("exit status 255")},
+		},
+	}
+	rec, err := NewRecordingTransport(inner, path)
+	if err != nil {
+		t.Fatalf("create recorder: %v", err)
+	}
+	if rec.Describe() != "ssh:cluster" {
+		t.Fatalf("expected recorder to keep the inner description, got %q", rec.Describe())
+	}
+	ctx := context.Background()
+	for _, cmd := range []string{"squeue", "sinfo"} {
+		_, _ = rec.Run(ctx, cmd)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	replay, err := LoadFixture(path)
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	if replay.Describe() != "fixture://"+path {
+		t.Fatalf("unexpected description %q", replay.Describe())
+	}
+
+	res, err := replay.Run(ctx, "squeue")
+	if err != nil || res.Stdout != "1|RUNNING|alice\n" {
+		t.Fatalf("expected recorded stdout, got %q err %v", res.Stdout, err)
+	}
+	_, err = replay.Run(ctx, "sinfo")
+	var runErr *RunError
+	if !errors.As(err, &runErr) || runErr.ExitCode != 255 || runErr.Stderr != "Connection timed out" {
+		t.Fatalf("expected recorded failure to be rebuilt, got %v", err)
+	}
+	if !IsRetryable(err) {
+		t.Fatalf("expected replayed transient failure to stay retryable")
+	}
+
+	_, err = replay.Run(ctx, "scontrol ping")
+	if !errors.As(err, &runErr) || runErr.ExitCode != 127 || IsRetryable(err) {
+		t.Fatalf("expected unknown command to fail permanently, got %v", err)
+	}
+}
+
+func TestReplayTransportRepeatsLastExchange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	outputs := []string{"first", "second"}
+	rec, err := NewRecordingTransport(scriptedTransport{}, path)
+	if err != nil {
+		t.Fatalf("create recorder: %v", err)
+	}
+	for _, out := range outputs {
+		rec.inner = scriptedTransport{results: map[string]RunResult{"poll": {Stdout: out}}}
+		_, _ = rec.Run(context.Background(), "poll")
+	}
+	_ = rec.Close()
+
+	replay, err := LoadFixture(path)
+	if err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	for _, want := range []string{"first", "second", "second"} {
+		res, err := replay.Run(context.Background(), "poll")
+		if err != nil || res.Stdout != want {
+			t.Fatalf("expected %q, got %q err %v", want, res.Stdout, err)
+		}
+	}
+}
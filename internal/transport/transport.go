@@ -50,10 +50,20 @@ func (e *RunError) Unwrap() error {
 	return e.Err
 }
 
+// retryable is implemented by errors that classify themselves, such as
+// those from the slurmrestd collector, which does not run commands.
+type retryable interface {
+	Retryable() bool
+}
+
 func IsRetryable(err error) bool {
 	if err == nil {
 		return false
 	}
+	var r retryable
+	if errors.As(err, &r) {
+		return r.Retryable()
+	}
 	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.EOF) {
 		return true
 	}
@@ -0,0 +1,269 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"slurm_monitor/internal/slurm"
+	"slurm_monitor/internal/uifmt"
+)
+
+type detailKind int
+
+const (
+	detailNode detailKind = iota
+	detailJob
+)
+
+// JobDetailSource looks up the full scontrol record for a job. The collector
+// implements it and caches results per job ID.
+type JobDetailSource interface {
+	JobDetail(ctx context.Context, id string) (slurm.JobDetail, error)
+}
+
+// jobDetailMsg delivers the result of an asynchronous job lookup.
+type jobDetailMsg struct {
+	id     string
+	detail slurm.JobDetail
+	err    error
+}
+
+// jobDetailState is the lookup for the open job pane; loading is set until
+// the matching jobDetailMsg arrives.
+type jobDetailState struct {
+	id      string
+	loading bool
+	detail  slurm.JobDetail
+	err     string
+}
+
+// detailTarget names the record a drill-down pane shows. The record is looked
+// up by ID on every render so the pane follows new snapshots.
+type detailTarget struct {
+	kind detailKind
+	id   string
+}
+
+// handleDetailKey opens a drill-down pane for the selected row on enter and
+// closes an open pane on esc or backspace. It reports whether the key was
+// consumed, plus any command needed to load the pane.
+func (m *Model) handleDetailKey(key string) (bool, tea.Cmd) {
+	if m.detail != nil {
+		switch key {
+		case "esc", "backspace", "enter":
+			m.detail = nil
+			m.jobDetail = nil
+			if m.scroll != nil {
+				m.scroll.offset[panelDetail] = 0
+			}
+			return true, nil
+		}
+		return false, nil
+	}
+	if key != "enter" || m.snapshot == nil {
+		return false, nil
+	}
+	if row, ok := m.selectedRow(panelNodes); ok {
+		nodes := m.sortedNodes()
+		if row >= len(nodes) {
+			return false, nil
+		}
+		m.detail = &detailTarget{kind: detailNode, id: nodes[row].Name}
+		return true, nil
+	}
+	if row, ok := m.selectedRow(panelJobs); ok {
+		if row >= len(m.snapshot.Jobs) {
+			return false, nil
+		}
+		id := m.snapshot.Jobs[row].ID
+		m.detail = &detailTarget{kind: detailJob, id: id}
+		if m.jobDetails == nil {
+			m.jobDetail = nil
+			return true, nil
+		}
+		m.jobDetail = &jobDetailState{id: id, loading: true}
+		return true, fetchJobDetail(m.jobDetails, id)
+	}
+	return false, nil
+}
+
+func fetchJobDetail(source JobDetailSource, id string) tea.Cmd {
+	return func() tea.Msg {
+		detail, err := source.JobDetail(context.Background(), id)
+		return jobDetailMsg{id: id, detail: detail, err: err}
+	}
+}
+
+// applyJobDetail stores a lookup result if its pane is still open; results
+// for a pane the user already left are dropped.
+func (m *Model) applyJobDetail(msg jobDetailMsg) {
+	if m.jobDetail == nil || m.jobDetail.id != msg.id {
+		return
+	}
+	m.jobDetail.loading = false
+	m.jobDetail.detail = msg.detail
+	m.jobDetail.err = ""
+	if msg.err != nil {
+		m.jobDetail.err = msg.err.Error()
+	}
+}
+
+// detailSnapshot is the unfiltered snapshot, so a drill-down shows every job
+// on a node even while a filter hides some of them.
+func (m Model) detailSnapshot() *slurm.Snapshot {
+	if m.unfiltered != nil {
+		return m.unfiltered
+	}
+	return m.snapshot
+}
+
+func (m Model) renderDetail(contentHeight, contentWidth int) string {
+	var title string
+	var body []string
+	switch m.detail.kind {
+	case detailNode:
+		title, body = m.nodeDetailLines(m.detail.id)
+	case detailJob:
+		title, body = m.jobDetailLines(m.detail.id)
+	}
+
+	visible := max(0, contentHeight-1)
+	offset := m.scrollOffset(panelDetail, min(visible, len(body)), len(body))
+	end := min(len(body), offset+visible)
+	lines := []string{m.sectionTitle(windowTitle(title, offset, end-offset, len(body)) + " · esc back")}
+	lines = append(lines, body[offset:end]...)
+	return strings.Join(fitLinesToWidth(clipLines(lines, contentHeight), contentWidth), "\n")
+}
+
+func (m Model) nodeDetailLines(name string) (string, []string) {
+	title := "node detail: " + name
+	snap := m.detailSnapshot()
+	var node *slurm.Node
+	if snap != nil {
+		for i := range snap.Nodes {
+			if snap.Nodes[i].Name == name {
+				node = &snap.Nodes[i]
+				break
+			}
+		}
+	}
+	if node == nil {
+		return title, []string{m.styles.warn.Render("node is not in the latest snapshot")}
+	}
+
+	n := *node
+	lines := []string{
+		m.detailLine("state", n.State),
+		m.detailLine("partitions", n.Partition),
+		m.detailLine("cpu", uifmt.Ratio(n.CPUAlloc, n.CPUTotal)+"  load "+uifmt.Percent(n.CPUUtil, n.HasCPU)),
+		m.detailLine("mem", uifmt.MemPair(n.MemAllocMB, n.MemTotalMB)+"  used "+uifmt.Percent(n.MemUtil, n.HasMem)),
+		m.detailLine("gpu", uifmt.Ratio(n.GPUAlloc, n.GPUTotal)),
+		m.detailLine("features", n.Features),
+		m.detailLine("active features", n.ActiveFeatures),
+		m.detailLine("gres", n.Gres),
+		m.detailLine("gres used", n.GresUsed),
+		m.detailLine("reason", n.Reason),
+		m.detailLine("boot time", formatDetailTime(n.BootTime)),
+		m.detailLine("weight", fmt.Sprint(n.Weight)),
+		m.detailLine("owner", n.Owner),
+	}
+
+	jobs := slurm.JobsOnNode(snap.Jobs, n.Name)
+	lines = append(lines, "", m.sectionTitle(fmt.Sprintf("jobs on node (%d)", len(jobs))))
+	if len(jobs) == 0 {
+		lines = append(lines, m.styles.dim.Render("none"))
+	} else {
+		lines = append(lines, jobHeaderLine())
+		for _, j := range jobs {
+			lines = append(lines, jobRowLine(j))
+		}
+	}
+
+	if len(n.Fields) > 0 {
+		lines = append(lines, "", m.sectionTitle("all fields"))
+		for _, kv := range n.Fields {
+			lines = append(lines, fmt.Sprintf("%-22s %s", kv.Key, kv.Value))
+		}
+	}
+	return title, lines
+}
+
+// jobDetailKeys are the scontrol fields shown ahead of the full field list.
+var jobDetailKeys = []struct{ label, key string }{
+	{"req tres", "ReqTRES"},
+	{"alloc tres", "AllocTRES"},
+	{"node list", "NodeList"},
+	{"submit time", "SubmitTime"},
+	{"eligible time", "EligibleTime"},
+	{"start time", "StartTime"},
+	{"end time", "EndTime"},
+	{"run time", "RunTime"},
+	{"time limit", "TimeLimit"},
+	{"dependency", "Dependency"},
+	{"work dir", "WorkDir"},
+	{"command", "Command"},
+	{"reason", "Reason"},
+	{"stdout", "StdOut"},
+	{"stderr", "StdErr"},
+}
+
+func (m Model) jobDetailLines(id string) (string, []string) {
+	title := "job detail: " + id
+	var lines []string
+	if snap := m.detailSnapshot(); snap != nil {
+		for _, j := range snap.Jobs {
+			if j.ID != id {
+				continue
+			}
+			lines = append(lines,
+				m.detailLine("state", j.State),
+				m.detailLine("user", j.User),
+				m.detailLine("partition", j.Partition),
+				m.detailLine("name", j.Name),
+				m.detailLine("cpu / mem / gpu", fmt.Sprintf("%d / %s / %d", j.CPUs, uifmt.MemMB(j.MemMB), j.GPUs)),
+				"",
+			)
+			break
+		}
+	}
+
+	lines = append(lines, m.sectionTitle("scontrol show job"))
+	switch {
+	case m.jobDetail == nil || m.jobDetail.id != id:
+		return title, append(lines, m.styles.dim.Render("job lookup is not available for this source"))
+	case m.jobDetail.loading:
+		return title, append(lines, m.styles.dim.Render("loading..."))
+	case m.jobDetail.err != "":
+		return title, append(lines, m.styles.bad.Render(m.jobDetail.err))
+	}
+
+	d := m.jobDetail.detail
+	for _, f := range jobDetailKeys {
+		lines = append(lines, m.detailLine(f.label, d.Field(f.key)))
+	}
+	if len(d.Fields) > 0 {
+		lines = append(lines, "", m.sectionTitle("all fields"))
+		for _, kv := range d.Fields {
+			lines = append(lines, fmt.Sprintf("%-22s %s", kv.Key, kv.Value))
+		}
+	}
+	return title, lines
+}
+
+func (m Model) detailLine(label, value string) string {
+	if value == "" {
+		value = "-"
+	}
+	return m.styles.label.Render(fmt.Sprintf("%-16s", label)) + "  " + value
+}
+
+func formatDetailTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
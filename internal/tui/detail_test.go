@@ -0,0 +1,147 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"slurm_monitor/internal/slurm"
+)
+
+func pressEnter(m Model) Model {
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	return next.(Model)
+}
+
+func TestEnterOpensNodeDetailWithJobsAndFields(t *testing.T) {
+	m := seededModel()
+	m.view = viewNodes
+	m.height = 50
+	nodes := m.sortedNodes()
+	target := nodes[1]
+	m.snapshot.Nodes[indexOfNode(m.snapshot.Nodes, target.Name)].Features = "a100,ib"
+	m.snapshot.Nodes[indexOfNode(m.snapshot.Nodes, target.Name)].Fields = []slurm.KeyValue{
+		{Key: "NodeName", Value: target.Name},
+		{Key: "OS", Value: "Linux 5.14"},
+	}
+	m.snapshot.Jobs = []slurm.Job{
+		{ID: "77", User: "alice", State: "RUNNING", NodeList: target.Name},
+		{ID: "78", User: "bob", State: "RUNNING", NodeList: "elsewhere01"},
+	}
+
+	_ = m.View()
+	m = pressKey(t, m, "j")
+	m = pressEnter(m)
+	if m.detail == nil || m.detail.id != target.Name {
+		t.Fatalf("expected detail for %s, got %+v", target.Name, m.detail)
+	}
+
+	out := m.View()
+	for _, want := range []string{"node detail: " + target.Name, "a100,ib", "jobs on node (1)", "77", "all fields", "Linux 5.14"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in node detail, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "elsewhere01") {
+		t.Fatalf("did not expect jobs from other nodes, got:\n%s", out)
+	}
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = next.(Model)
+	if m.detail != nil {
+		t.Fatalf("expected esc to close the detail pane")
+	}
+}
+
+func TestNodeDetailOnlyOpensFromFocusedNodeTable(t *testing.T) {
+	m := seededModel()
+	m.view = viewQueue
+	m = pressEnter(m)
+	if m.detail != nil {
+		t.Fatalf("expected enter to do nothing without a node table")
+	}
+	m.view = viewOverview
+	m = pressKey(t, m, "f")
+	m = pressEnter(m)
+	if m.detail != nil {
+		t.Fatalf("expected enter to do nothing while users are focused")
+	}
+}
+
+func indexOfNode(nodes []slurm.Node, name string) int {
+	for i, n := range nodes {
+		if n.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+type stubJobDetails struct {
+	calls []string
+}
+
+func (s *stubJobDetails) JobDetail(_ context.Context, id string) (slurm.JobDetail, error) {
+	s.calls = append(s.calls, id)
+	return slurm.JobDetail{ID: id, Fields: []slurm.KeyValue{
+		{Key: "JobId", Value: id},
+		{Key: "AllocTRES", Value: "cpu=4,gres/gpu=1"},
+		{Key: "WorkDir", Value: "/scratch/alice"},
+		{Key: "Dependency", Value: "(null)"},
+	}}, nil
+}
+
+func TestEnterOpensJobDetailAndFetchesFields(t *testing.T) {
+	m := seededModel()
+	m.view = viewJobs
+	m.height = 50
+	m.snapshot.Jobs = sampleJobs()
+	stub := &stubJobDetails{}
+	m.jobDetails = stub
+
+	_ = m.View()
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(Model)
+	want := m.snapshot.Jobs[0].ID
+	if m.detail == nil || m.detail.kind != detailJob || m.detail.id != want {
+		t.Fatalf("expected job detail for %s, got %+v", want, m.detail)
+	}
+	if cmd == nil {
+		t.Fatalf("expected a lookup command")
+	}
+	if out := m.View(); !strings.Contains(out, "loading...") {
+		t.Fatalf("expected loading state before the lookup returns, got:\n%s", out)
+	}
+
+	next, _ = m.Update(cmd())
+	m = next.(Model)
+	if len(stub.calls) != 1 || stub.calls[0] != want {
+		t.Fatalf("expected one lookup for %s, got %v", want, stub.calls)
+	}
+	out := m.View()
+	for _, want := range []string{"job detail: " + want, "alloc tres", "cpu=4,gres/gpu=1", "/scratch/alice", "all fields"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in job detail, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestJobDetailDropsResultForClosedPane(t *testing.T) {
+	m := seededModel()
+	m.view = viewJobs
+	m.snapshot.Jobs = sampleJobs()
+	m.jobDetails = &stubJobDetails{}
+
+	_ = m.View()
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(Model)
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = next.(Model)
+	next, _ = m.Update(cmd())
+	m = next.(Model)
+	if m.detail != nil || m.jobDetail != nil {
+		t.Fatalf("expected late lookup result to be ignored")
+	}
+}
@@ -0,0 +1,205 @@
+package tui
+
+import (
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"slurm_monitor/internal/slurm"
+)
+
+const regexFilterPrefix = "re:"
+
+// snapshotFilter matches rows by case-insensitive substring, or by regular
+// expression when the text starts with "re:".
+type snapshotFilter struct {
+	text string
+	re   *regexp.Regexp
+	err  error
+}
+
+func newSnapshotFilter(text string) snapshotFilter {
+	f := snapshotFilter{text: text}
+	if pattern, ok := strings.CutPrefix(strings.TrimSpace(text), regexFilterPrefix); ok {
+		f.re, f.err = regexp.Compile("(?i)" + pattern)
+	}
+	return f
+}
+
+func (f snapshotFilter) active() bool {
+	return strings.TrimSpace(f.text) != "" && f.err == nil
+}
+
+func (f snapshotFilter) match(fields ...string) bool {
+	if !f.active() {
+		return true
+	}
+	needle := strings.ToLower(strings.TrimSpace(f.text))
+	for _, field := range fields {
+		if f.re != nil {
+			if f.re.MatchString(field) {
+				return true
+			}
+			continue
+		}
+		if strings.Contains(strings.ToLower(field), needle) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterSnapshot keeps nodes matching on name, partition or state, jobs
+// matching on ID, user, partition, state, name or reason, and partitions
+// matching on name or holding a kept job. Queue and user
+// summaries are rebuilt from the kept jobs so every count and TOTAL row covers
+// only the filtered set. Snapshots without per-job records fall back to
+// filtering the summary rows by their own names.
+func filterSnapshot(snap slurm.Snapshot, f snapshotFilter) slurm.Snapshot {
+	if !f.active() {
+		return snap
+	}
+	out := snap
+	out.Nodes = nil
+	for _, n := range snap.Nodes {
+		if f.match(n.Name, n.Partition, n.State) {
+			out.Nodes = append(out.Nodes, n)
+		}
+	}
+
+	if len(snap.Jobs) > 0 {
+		out.Jobs = nil
+		for _, j := range snap.Jobs {
+			if f.match(j.ID, j.User, j.Partition, j.State, j.Name, j.Reason) {
+				out.Jobs = append(out.Jobs, j)
+			}
+		}
+		out.Queue, out.Users = slurm.SummarizeJobs(out.Jobs)
+		out.Partitions = filterPartitions(snap.Partitions, out.Queue.ByPartition, f)
+		return out
+	}
+
+	out.Users = nil
+	for _, u := range snap.Users {
+		if f.match(u.User) {
+			out.Users = append(out.Users, u)
+		}
+	}
+	out.Partitions = filterPartitions(snap.Partitions, nil, f)
+	q := snap.Queue
+	q.ByState = nil
+	for _, s := range snap.Queue.ByState {
+		if f.match(s.State) {
+			q.ByState = append(q.ByState, s)
+		}
+	}
+	q.ByPartition = nil
+	for _, p := range snap.Queue.ByPartition {
+		if f.match(p.Partition) {
+			q.ByPartition = append(q.ByPartition, p)
+		}
+	}
+	q.ByJobName = filterNameCounts(snap.Queue.ByJobName, f)
+	q.PendingCause = filterNameCounts(snap.Queue.PendingCause, f)
+	out.Queue = q
+	return out
+}
+
+// filterPartitions keeps partitions matching by name, plus those holding kept
+// jobs so their rows keep capacity next to the filtered counts.
+func filterPartitions(in []slurm.Partition, kept []slurm.PartitionCount, f snapshotFilter) []slurm.Partition {
+	withJobs := make(map[string]bool, len(kept))
+	for _, c := range kept {
+		withJobs[c.Partition] = true
+	}
+	var out []slurm.Partition
+	for _, p := range in {
+		if f.match(p.Name) || withJobs[p.Name] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func filterNameCounts(in []slurm.NameCount, f snapshotFilter) []slurm.NameCount {
+	var out []slurm.NameCount
+	for _, c := range in {
+		if f.match(c.Name) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// handleFilterKey edits the filter while the prompt is open and opens or
+// clears it otherwise. It reports whether the key was consumed.
+func (m *Model) handleFilterKey(msg tea.KeyMsg) bool {
+	if !m.filtering {
+		switch msg.String() {
+		case "/":
+			m.filtering = true
+			return true
+		case "esc":
+			if m.filter.text == "" {
+				return false
+			}
+			m.setFilter("")
+			return true
+		}
+		return false
+	}
+
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.filtering = false
+	case tea.KeyEsc:
+		m.filtering = false
+		m.setFilter("")
+	case tea.KeyBackspace:
+		runes := []rune(m.filter.text)
+		if len(runes) > 0 {
+			m.setFilter(string(runes[:len(runes)-1]))
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.setFilter(m.filter.text + string(msg.Runes))
+	}
+	// The prompt swallows every other key so typing never switches views.
+	return true
+}
+
+// setFilter updates the filter text, re-applies it and resets scroll offsets
+// since row positions no longer line up.
+func (m *Model) setFilter(text string) {
+	m.filter = newSnapshotFilter(text)
+	if m.scroll != nil {
+		m.scroll.offset = [panelCount]int{}
+	}
+	m.applyFilter()
+}
+
+func (m *Model) applyFilter() {
+	if m.unfiltered == nil {
+		m.unfiltered = m.snapshot
+	}
+	if m.unfiltered == nil {
+		return
+	}
+	filtered := filterSnapshot(*m.unfiltered, m.filter)
+	m.snapshot = &filtered
+}
+
+func (m Model) filterChip() string {
+	if strings.TrimSpace(m.filter.text) == "" {
+		return ""
+	}
+	label := "filter: " + m.filter.text
+	if m.filter.err != nil {
+		return m.styles.chipBad.Render(label + " (invalid regex)")
+	}
+	return m.styles.chipWarn.Render(label)
+}
+
+func (m Model) filterPrompt() string {
+	return "/" + m.filter.text + "▏  enter apply · esc clear · re: prefix for regex"
+}
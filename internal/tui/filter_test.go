@@ -0,0 +1,147 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"slurm_monitor/internal/monitor"
+	"slurm_monitor/internal/slurm"
+)
+
+func typeFilter(t *testing.T, m Model, text string) Model {
+	t.Helper()
+	m = pressKey(t, m, "/")
+	for _, r := range text {
+		m = pressKey(t, m, string(r))
+	}
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	return next.(Model)
+}
+
+func filterFixture() slurm.Snapshot {
+	snap := sampleSnapshot()
+	snap.Jobs = []slurm.Job{
+		{ID: "1", State: "RUNNING", User: "alice", Partition: "gpu", Name: "train", CPUs: 16, GPUs: 4},
+		{ID: "2", State: "PENDING", User: "alice", Partition: "gpu", Name: "train", CPUs: 8, GPUs: 2, Reason: "Resources"},
+		{ID: "3", State: "PENDING", User: "bob", Partition: "cpu", Name: "prep", CPUs: 4, Reason: "Priority"},
+	}
+	return snap
+}
+
+func TestFilterRecomputesQueueAndUsersFromJobs(t *testing.T) {
+	filtered := filterSnapshot(filterFixture(), newSnapshotFilter("alice"))
+	if len(filtered.Jobs) != 2 {
+		t.Fatalf("expected alice's two jobs, got %d", len(filtered.Jobs))
+	}
+	if len(filtered.Users) != 1 || filtered.Users[0].User != "alice" {
+		t.Fatalf("expected only alice in users, got %+v", filtered.Users)
+	}
+	q := filtered.Queue
+	if q.Running != 1 || q.Pending != 1 || q.ResourceLoad.PendingGPU != 2 {
+		t.Fatalf("expected queue totals over the filtered jobs, got %+v", q)
+	}
+	if len(q.PendingCause) != 1 || q.PendingCause[0].Name != "Resources" {
+		t.Fatalf("expected only alice's pending cause, got %+v", q.PendingCause)
+	}
+}
+
+func TestFilterMatchesNodesByPartitionAndRegex(t *testing.T) {
+	snap := filterFixture()
+	byPartition := filterSnapshot(snap, newSnapshotFilter("GPU"))
+	for _, n := range byPartition.Nodes {
+		if !strings.Contains(strings.ToLower(n.Name+n.Partition+n.State), "gpu") {
+			t.Fatalf("unexpected node %s kept by substring filter", n.Name)
+		}
+	}
+	if len(byPartition.Nodes) == 0 {
+		t.Fatalf("expected gpu nodes to match")
+	}
+
+	byRegex := filterSnapshot(snap, newSnapshotFilter("re:^(bob|carol)$"))
+	if len(byRegex.Jobs) != 1 || byRegex.Jobs[0].User != "bob" {
+		t.Fatalf("expected regex to keep bob's job only, got %+v", byRegex.Jobs)
+	}
+
+	invalid := newSnapshotFilter("re:(")
+	if invalid.err == nil || invalid.active() {
+		t.Fatalf("expected invalid regex to be reported and ignored")
+	}
+	if got := filterSnapshot(snap, invalid); len(got.Jobs) != len(snap.Jobs) {
+		t.Fatalf("expected invalid filter to keep every job")
+	}
+}
+
+func TestFilterKeepsMatchingPartitionsWithJobs(t *testing.T) {
+	snap := filterFixture()
+	snap.Partitions = []slurm.Partition{{Name: "gpu"}, {Name: "cpu"}, {Name: "debug"}}
+
+	byName := filterSnapshot(snap, newSnapshotFilter("debug"))
+	if len(byName.Jobs) != 0 {
+		t.Fatalf("expected no jobs to match, got %+v", byName.Jobs)
+	}
+	if len(byName.Partitions) != 1 || byName.Partitions[0].Name != "debug" {
+		t.Fatalf("expected only the debug partition, got %+v", byName.Partitions)
+	}
+
+	byUser := filterSnapshot(snap, newSnapshotFilter("bob"))
+	if len(byUser.Partitions) != 1 || byUser.Partitions[0].Name != "cpu" {
+		t.Fatalf("expected the partition holding bob's job, got %+v", byUser.Partitions)
+	}
+}
+
+func TestFilterFallsBackToSummaryRowsWithoutJobs(t *testing.T) {
+	filtered := filterSnapshot(sampleSnapshot(), newSnapshotFilter("prior"))
+	if len(filtered.Queue.PendingCause) != 1 || filtered.Queue.PendingCause[0].Name != "Priority" {
+		t.Fatalf("expected pending causes filtered by name, got %+v", filtered.Queue.PendingCause)
+	}
+	if len(filtered.Users) != 0 {
+		t.Fatalf("expected no users to match, got %+v", filtered.Users)
+	}
+}
+
+func TestFilterPromptAppliesAndClears(t *testing.T) {
+	m := seededModel()
+	snap := filterFixture()
+	next, _ := m.Update(updateMsg{update: monitor.Update{Snapshot: &snap, State: monitor.StateConnected}})
+	m = next.(Model)
+
+	m = pressKey(t, m, "/")
+	m = pressKey(t, m, "2")
+	if m.view != viewOverview {
+		t.Fatalf("expected typing in the prompt not to switch views")
+	}
+	if !strings.Contains(m.View(), "/2▏") {
+		t.Fatalf("expected prompt in footer while typing")
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	m = next.(Model)
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = next.(Model)
+
+	m = typeFilter(t, m, "bob")
+	if m.filtering {
+		t.Fatalf("expected enter to close the prompt")
+	}
+	if len(m.snapshot.Jobs) != 1 {
+		t.Fatalf("expected filtered snapshot, got %d jobs", len(m.snapshot.Jobs))
+	}
+	if !strings.Contains(m.renderHeader(m.now), "filter: bob") {
+		t.Fatalf("expected active filter in header")
+	}
+
+	// New snapshots keep the filter applied.
+	fresh := filterFixture()
+	next, _ = m.Update(updateMsg{update: monitor.Update{Snapshot: &fresh, State: monitor.StateConnected}})
+	m = next.(Model)
+	if len(m.snapshot.Jobs) != 1 {
+		t.Fatalf("expected filter to persist across updates, got %d jobs", len(m.snapshot.Jobs))
+	}
+
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = next.(Model)
+	if m.filter.text != "" || len(m.snapshot.Jobs) != 3 {
+		t.Fatalf("expected esc to clear the filter, got %q with %d jobs", m.filter.text, len(m.snapshot.Jobs))
+	}
+}
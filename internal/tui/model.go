@@ -9,6 +9,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/ansi"
 
+	"slurm_monitor/internal/history"
 	"slurm_monitor/internal/monitor"
 	"slurm_monitor/internal/slurm"
 	"slurm_monitor/internal/uifmt"
@@ -20,7 +21,18 @@ type Options struct {
 	NoColor     bool
 	Refresh     time.Duration
 	MaxDuration time.Duration
+	NodeSort    slurm.NodeSort
+	UserSort    slurm.UserSort
 	Updates     <-chan monitor.Update
+	// History is how far back utilization trends reach; 0 disables them.
+	History time.Duration
+	// SampleInterval is the shortest time between two updates, which sizes
+	// the trend history. It defaults to Refresh.
+	SampleInterval time.Duration
+	// JobDetails backs the job drill-down pane; nil disables lookups.
+	JobDetails JobDetailSource
+	// Playback is set when Updates comes from a recording.
+	Playback Playback
 }
 
 type Model struct {
@@ -41,8 +53,28 @@ type Model struct {
 	lastError   string
 	lastSuccess time.Time
 	nextRetry   time.Time
-	pulseIndex  int
-	snapshot    *slurm.Snapshot
+	// latency is how long the last successful collection took; refresh
+	// follows the loop's effective interval.
+	latency    time.Duration
+	pulseIndex int
+	snapshot   *slurm.Snapshot
+	view       viewID
+	focus      panelID
+	scroll     *scrollState
+	nodeSort   slurm.NodeSort
+	userSort   slurm.UserSort
+	filter     snapshotFilter
+	filtering  bool
+	detail     *detailTarget
+	jobDetails JobDetailSource
+	jobDetail  *jobDetailState
+	// history is fed from unfiltered snapshots so trends describe the
+	// whole cluster.
+	history  *history.Ring
+	playback Playback
+	// unfiltered is the latest collected snapshot; snapshot is the view of
+	// it after the active filter.
+	unfiltered *slurm.Snapshot
 
 	styles styles
 }
@@ -63,6 +95,7 @@ type styles struct {
 	chipBad    lipgloss.Style
 	errorLabel lipgloss.Style
 	accent     lipgloss.Style
+	selected   lipgloss.Style
 }
 
 type updateMsg struct {
@@ -83,6 +116,18 @@ const (
 )
 
 func NewModel(opts Options) Model {
+	nodeSort := opts.NodeSort
+	if nodeSort.Key == "" {
+		nodeSort = slurm.DefaultNodeSort
+	}
+	userSort := opts.UserSort
+	if userSort.Key == "" {
+		userSort = slurm.DefaultUserSort
+	}
+	sampleInterval := opts.SampleInterval
+	if sampleInterval <= 0 {
+		sampleInterval = opts.Refresh
+	}
 	return Model{
 		source:      opts.Source,
 		compact:     opts.Compact,
@@ -93,6 +138,13 @@ func NewModel(opts Options) Model {
 		started:     time.Now(),
 		now:         time.Now(),
 		state:       monitor.StateReconnecting,
+		focus:       panelNodes,
+		scroll:      &scrollState{},
+		nodeSort:    nodeSort,
+		userSort:    userSort,
+		jobDetails:  opts.JobDetails,
+		history:     history.NewRing(opts.History, sampleInterval),
+		playback:    opts.Playback,
 		styles:      defaultStyles(opts.NoColor),
 	}
 }
@@ -116,6 +168,7 @@ func defaultStyles(noColor bool) styles {
 			chipBad:    lipgloss.NewStyle().Bold(true),
 			errorLabel: lipgloss.NewStyle().Bold(true),
 			accent:     lipgloss.NewStyle().Bold(true),
+			selected:   lipgloss.NewStyle().Reverse(true),
 		}
 	}
 
@@ -135,6 +188,7 @@ func defaultStyles(noColor bool) styles {
 		chipBad:    lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("230")).Background(lipgloss.Color("160")).Padding(0, 1),
 		errorLabel: lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("203")),
 		accent:     lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("81")),
+		selected:   lipgloss.NewStyle().Reverse(true),
 	}
 }
 
@@ -165,6 +219,18 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "ctrl+c":
 			return m, tea.Quit
 		}
+		key := msg.String()
+		if !m.filtering {
+			if ok, cmd := m.handleDetailKey(key); ok {
+				return m, cmd
+			}
+		}
+		if m.handleFilterKey(msg) {
+			return m, nil
+		}
+		if !m.handleViewKey(key) && !m.handleSortKey(key) && !m.handlePlaybackKey(key) {
+			m.handleScrollKey(key)
+		}
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -173,12 +239,22 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.lastError = msg.update.LastError
 		m.lastSuccess = msg.update.LastSuccess
 		m.nextRetry = msg.update.NextRetry
+		if msg.update.Refresh > 0 {
+			m.refresh = msg.update.Refresh
+			m.latency = msg.update.Latency
+		}
 		if msg.update.Snapshot != nil {
 			snap := *msg.update.Snapshot
-			m.snapshot = &snap
+			anchors := m.rowAnchors()
+			m.unfiltered = &snap
+			m.history.Add(snap)
+			m.applyFilter()
+			m.reanchorRows(anchors)
 			m.lastError = ""
 		}
 		return m, waitForUpdate(m.updates)
+	case jobDetailMsg:
+		m.applyJobDetail(msg)
 	case tickMsg:
 		m.now = msg.now
 		if len(pulseFrames) > 0 {
@@ -207,7 +283,10 @@ func (m Model) View() string {
 	}
 
 	header := m.renderHeader(now)
-	footer := m.styles.dim.Render("Ctrl+C to exit")
+	footer := m.styles.dim.Render(m.footerHint())
+	if m.filtering {
+		footer = m.styles.accent.Render(m.filterPrompt())
+	}
 	headerLines := lineCount(header)
 	footerLines := lineCount(footer)
 	separatorLines := 1
@@ -224,12 +303,13 @@ func (m Model) View() string {
 		body = m.styles.panel.Width(max(20, m.width-6)).Render("waiting for first successful snapshot...")
 		body = clipToHeight(body, bodyHeight)
 	} else {
-		body = m.renderMain(bodyHeight)
+		body = m.renderBody(bodyHeight)
 	}
 
 	parts := []string{header}
 	if separatorLines > 0 {
-		parts = append(parts, "")
+		// The tab bar takes the separator row so views do not lose body height.
+		parts = append(parts, m.renderTabBar())
 	}
 	parts = append(parts, body)
 	top := lipgloss.JoinVertical(lipgloss.Left, parts...)
@@ -246,10 +326,23 @@ func (m Model) renderHeader(now time.Time) string {
 		ageText = "refresh: " + humanDuration(now.Sub(m.lastSuccess)) + " ago"
 	}
 
-	left := m.styles.title.Render(" SLURM MONITOR ") + "  " +
-		m.styles.label.Render("source: ") + m.styles.value.Render(m.source) + "  " +
-		m.styles.chip.Render("clock: "+now.Format("15:04:05")) + " " +
+	chips := m.styles.chip.Render("clock: "+now.Format("15:04:05")) + " " +
 		m.styles.chip.Render(ageText)
+	if m.latency > 0 {
+		chips += " " + m.styles.chip.Render("every "+humanDuration(m.refresh)+", took "+latencyText(m.latency))
+	}
+	if m.playback != nil {
+		chips = m.playbackChips()
+	}
+	left := m.styles.title.Render(" SLURM MONITOR ") + "  " +
+		m.styles.label.Render("source: ") + m.styles.value.Render(m.source) + "  "
+	if m.snapshot != nil && m.snapshot.SlurmVersion != "" {
+		left += m.styles.label.Render("slurm: ") + m.styles.value.Render(m.snapshot.SlurmVersion) + "  "
+	}
+	left += chips
+	if chip := m.filterChip(); chip != "" {
+		left += " " + chip
+	}
 	right := statusChip.Render(statusText)
 	line1 := joinWithPaddingKeepRight(left, right, m.width)
 	if m.lastError == "" {
@@ -324,7 +417,7 @@ func (m Model) renderQueuePanel(userLimit int, showDemand bool) string {
 	total := q.Running + q.Pending + q.Other
 
 	lines := []string{
-		m.sectionTitle("queue summary"),
+		m.sectionTitle("queue summary") + m.sectionLabel(slurm.SectionQueue) + m.lookupsLabel(),
 		m.queueStatusLine("running cpu jobs", q.RunningCPUJobs),
 		m.queueStatusLine("running gpu jobs", q.RunningGPUJobs),
 		m.queueStatusLine("pending cpu jobs", q.PendingCPUJobs),
@@ -350,11 +443,11 @@ func (m Model) renderQueuePanelWithBudget(contentHeight, maxHeight int, compactL
 	q := m.snapshot.Queue
 	total := q.Running + q.Pending + q.Other
 	lines := []string{
-		m.sectionTitle("queue summary"),
+		m.sectionTitle("queue summary") + m.sectionLabel(slurm.SectionQueue) + m.lookupsLabel(),
 		m.queueStatusLine("running cpu jobs", q.RunningCPUJobs),
 		m.queueStatusLine("running gpu jobs", q.RunningGPUJobs),
-		m.queueStatusLine("pending cpu jobs", q.PendingCPUJobs),
-		m.queueStatusLine("pending gpu jobs", q.PendingGPUJobs),
+		m.queueTrendLine("pending cpu jobs", q.PendingCPUJobs, pendingCPUJobs),
+		m.queueTrendLine("pending gpu jobs", q.PendingGPUJobs, pendingGPUJobs),
 		m.queueStatusLine("other", q.Other),
 		m.queueStatusLine("total", total),
 	}
@@ -391,8 +484,7 @@ func (m Model) renderUserLines(limit int, showDemand bool) []string {
 	if m.snapshot == nil {
 		return []string{"users", "(no data)"}
 	}
-	users := append([]slurm.UserSummary(nil), m.snapshot.Users...)
-	slurm.SortUsersForDisplay(users)
+	users := m.sortedUsers()
 
 	if limit <= 0 {
 		limit = 10
@@ -416,18 +508,18 @@ func (m Model) renderUserLines(limit int, showDemand bool) []string {
 		}
 	}
 
-	lines := []string{m.sectionTitle(title)}
+	lines := []string{m.sectionTitle(title) + m.sectionLabel(slurm.SectionQueue)}
 	if showDemand {
-		lines = append(lines, wideUserHeaderLine())
+		lines = append(lines, m.userTrendHeader(wideUserHeaderLine()))
 		for _, u := range users {
-			lines = append(lines, wideUserRowLine(u))
+			lines = append(lines, m.userTrendRow(wideUserRowLine(u), u.User))
 		}
 		return lines
 	}
 
-	lines = append(lines, compactUserHeaderLine())
+	lines = append(lines, m.userTrendHeader(compactUserHeaderLine()))
 	for _, u := range users {
-		lines = append(lines, compactUserRowLine(u))
+		lines = append(lines, m.userTrendRow(compactUserRowLine(u), u.User))
 	}
 	return fitLinesToWidth(lines, panelContentWidth(max(20, m.width-6)))
 }
@@ -436,8 +528,7 @@ func (m Model) renderUserLinesWithBudget(maxRows, rowBudget int, showDemand bool
 	if m.snapshot == nil || rowBudget <= 0 {
 		return nil
 	}
-	users := append([]slurm.UserSummary(nil), m.snapshot.Users...)
-	slurm.SortUsersForDisplay(users)
+	users := m.sortedUsers()
 
 	totalUsers := len(users)
 	if maxRows < 0 {
@@ -457,40 +548,33 @@ func (m Model) renderUserLinesWithBudget(maxRows, rowBudget int, showDemand bool
 	if visibleRows > totalUsers {
 		visibleRows = totalUsers
 	}
-	visibleUsers := users[:visibleRows]
-	hiddenUsers := totalUsers - len(visibleUsers)
+	offset := m.scrollOffset(panelUsers, visibleRows, totalUsers)
+	visibleUsers := users[offset : offset+visibleRows]
 
-	title := "user view"
-	if hiddenUsers > 0 {
-		if len(visibleUsers) == 0 {
-			title = fmt.Sprintf("user view (+%d hidden)", hiddenUsers)
-		} else {
-			title = fmt.Sprintf("user view (top %d/%d, +%d hidden)", len(visibleUsers), totalUsers, hiddenUsers)
-		}
-	}
-	lines := []string{m.sectionTitle(title)}
+	title := windowTitle("user view", offset, visibleRows, totalUsers) + sortSuffix(m.userSort.String())
+	lines := []string{m.sectionTitle(title) + m.sectionLabel(slurm.SectionQueue)}
 	if rowBudget == 1 {
 		return fitLinesToWidth(lines, contentWidth)
 	}
 	if rowBudget == 2 {
 		if len(visibleUsers) == 1 {
-			lines = append(lines, compactUserRowLine(visibleUsers[0]))
+			lines = append(lines, m.highlightRow(panelUsers, offset, m.userTrendRow(compactUserRowLine(visibleUsers[0]), visibleUsers[0].User)))
 		}
 		return fitLinesToWidth(lines, contentWidth)
 	}
 
 	if showDemand {
-		lines = append(lines, wideUserHeaderLine())
-		for _, u := range visibleUsers {
-			lines = append(lines, wideUserRowLine(u))
+		lines = append(lines, m.userTrendHeader(wideUserHeaderLine()))
+		for i, u := range visibleUsers {
+			lines = append(lines, m.highlightRow(panelUsers, offset+i, m.userTrendRow(wideUserRowLine(u), u.User)))
 		}
 		lines = clipLines(lines, rowBudget)
 		return fitLinesToWidth(lines, contentWidth)
 	}
 
-	lines = append(lines, compactUserHeaderLine())
-	for _, u := range visibleUsers {
-		lines = append(lines, compactUserRowLine(u))
+	lines = append(lines, m.userTrendHeader(compactUserHeaderLine()))
+	for i, u := range visibleUsers {
+		lines = append(lines, m.highlightRow(panelUsers, offset+i, m.userTrendRow(compactUserRowLine(u), u.User)))
 	}
 	lines = clipLines(lines, rowBudget)
 	return fitLinesToWidth(lines, contentWidth)
@@ -535,7 +619,7 @@ func (m Model) renderNodeTable(limit int) string {
 		wideRowFmt    = "%-12s %-14s %-14s %-10s %-6s %-13s %-6s %-10s %-6s"
 	)
 
-	nodes := m.snapshot.Nodes
+	nodes := m.sortedNodes()
 	totalNodes := len(nodes)
 	if len(nodes) > limit {
 		nodes = nodes[:limit]
@@ -549,7 +633,7 @@ func (m Model) renderNodeTable(limit int) string {
 		title = fmt.Sprintf("node summary (top %d/%d, +%d hidden)", len(nodes), totalNodes, hiddenNodes)
 	}
 	contentWidth := panelContentWidth(max(20, m.width-6))
-	lines := []string{m.sectionTitle(title)}
+	lines := []string{m.sectionTitle(title) + m.sectionLabel(slurm.SectionNodes)}
 	if alert, ok := nodeStateAlert(m.snapshot); ok {
 		lines = append(lines, m.styles.bad.Render(alert))
 	}
@@ -559,7 +643,7 @@ func (m Model) renderNodeTable(limit int) string {
 			lines = append(lines, fmt.Sprintf(
 				compactRowFmt,
 				truncateRunes(n.Name, 14),
-				truncateRunes(n.Partition, 9),
+				nodePartitionLabel(n, 9),
 				truncateRunes(n.State, 10),
 				uifmt.Ratio(n.CPUAlloc, n.CPUTotal),
 				uifmt.MemPair(n.MemAllocMB, n.MemTotalMB),
@@ -588,7 +672,7 @@ func (m Model) renderNodeTable(limit int) string {
 		lines = append(lines, fmt.Sprintf(
 			wideRowFmt,
 			truncateRunes(n.Name, 12),
-			truncateRunes(n.Partition, 14),
+			nodePartitionLabel(n, 14),
 			truncateRunes(n.State, 14),
 			uifmt.Ratio(n.CPUAlloc, n.CPUTotal),
 			uifmt.Percent(n.CPUUtil, n.HasCPU),
@@ -646,7 +730,7 @@ func (m Model) renderNodeTableWithBudget(contentHeight, maxHeight int, compactLa
 	if compactLayout && m.width < 132 {
 		compact = true
 	}
-	nodes := m.snapshot.Nodes
+	nodes := m.sortedNodes()
 	totalNodes := len(nodes)
 
 	alert, hasAlert := nodeStateAlert(m.snapshot)
@@ -654,21 +738,22 @@ func (m Model) renderNodeTableWithBudget(contentHeight, maxHeight int, compactLa
 	if hasAlert {
 		mandatoryLines++
 	}
+	showTrend := m.hasTrend()
+	if showTrend {
+		mandatoryLines++
+	}
 	remainingAfterMandatory := contentHeight - mandatoryLines
 	showHeader := remainingAfterMandatory > 0
 	visibleRows := 0
 	if showHeader {
 		visibleRows = min(totalNodes, remainingAfterMandatory-1)
 	}
-	hiddenNodes := totalNodes - visibleRows
-
-	title := "node summary"
-	if hiddenNodes > 0 {
-		title = fmt.Sprintf("node summary (top %d/%d, +%d hidden)", visibleRows, totalNodes, hiddenNodes)
-	}
+	offset := m.scrollOffset(panelNodes, visibleRows, totalNodes)
+	nodes = nodes[offset:]
+	title := windowTitle("node summary", offset, visibleRows, totalNodes) + sortSuffix(m.nodeSort.String())
 
 	t := m.snapshot.Totals()
-	lines := []string{m.sectionTitle(title)}
+	lines := []string{m.sectionTitle(title) + m.sectionLabel(slurm.SectionNodes)}
 	if hasAlert {
 		lines = append(lines, m.styles.bad.Render(alert))
 	}
@@ -679,15 +764,15 @@ func (m Model) renderNodeTableWithBudget(contentHeight, maxHeight int, compactLa
 		}
 		for i := 0; i < visibleRows; i++ {
 			n := nodes[i]
-			lines = append(lines, fmt.Sprintf(
+			lines = append(lines, m.highlightRow(panelNodes, offset+i, fmt.Sprintf(
 				compactRowFmt,
 				truncateRunes(n.Name, 14),
-				truncateRunes(n.Partition, 9),
+				nodePartitionLabel(n, 9),
 				truncateRunes(n.State, 10),
 				uifmt.Ratio(n.CPUAlloc, n.CPUTotal),
 				uifmt.MemPair(n.MemAllocMB, n.MemTotalMB),
 				uifmt.Ratio(n.GPUAlloc, n.GPUTotal),
-			))
+			)))
 		}
 		totalLine := fmt.Sprintf(
 			compactRowFmt,
@@ -699,6 +784,9 @@ func (m Model) renderNodeTableWithBudget(contentHeight, maxHeight int, compactLa
 			uifmt.Ratio(t.GPUAlloc, t.GPUTotal),
 		)
 		lines = append(lines, m.styles.accent.Render(totalLine))
+		if showTrend {
+			lines = append(lines, m.nodeTrendLine(true))
+		}
 		lines = clipLines(lines, contentHeight)
 		lines = fitLinesToWidth(lines, contentWidth)
 		return strings.Join(lines, "\n")
@@ -712,10 +800,10 @@ func (m Model) renderNodeTableWithBudget(contentHeight, maxHeight int, compactLa
 	}
 	for i := 0; i < visibleRows; i++ {
 		n := nodes[i]
-		lines = append(lines, fmt.Sprintf(
+		lines = append(lines, m.highlightRow(panelNodes, offset+i, fmt.Sprintf(
 			wideRowFmt,
 			truncateRunes(n.Name, 12),
-			truncateRunes(n.Partition, 14),
+			nodePartitionLabel(n, 14),
 			truncateRunes(n.State, 14),
 			uifmt.Ratio(n.CPUAlloc, n.CPUTotal),
 			uifmt.Percent(n.CPUUtil, n.HasCPU),
@@ -723,7 +811,7 @@ func (m Model) renderNodeTableWithBudget(contentHeight, maxHeight int, compactLa
 			uifmt.Percent(n.MemUtil, n.HasMem),
 			uifmt.Ratio(n.GPUAlloc, n.GPUTotal),
 			uifmt.Percent(n.GPUUtil, n.HasGPU),
-		))
+		)))
 	}
 
 	var cpuPct, memPct, gpuPct string
@@ -756,6 +844,9 @@ func (m Model) renderNodeTableWithBudget(contentHeight, maxHeight int, compactLa
 		gpuPct,
 	)
 	lines = append(lines, m.styles.accent.Render(totalLine))
+	if showTrend {
+		lines = append(lines, m.nodeTrendLine(false))
+	}
 	lines = clipLines(lines, contentHeight)
 	lines = fitLinesToWidth(lines, contentWidth)
 	return strings.Join(lines, "\n")
@@ -800,6 +891,10 @@ func (m Model) sectionTitle(label string) string {
 		icon = "◍"
 	case strings.HasPrefix(label, "user view"):
 		icon = "◒"
+	case strings.HasPrefix(label, "partition view"):
+		icon = "◫"
+	case strings.HasPrefix(label, "job view"):
+		icon = "◆"
 	}
 	return m.styles.tableHdr.Render(icon + " " + label)
 }
@@ -814,6 +909,49 @@ func stabilizedFrameWidth(width int) int {
 	return width - frameRightGutter
 }
 
+// sectionLabel marks a section whose data is not from the latest collection:
+// stale data shows how much older it is than the snapshot, and a section that
+// never succeeded shows as unavailable.
+func (m Model) sectionLabel(section slurm.Section) string {
+	if m.snapshot == nil {
+		return ""
+	}
+	st := m.snapshot.SectionStatus(section)
+	switch st.State {
+	case slurm.SectionStale:
+		return " " + m.styles.warn.Render("stale "+humanDuration(m.snapshot.CollectedAt.Sub(st.UpdatedAt)))
+	case slurm.SectionFailed:
+		return " " + m.styles.bad.Render("unavailable")
+	}
+	return ""
+}
+
+// lookupsLabel marks queue panels when the pending request lookups that fill
+// in pending GPU and memory demand are stale or failed, since the demand is
+// understated until they succeed again.
+func (m Model) lookupsLabel() string {
+	if m.snapshot == nil {
+		return ""
+	}
+	st := m.snapshot.SectionStatus(slurm.SectionPendingLookups)
+	switch st.State {
+	case slurm.SectionStale:
+		return " " + m.styles.warn.Render("pending requests stale "+humanDuration(m.snapshot.CollectedAt.Sub(st.UpdatedAt)))
+	case slurm.SectionFailed:
+		return " " + m.styles.bad.Render("pending requests unavailable")
+	}
+	return ""
+}
+
+// latencyText shows collection latency in milliseconds below a second,
+// where humanDuration would only say <1s.
+func latencyText(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
 func humanDuration(d time.Duration) string {
 	if d < 0 {
 		d = 0
@@ -831,6 +969,16 @@ func humanDuration(d time.Duration) string {
 	return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
 }
 
+// nodePartitionLabel shows a node's primary partition, with +N for the other
+// partitions it also belongs to, so the count survives truncation.
+func nodePartitionLabel(n slurm.Node, width int) string {
+	if len(n.Partitions) <= 1 {
+		return truncateRunes(n.Partition, width)
+	}
+	suffix := fmt.Sprintf("+%d", len(n.Partitions)-1)
+	return truncateRunes(n.PrimaryPartition(), width-len(suffix)) + suffix
+}
+
 func truncateRunes(s string, maxRunes int) string {
 	if maxRunes <= 0 {
 		return ""
@@ -118,6 +118,38 @@ func TestHeaderShowsLoadingBeforeFirstSnapshot(t *testing.T) {
 	}
 }
 
+func TestHeaderShowsSlurmVersionWhenKnown(t *testing.T) {
+	m := seededModel()
+	m.styles = defaultStyles(true)
+	if h := m.renderHeader(m.now); strings.Contains(h, "slurm:") {
+		t.Fatalf("expected no version chip before detection, got: %q", h)
+	}
+	m.snapshot.SlurmVersion = "23.02.7"
+	if h := m.renderHeader(m.now); !strings.Contains(h, "slurm: 23.02.7") {
+		t.Fatalf("expected the slurm version in the header, got: %q", h)
+	}
+}
+
+func TestHeaderShowsPollIntervalAndLatency(t *testing.T) {
+	m := seededModel()
+	m.styles = defaultStyles(true)
+	if h := m.renderHeader(m.now); strings.Contains(h, "took") {
+		t.Fatalf("expected no latency chip before a live update, got: %q", h)
+	}
+	snap := sampleSnapshot()
+	next, _ := m.Update(updateMsg{update: monitor.Update{
+		Snapshot:    &snap,
+		State:       monitor.StateConnected,
+		LastSuccess: snap.CollectedAt,
+		Refresh:     8 * time.Second,
+		Latency:     350 * time.Millisecond,
+	}})
+	m = next.(Model)
+	if h := m.renderHeader(m.now); !strings.Contains(h, "every 8s, took 350ms") {
+		t.Fatalf("expected the effective interval and latency in the header, got: %q", h)
+	}
+}
+
 func TestHeaderDoesNotIncludeNodeAlert(t *testing.T) {
 	m := seededModel()
 	m.styles = defaultStyles(true)
@@ -451,6 +483,23 @@ func TestWideNodeTableShowsUntruncatedDrainState(t *testing.T) {
 	}
 }
 
+func TestNodeTableShowsPrimaryPartitionWithExtraCount(t *testing.T) {
+	m := seededModel()
+	m.styles = defaultStyles(true)
+	m.snapshot.Nodes[0].Partition = "gpu-interactive,gpu-long,debug"
+	m.snapshot.Nodes[0].Partitions = []string{"gpu-interactive", "gpu-long", "debug"}
+
+	wide := m.renderNodeTableWithBudget(12, 24, false, 140)
+	if !strings.Contains(wide, "gpu-interac…+2") {
+		t.Fatalf("expected truncated primary partition with +2 marker, got: %q", wide)
+	}
+	m.width = 96
+	compact := m.renderNodeTableWithBudget(12, 24, true, 88)
+	if !strings.Contains(compact, "gpu-in…+2") {
+		t.Fatalf("expected compact column to keep the +2 marker, got: %q", compact)
+	}
+}
+
 func TestUserViewShowsHiddenCountWhenCapped(t *testing.T) {
 	m := seededModel()
 	m.styles = defaultStyles(true)
@@ -0,0 +1,66 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"slurm_monitor/internal/replay"
+)
+
+// Playback controls a recording being replayed through the Updates channel.
+// replay.Player implements it.
+type Playback interface {
+	TogglePause()
+	Step(frames int)
+	Seek(d time.Duration)
+	ScaleSpeed(factor float64)
+	Status() replay.Status
+}
+
+// handlePlaybackKey maps replay controls. It reports whether the key was
+// consumed and never consumes keys outside replay.
+func (m Model) handlePlaybackKey(key string) bool {
+	if m.playback == nil {
+		return false
+	}
+	switch key {
+	case " ", "space":
+		m.playback.TogglePause()
+	case ",":
+		m.playback.Step(-1)
+	case ".":
+		m.playback.Step(1)
+	case "[":
+		m.playback.Seek(-time.Minute)
+	case "]":
+		m.playback.Seek(time.Minute)
+	case "{":
+		m.playback.Seek(-10 * time.Minute)
+	case "}":
+		m.playback.Seek(10 * time.Minute)
+	case "-":
+		m.playback.ScaleSpeed(0.5)
+	case "+", "=":
+		m.playback.ScaleSpeed(2)
+	default:
+		return false
+	}
+	return true
+}
+
+// playbackChips replace the clock and refresh-age chips during replay: the
+// clock is the recorded collection time and the age chip shows the position.
+func (m Model) playbackChips() string {
+	st := m.playback.Status()
+	state := "▶ " + formatSpeed(st.Speed)
+	if st.Paused {
+		state = "❚❚ paused"
+	}
+	return m.styles.chip.Render("at: "+st.At.Format("2006-01-02 15:04:05")) + " " +
+		m.styles.chipWarn.Render(fmt.Sprintf("replay %s · %d/%d", state, st.Index+1, st.Total))
+}
+
+func formatSpeed(speed float64) string {
+	return strconv.FormatFloat(speed, 'g', 4, 64) + "x"
+}
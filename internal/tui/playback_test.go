@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"slurm_monitor/internal/replay"
+)
+
+type stubPlayback struct {
+	status replay.Status
+	seeks  []time.Duration
+	steps  []int
+}
+
+func (s *stubPlayback) TogglePause()          { s.status.Paused = !s.status.Paused }
+func (s *stubPlayback) Step(n int)            { s.steps = append(s.steps, n) }
+func (s *stubPlayback) Seek(d time.Duration)  { s.seeks = append(s.seeks, d) }
+func (s *stubPlayback) ScaleSpeed(f float64)  { s.status.Speed *= f }
+func (s *stubPlayback) Status() replay.Status { return s.status }
+
+func TestPlaybackKeysDriveReplayAndHeader(t *testing.T) {
+	m := seededModel()
+	stub := &stubPlayback{status: replay.Status{
+		At:    time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC),
+		Index: 4, Total: 10, Speed: 1,
+	}}
+	m.playback = stub
+
+	out := m.View()
+	if !strings.Contains(out, "at: 2026-03-01 03:00:00") || !strings.Contains(out, "replay ▶ 1x · 5/10") {
+		t.Fatalf("expected recorded time and position in the header, got:\n%s", out)
+	}
+
+	for _, key := range []string{"+", "+", "]", "{", ","} {
+		m = pressKey(t, m, key)
+	}
+	m = pressKey(t, m, " ")
+	if stub.status.Speed != 4 || !stub.status.Paused {
+		t.Fatalf("expected speed 4x and paused, got %+v", stub.status)
+	}
+	if len(stub.seeks) != 2 || stub.seeks[0] != time.Minute || stub.seeks[1] != -10*time.Minute {
+		t.Fatalf("unexpected seeks %v", stub.seeks)
+	}
+	if len(stub.steps) != 1 || stub.steps[0] != -1 {
+		t.Fatalf("unexpected steps %v", stub.steps)
+	}
+	if out := m.View(); !strings.Contains(out, "replay ❚❚ paused") {
+		t.Fatalf("expected paused chip, got:\n%s", out)
+	}
+}
+
+func TestPlaybackKeysIgnoredWhenLive(t *testing.T) {
+	m := seededModel()
+	if m.handlePlaybackKey(" ") {
+		t.Fatalf("expected playback keys to be ignored without a recording")
+	}
+	if strings.Contains(m.footerHint(), "speed") {
+		t.Fatalf("expected no replay hint when live, got %q", m.footerHint())
+	}
+}
@@ -0,0 +1,283 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+)
+
+type panelID int
+
+const (
+	panelNone panelID = iota - 1
+	panelNodes
+	panelUsers
+	panelPartitions
+	panelJobs
+	panelDetail
+	panelCount
+)
+
+var panelNames = [panelCount]string{
+	panelNodes:      "nodes",
+	panelUsers:      "users",
+	panelPartitions: "partitions",
+	panelJobs:       "jobs",
+	panelDetail:     "detail",
+}
+
+func (p panelID) String() string {
+	if p < 0 || p >= panelCount {
+		return "none"
+	}
+	return panelNames[p]
+}
+
+// scrollState holds the per-panel cursor and scroll offset plus the row
+// window each panel had on its last render. Model keeps it behind a pointer so
+// View can record window sizes that Update later needs for paging and
+// clamping.
+type scrollState struct {
+	offset [panelCount]int
+	cursor [panelCount]int
+	window [panelCount]scrollWindow
+}
+
+type scrollWindow struct {
+	visible int
+	total   int
+}
+
+// focusedPanel is the panel that scroll keys move. Full-screen views have a
+// single table; the overview toggles between nodes and users with f.
+func (m Model) focusedPanel() panelID {
+	if m.detail != nil {
+		return panelDetail
+	}
+	switch m.view {
+	case viewOverview:
+		return m.focus
+	case viewNodes:
+		return panelNodes
+	case viewUsers:
+		return panelUsers
+	case viewPartitions:
+		return panelPartitions
+	case viewJobs:
+		return panelJobs
+	default:
+		return panelNone
+	}
+}
+
+// handleScrollKey applies scroll and focus keys. Table panels move a row
+// cursor and scroll to keep it visible; the detail pane scrolls directly. It
+// reports whether the key was consumed.
+func (m *Model) handleScrollKey(key string) bool {
+	if key == "f" {
+		if m.view != viewOverview || m.detail != nil {
+			return false
+		}
+		if m.focus == panelNodes {
+			m.focus = panelUsers
+		} else {
+			m.focus = panelNodes
+		}
+		return true
+	}
+
+	p := m.focusedPanel()
+	if p == panelNone || m.scroll == nil {
+		return false
+	}
+	win := m.scroll.window[p]
+	page := max(1, win.visible)
+	if p == panelDetail {
+		offset := m.scroll.offset[p]
+		switch key {
+		case "j", "down":
+			offset++
+		case "k", "up":
+			offset--
+		case "pgdown":
+			offset += page
+		case "pgup":
+			offset -= page
+		case "g", "home":
+			offset = 0
+		case "G", "end":
+			offset = math.MaxInt
+		default:
+			return false
+		}
+		m.scroll.offset[p] = clampOffset(offset, win.visible, win.total)
+		return true
+	}
+
+	cursor, offset := m.scroll.cursor[p], m.scroll.offset[p]
+	switch key {
+	case "j", "down":
+		cursor++
+	case "k", "up":
+		cursor--
+	case "pgdown":
+		cursor += page
+		offset += page
+	case "pgup":
+		cursor -= page
+		offset -= page
+	case "g", "home":
+		cursor = 0
+	case "G", "end":
+		cursor = math.MaxInt
+	default:
+		return false
+	}
+	m.scroll.cursor[p] = clampCursor(cursor, win.total)
+	m.scroll.offset[p] = followCursor(offset, m.scroll.cursor[p], win.visible, win.total)
+	return true
+}
+
+// scrollOffset returns the offset for a panel showing visible of total rows,
+// clamped so the panel's cursor stays in view, and remembers the window for
+// the next key press.
+func (m Model) scrollOffset(p panelID, visible, total int) int {
+	if p == panelNone || m.scroll == nil {
+		return 0
+	}
+	m.scroll.window[p] = scrollWindow{visible: visible, total: total}
+	var offset int
+	if p == panelDetail {
+		offset = clampOffset(m.scroll.offset[p], visible, total)
+	} else {
+		m.scroll.cursor[p] = clampCursor(m.scroll.cursor[p], total)
+		offset = followCursor(m.scroll.offset[p], m.scroll.cursor[p], visible, total)
+	}
+	m.scroll.offset[p] = offset
+	return offset
+}
+
+// selectedRow returns the cursor row of p when p has keyboard focus.
+func (m Model) selectedRow(p panelID) (int, bool) {
+	if m.scroll == nil || p == panelNone || p == panelDetail || m.focusedPanel() != p {
+		return 0, false
+	}
+	return m.scroll.cursor[p], true
+}
+
+func clampCursor(cursor, total int) int {
+	if cursor >= total {
+		cursor = total - 1
+	}
+	if cursor < 0 {
+		return 0
+	}
+	return cursor
+}
+
+// followCursor moves offset the least needed to keep cursor inside a window
+// of visible rows.
+func followCursor(offset, cursor, visible, total int) int {
+	if visible > 0 {
+		if cursor < offset {
+			offset = cursor
+		}
+		if cursor >= offset+visible {
+			offset = cursor - visible + 1
+		}
+	}
+	return clampOffset(offset, visible, total)
+}
+
+func clampOffset(offset, visible, total int) int {
+	limit := max(0, total-visible)
+	if offset > limit {
+		return limit
+	}
+	if offset < 0 {
+		return 0
+	}
+	return offset
+}
+
+// windowTitle appends row-window metadata to a section title. An unscrolled
+// window keeps the "top X/Y" wording; a scrolled one shows its row range.
+func windowTitle(title string, offset, visible, total int) string {
+	hidden := total - visible
+	switch {
+	case hidden <= 0:
+		return title
+	case visible == 0:
+		return fmt.Sprintf("%s (+%d hidden)", title, hidden)
+	case offset == 0:
+		return fmt.Sprintf("%s (top %d/%d, +%d hidden)", title, visible, total, hidden)
+	default:
+		return fmt.Sprintf("%s (rows %d-%d/%d, +%d hidden)", title, offset+1, offset+visible, total, hidden)
+	}
+}
+
+// rowAnchor is the row a table cursor was on, by identity, so it can be found
+// again in the next snapshot.
+type rowAnchor struct {
+	id  string
+	row int
+}
+
+// rowAnchors records the node, user, partition or job under each table
+// cursor before a new snapshot replaces the rows.
+func (m Model) rowAnchors() map[panelID]rowAnchor {
+	if m.scroll == nil || m.snapshot == nil {
+		return nil
+	}
+	anchors := make(map[panelID]rowAnchor)
+	for p := panelNodes; p <= panelJobs; p++ {
+		ids := m.rowIDs(p)
+		if c := m.scroll.cursor[p]; c < len(ids) {
+			anchors[p] = rowAnchor{id: ids[c], row: c}
+		}
+	}
+	return anchors
+}
+
+// reanchorRows moves each cursor to its anchored row in the new snapshot and
+// shifts the window with it, so rows arriving or leaving above the cursor
+// do not move the selection. A row that is gone leaves the cursor where it
+// was.
+func (m *Model) reanchorRows(anchors map[panelID]rowAnchor) {
+	if m.scroll == nil || m.snapshot == nil {
+		return
+	}
+	for p, a := range anchors {
+		for i, id := range m.rowIDs(p) {
+			if id == a.id {
+				m.scroll.cursor[p] = i
+				m.scroll.offset[p] = max(0, m.scroll.offset[p]+i-a.row)
+				break
+			}
+		}
+	}
+}
+
+// rowIDs lists the identity of each row of a table panel in display order.
+func (m Model) rowIDs(p panelID) []string {
+	var ids []string
+	switch p {
+	case panelNodes:
+		for _, n := range m.sortedNodes() {
+			ids = append(ids, n.Name)
+		}
+	case panelUsers:
+		for _, u := range m.sortedUsers() {
+			ids = append(ids, u.User)
+		}
+	case panelPartitions:
+		for _, r := range m.partitionRows() {
+			ids = append(ids, r.name)
+		}
+	case panelJobs:
+		ids = make([]string, len(m.snapshot.Jobs))
+		for i, j := range m.snapshot.Jobs {
+			ids[i] = j.ID
+		}
+	}
+	return ids
+}
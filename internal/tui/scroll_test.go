@@ -0,0 +1,161 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"slurm_monitor/internal/monitor"
+	"slurm_monitor/internal/slurm"
+)
+
+func manyNodesModel(count int) Model {
+	m := seededModel()
+	nodes := make([]slurm.Node, 0, count)
+	for i := 1; i <= count; i++ {
+		nodes = append(nodes, slurm.Node{
+			Name:      fmt.Sprintf("node%03d", i),
+			State:     "IDLE",
+			Partition: "cpu",
+			CPUTotal:  64,
+		})
+	}
+	m.snapshot.Nodes = nodes
+	m.view = viewNodes
+	m.width = 100
+	m.height = 20
+	return m
+}
+
+func TestNodeViewScrollsWithKeys(t *testing.T) {
+	m := manyNodesModel(50)
+	out := m.View()
+	if !strings.Contains(out, "node summary (top ") || !strings.Contains(out, "node001") {
+		t.Fatalf("expected unscrolled window first, got:\n%s", out)
+	}
+
+	m = pressKey(t, m, "j")
+	if m.scroll.cursor[panelNodes] != 1 || m.scroll.offset[panelNodes] != 0 {
+		t.Fatalf("expected j to move the cursor inside the window first")
+	}
+	for i := 1; i < m.scroll.window[panelNodes].visible; i++ {
+		m = pressKey(t, m, "j")
+	}
+	out = m.View()
+	if !strings.Contains(out, "rows 2-") || strings.Contains(out, "node001") {
+		t.Fatalf("expected moving past the window to scroll one row, got:\n%s", out)
+	}
+
+	m = pressKey(t, m, "G")
+	out = m.View()
+	if !strings.Contains(out, "node050") || !strings.Contains(out, "/50, +") {
+		t.Fatalf("expected G to jump to the last page, got:\n%s", out)
+	}
+	if !strings.Contains(out, "TOTAL") {
+		t.Fatalf("expected TOTAL row to stay visible while scrolled, got:\n%s", out)
+	}
+
+	m = pressKey(t, m, "j")
+	if got := m.scroll.offset[panelNodes]; got != 50-m.scroll.window[panelNodes].visible {
+		t.Fatalf("expected offset clamped at last page, got %d", got)
+	}
+
+	m = pressKey(t, m, "g")
+	out = m.View()
+	if !strings.Contains(out, "node001") {
+		t.Fatalf("expected g to return to the top, got:\n%s", out)
+	}
+}
+
+func TestPageKeysMoveByVisibleRows(t *testing.T) {
+	m := manyNodesModel(50)
+	_ = m.View()
+	page := m.scroll.window[panelNodes].visible
+	if page <= 1 {
+		t.Fatalf("expected a multi-row window, got %d", page)
+	}
+
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyPgDown})
+	m = next.(Model)
+	if got := m.scroll.offset[panelNodes]; got != page {
+		t.Fatalf("expected pgdown to move %d rows, got %d", page, got)
+	}
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyPgUp})
+	m = next.(Model)
+	if got := m.scroll.offset[panelNodes]; got != 0 {
+		t.Fatalf("expected pgup to return to 0, got %d", got)
+	}
+}
+
+func TestOverviewFocusSwitchesScrolledPanel(t *testing.T) {
+	m := manyNodesModel(50)
+	m.view = viewOverview
+	_ = m.View()
+
+	m = pressKey(t, m, "j")
+	if m.scroll.cursor[panelNodes] != 1 {
+		t.Fatalf("expected nodes focused by default in overview")
+	}
+	m = pressKey(t, m, "f")
+	if m.focusedPanel() != panelUsers {
+		t.Fatalf("expected f to move focus to users, got %s", m.focusedPanel())
+	}
+	if !strings.Contains(m.footerHint(), "scroll users") {
+		t.Fatalf("expected footer to name focused panel, got %q", m.footerHint())
+	}
+}
+
+func TestCursorFollowsItsJobAcrossSnapshots(t *testing.T) {
+	m := seededModel()
+	m.view = viewJobs
+	m.height = 12
+	jobs := make([]slurm.Job, 0, 40)
+	for i := 1; i <= 40; i++ {
+		jobs = append(jobs, slurm.Job{ID: fmt.Sprint(2000 + i), State: "PENDING", User: "alice", Partition: "cpu", CPUs: 1})
+	}
+	m.snapshot.Jobs = jobs
+	_ = m.View()
+	for i := 0; i < 10; i++ {
+		m = pressKey(t, m, "j")
+	}
+	offset := m.scroll.offset[panelJobs]
+
+	snap := *m.snapshot
+	snap.Jobs = append([]slurm.Job{{ID: "1998", State: "RUNNING"}, {ID: "1999", State: "RUNNING"}}, jobs[1:]...)
+	next, _ := m.Update(updateMsg{update: monitor.Update{Snapshot: &snap, State: monitor.StateConnected}})
+	m = next.(Model)
+	_ = m.View()
+	if got := m.snapshot.Jobs[m.scroll.cursor[panelJobs]].ID; got != "2011" {
+		t.Fatalf("expected the cursor to stay on job 2011, got %s", got)
+	}
+	if got := m.scroll.offset[panelJobs]; got != offset+1 {
+		t.Fatalf("expected the window to shift with the job, got offset %d from %d", got, offset)
+	}
+
+	snap.Jobs = snap.Jobs[:5]
+	next, _ = m.Update(updateMsg{update: monitor.Update{Snapshot: &snap, State: monitor.StateConnected}})
+	m = next.(Model)
+	_ = m.View()
+	if got := m.scroll.cursor[panelJobs]; got != 4 {
+		t.Fatalf("expected a cursor on a departed job clamped to the last row, got %d", got)
+	}
+}
+
+func TestWindowTitle(t *testing.T) {
+	cases := []struct {
+		offset, visible, total int
+		want                   string
+	}{
+		{0, 5, 5, "nodes"},
+		{0, 0, 5, "nodes (+5 hidden)"},
+		{0, 3, 10, "nodes (top 3/10, +7 hidden)"},
+		{4, 3, 10, "nodes (rows 5-7/10, +7 hidden)"},
+	}
+	for _, tc := range cases {
+		if got := windowTitle("nodes", tc.offset, tc.visible, tc.total); got != tc.want {
+			t.Fatalf("windowTitle(%d,%d,%d) = %q, want %q", tc.offset, tc.visible, tc.total, got, tc.want)
+		}
+	}
+}
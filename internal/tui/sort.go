@@ -0,0 +1,72 @@
+package tui
+
+import "slurm_monitor/internal/slurm"
+
+// handleSortKey cycles the focused table's sort key with s and flips its
+// direction with S. Cycling resets the direction to the key's natural one.
+func (m *Model) handleSortKey(key string) bool {
+	if key != "s" && key != "S" {
+		return false
+	}
+	switch m.focusedPanel() {
+	case panelNodes:
+		if key == "S" {
+			m.nodeSort.Desc = !m.nodeSort.Desc
+		} else {
+			next := nextNodeSortKey(m.nodeSort.Key)
+			m.nodeSort = slurm.NodeSort{Key: next, Desc: next.DefaultDesc()}
+		}
+	case panelUsers:
+		if key == "S" {
+			m.userSort.Desc = !m.userSort.Desc
+		} else {
+			next := nextUserSortKey(m.userSort.Key)
+			m.userSort = slurm.UserSort{Key: next, Desc: next.DefaultDesc()}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func nextNodeSortKey(k slurm.NodeSortKey) slurm.NodeSortKey {
+	for i, key := range slurm.NodeSortKeys {
+		if key == k {
+			return slurm.NodeSortKeys[(i+1)%len(slurm.NodeSortKeys)]
+		}
+	}
+	return slurm.NodeSortKeys[0]
+}
+
+func nextUserSortKey(k slurm.UserSortKey) slurm.UserSortKey {
+	for i, key := range slurm.UserSortKeys {
+		if key == k {
+			return slurm.UserSortKeys[(i+1)%len(slurm.UserSortKeys)]
+		}
+	}
+	return slurm.UserSortKeys[0]
+}
+
+// sortedNodes returns a copy of the snapshot nodes in the active node order.
+func (m Model) sortedNodes() []slurm.Node {
+	if m.snapshot == nil {
+		return nil
+	}
+	nodes := append([]slurm.Node(nil), m.snapshot.Nodes...)
+	slurm.SortNodes(nodes, m.nodeSort)
+	return nodes
+}
+
+// sortedUsers returns a copy of the snapshot users in the active user order.
+func (m Model) sortedUsers() []slurm.UserSummary {
+	if m.snapshot == nil {
+		return nil
+	}
+	users := append([]slurm.UserSummary(nil), m.snapshot.Users...)
+	slurm.SortUsers(users, m.userSort)
+	return users
+}
+
+func sortSuffix(label string) string {
+	return " · sort " + label
+}
@@ -0,0 +1,59 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"slurm_monitor/internal/slurm"
+)
+
+func TestSortKeysCycleFocusedTable(t *testing.T) {
+	m := seededModel()
+	m.view = viewNodes
+	m = pressKey(t, m, "s")
+	if m.nodeSort.Key != slurm.NodeSortState || m.nodeSort.Desc {
+		t.Fatalf("expected s to advance to state ascending, got %+v", m.nodeSort)
+	}
+	m = pressKey(t, m, "S")
+	if !m.nodeSort.Desc {
+		t.Fatalf("expected S to flip direction, got %+v", m.nodeSort)
+	}
+
+	m.view = viewUsers
+	m = pressKey(t, m, "s")
+	if m.userSort.Key != slurm.UserSortHeldGPU || !m.userSort.Desc {
+		t.Fatalf("expected user sort to advance to held-gpu descending, got %+v", m.userSort)
+	}
+	if m.nodeSort.Key != slurm.NodeSortState {
+		t.Fatalf("expected node sort to stay unchanged while users are focused")
+	}
+}
+
+func TestNodeTableUsesActiveSortAndShowsIt(t *testing.T) {
+	m := seededModel()
+	m.view = viewNodes
+	m.nodeSort = slurm.NodeSort{Key: slurm.NodeSortGPU, Desc: true}
+	nodes := m.sortedNodes()
+	for i := 1; i < len(nodes); i++ {
+		if nodes[i-1].GPUAlloc < nodes[i].GPUAlloc {
+			t.Fatalf("expected nodes by gpu alloc descending, got %d before %d", nodes[i-1].GPUAlloc, nodes[i].GPUAlloc)
+		}
+	}
+	out := m.View()
+	if !strings.Contains(out, "sort gpu↓") {
+		t.Fatalf("expected active sort in node panel header, got:\n%s", out)
+	}
+	if m.snapshot.Nodes[0].Name != sampleSnapshot().Nodes[0].Name {
+		t.Fatalf("expected sorting to leave the stored snapshot untouched")
+	}
+}
+
+func TestNewModelUsesSortOptions(t *testing.T) {
+	m := NewModel(Options{UserSort: slurm.UserSort{Key: slurm.UserSortPendingGPU, Desc: true}})
+	if m.nodeSort != slurm.DefaultNodeSort {
+		t.Fatalf("expected default node sort, got %+v", m.nodeSort)
+	}
+	if m.userSort.Key != slurm.UserSortPendingGPU {
+		t.Fatalf("expected user sort from options, got %+v", m.userSort)
+	}
+}
@@ -0,0 +1,97 @@
+package tui
+
+import (
+	"fmt"
+
+	"slurm_monitor/internal/history"
+	"slurm_monitor/internal/uifmt"
+)
+
+// hasTrend reports whether there are enough samples to draw a sparkline.
+func (m Model) hasTrend() bool {
+	return m.history.Len() >= 2
+}
+
+// percentSpark draws a 0-100% trend.
+func (m Model) percentSpark(width int, f func(history.Sample) float64) string {
+	return uifmt.Sparkline(m.history.Series(f), 0, 100, width)
+}
+
+// countSpark draws a count trend scaled to its own peak.
+func (m Model) countSpark(width int, f func(history.Sample) float64) string {
+	return uifmt.Sparkline(m.history.Series(f), 0, 0, width)
+}
+
+func cpuAllocPercent(s history.Sample) float64 {
+	return history.Percent(s.Totals.CPUAlloc, s.Totals.CPUTotal)
+}
+
+func memAllocPercent(s history.Sample) float64 {
+	return history.Percent(s.Totals.MemAllocMB, s.Totals.MemTotalMB)
+}
+
+func gpuAllocPercent(s history.Sample) float64 {
+	return history.Percent(s.Totals.GPUAlloc, s.Totals.GPUTotal)
+}
+
+// nodeTrendLine sits under the node TOTAL row with each allocation sparkline
+// under its own column.
+func (m Model) nodeTrendLine(compact bool) string {
+	span := humanDuration(m.history.Span())
+	if compact {
+		return m.styles.dim.Render(fmt.Sprintf(
+			"%-14s %-9s %-10s %-9s %-13s %-13s",
+			"trend", span, "",
+			m.percentSpark(9, cpuAllocPercent),
+			m.percentSpark(13, memAllocPercent),
+			m.percentSpark(13, gpuAllocPercent),
+		))
+	}
+	return m.styles.dim.Render(fmt.Sprintf(
+		"%-12s %-14s %-14s %-17s %-20s %-17s",
+		"trend", span, "",
+		m.percentSpark(17, cpuAllocPercent),
+		m.percentSpark(20, memAllocPercent),
+		m.percentSpark(17, gpuAllocPercent),
+	))
+}
+
+// queueTrendLine is a queue status line with the count's recent trend.
+func (m Model) queueTrendLine(label string, value int, f func(history.Sample) float64) string {
+	line := m.queueStatusLine(label, value)
+	if !m.hasTrend() {
+		return line
+	}
+	return line + "  " + m.styles.dim.Render(m.countSpark(16, f))
+}
+
+// userHeldGPUTrend is a user's held-GPU trend, scaled to that user's peak.
+func (m Model) userHeldGPUTrend(user string, width int) string {
+	return m.countSpark(width, func(s history.Sample) float64 {
+		return float64(s.HeldGPUByUser[user])
+	})
+}
+
+// userTrendHeader and userTrendRow add a narrow held-GPU trend column to the
+// overview user table once history has samples.
+func (m Model) userTrendHeader(header string) string {
+	if !m.hasTrend() {
+		return header
+	}
+	return header + "  heldGPU"
+}
+
+func (m Model) userTrendRow(row, user string) string {
+	if !m.hasTrend() {
+		return row
+	}
+	return row + "  " + m.userHeldGPUTrend(user, 7)
+}
+
+func pendingCPUJobs(s history.Sample) float64 {
+	return float64(s.PendingCPU)
+}
+
+func pendingGPUJobs(s history.Sample) float64 {
+	return float64(s.PendingGPU)
+}
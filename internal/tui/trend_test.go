@@ -0,0 +1,55 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"slurm_monitor/internal/history"
+)
+
+func TestTrendsRenderOnceHistoryHasSamples(t *testing.T) {
+	m := seededModel()
+	m.height = 40
+	m.history = history.NewRing(time.Hour, time.Second)
+
+	if out := m.View(); strings.Contains(out, "trend") {
+		t.Fatalf("did not expect trends without history, got:\n%s", out)
+	}
+
+	base := time.Now()
+	for i := 0; i < 3; i++ {
+		snap := *m.snapshot
+		snap.CollectedAt = base.Add(time.Duration(i) * time.Minute)
+		m.history.Add(snap)
+	}
+	out := m.View()
+	if !strings.Contains(out, "trend") || !strings.Contains(out, "2m0s") {
+		t.Fatalf("expected node trend row with span, got:\n%s", out)
+	}
+	if !strings.ContainsAny(out, "▁█") {
+		t.Fatalf("expected sparkline glyphs, got:\n%s", out)
+	}
+
+	if !strings.Contains(out, "heldGPU") {
+		t.Fatalf("expected held-GPU trend column in the overview user table, got:\n%s", out)
+	}
+
+	m.view = viewUsers
+	if out := m.View(); !strings.Contains(out, "heldGPU trend") {
+		t.Fatalf("expected held-GPU trend column in the users view, got:\n%s", out)
+	}
+}
+
+func TestHistorySizedForSampleInterval(t *testing.T) {
+	m := NewModel(Options{Refresh: 10 * time.Second, SampleInterval: 2 * time.Second, History: 30 * time.Minute})
+	base := time.Now()
+	snap := *seededModel().snapshot
+	for i := 0; i <= 900; i++ {
+		snap.CollectedAt = base.Add(time.Duration(i) * 2 * time.Second)
+		m.history.Add(snap)
+	}
+	if got := m.history.Span(); got != 30*time.Minute {
+		t.Fatalf("expected trends to span the whole history window, got %v", got)
+	}
+}
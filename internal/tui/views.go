@@ -0,0 +1,572 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"slurm_monitor/internal/slurm"
+	"slurm_monitor/internal/uifmt"
+)
+
+type viewID int
+
+const (
+	viewOverview viewID = iota
+	viewNodes
+	viewQueue
+	viewUsers
+	viewPartitions
+	viewJobs
+	viewCount
+)
+
+var viewNames = [viewCount]string{
+	viewOverview:   "overview",
+	viewNodes:      "nodes",
+	viewQueue:      "queue",
+	viewUsers:      "users",
+	viewPartitions: "partitions",
+	viewJobs:       "jobs",
+}
+
+func (v viewID) String() string {
+	if v < 0 || v >= viewCount {
+		return "unknown"
+	}
+	return viewNames[v]
+}
+
+// handleViewKey switches views on number keys and tab/shift+tab. It reports
+// whether the key was consumed.
+func (m *Model) handleViewKey(key string) bool {
+	switch key {
+	case "tab":
+		m.view = (m.view + 1) % viewCount
+		return true
+	case "shift+tab":
+		m.view = (m.view + viewCount - 1) % viewCount
+		return true
+	}
+	if len(key) == 1 && key[0] >= '1' && key[0] < '1'+byte(viewCount) {
+		m.view = viewID(key[0] - '1')
+		return true
+	}
+	return false
+}
+
+func (m Model) renderTabBar() string {
+	parts := make([]string, 0, viewCount)
+	for v := viewID(0); v < viewCount; v++ {
+		label := fmt.Sprintf("%d %s", v+1, v)
+		if v == m.view {
+			parts = append(parts, m.styles.chipOK.Render(label))
+			continue
+		}
+		parts = append(parts, m.styles.dim.Render(" "+label+" "))
+	}
+	return truncateRunes(strings.Join(parts, " "), m.width)
+}
+
+// renderBody dispatches to the active view. Every view except overview uses a
+// single full-height panel so its table can use the whole terminal.
+func (m Model) renderBody(maxHeight int) string {
+	if m.view == viewOverview && m.detail == nil {
+		return m.renderMain(maxHeight)
+	}
+
+	inner := max(20, m.width-6)
+	contentWidth := panelContentWidth(inner)
+	contentHeight := panelContentHeight(maxHeight)
+
+	var body string
+	switch {
+	case m.detail != nil:
+		body = m.renderDetail(contentHeight, contentWidth)
+	case m.view == viewNodes:
+		compactLayout := m.compact || m.width < 118
+		body = m.renderNodeTableWithBudget(contentHeight, maxHeight, compactLayout, contentWidth)
+	case m.view == viewQueue:
+		body = m.renderQueueDetail(contentHeight, contentWidth)
+	case m.view == viewUsers:
+		body = m.renderUserDetail(contentHeight, contentWidth)
+	case m.view == viewPartitions:
+		body = m.renderPartitionDetail(contentHeight, contentWidth)
+	case m.view == viewJobs:
+		body = m.renderJobDetail(contentHeight, contentWidth)
+	}
+	panel := m.styles.panel.Width(inner).Height(contentHeight).Render(body)
+	return clipToHeight(panel, maxHeight)
+}
+
+// renderQueueDetail shows the queue totals and per-model GPU capacity and
+// demand next to the breakdowns the parser already computes: pending causes,
+// raw job states and top job names. Wide
+// panels place the breakdowns in a second column; narrow ones stack them under
+// the totals.
+func (m Model) renderQueueDetail(contentHeight, contentWidth int) string {
+	q := m.snapshot.Queue
+	r := q.ResourceLoad
+	total := q.Running + q.Pending + q.Other
+	summary := []string{
+		m.sectionTitle("queue summary") + m.sectionLabel(slurm.SectionQueue) + m.lookupsLabel(),
+		m.queueStatusLine("running cpu jobs", q.RunningCPUJobs),
+		m.queueStatusLine("running gpu jobs", q.RunningGPUJobs),
+		m.queueTrendLine("pending cpu jobs", q.PendingCPUJobs, pendingCPUJobs),
+		m.queueTrendLine("pending gpu jobs", q.PendingGPUJobs, pendingGPUJobs),
+		m.queueStatusLine("other", q.Other),
+		m.queueStatusLine("total", total),
+		"",
+		m.sectionTitle("queue resources") + m.sectionLabel(slurm.SectionQueue) + m.lookupsLabel(),
+		fmt.Sprintf("%-10s %10s %10s %10s", "", "cpu", "mem", "gpu"),
+		fmt.Sprintf("%-10s %10d %10s %10d", "running", r.RunningCPU, uifmt.MemMB(r.RunningMemMB), r.RunningGPU),
+		fmt.Sprintf("%-10s %10d %10s %10d", "pending", r.PendingCPU, uifmt.MemMB(r.PendingMemMB), r.PendingGPU),
+	}
+	if stats := m.snapshot.GPUTypeStats(); len(stats) > 0 {
+		summary = append(summary, "", m.sectionTitle("gpu types"), fmt.Sprintf(gpuTypeRowFmt, "model", "alloc", "run", "pend", "pjobs"))
+		for _, st := range stats {
+			summary = append(summary, gpuTypeRowLine(st))
+		}
+	}
+
+	stateCounts := make([]slurm.NameCount, 0, len(q.ByState))
+	for _, st := range q.ByState {
+		stateCounts = append(stateCounts, slurm.NameCount{Name: st.State, Count: st.Count})
+	}
+	sections := []breakdownSection{
+		{title: "pending causes", counts: q.PendingCause, total: q.Pending},
+		{title: "job states", counts: stateCounts, total: total},
+		{title: "job names", counts: q.ByJobName, total: total},
+	}
+
+	const summaryWidth = 46
+	if contentWidth >= summaryWidth+breakdownMinWidth {
+		left := strings.Join(fitLinesToWidth(clipLines(summary, contentHeight), summaryWidth), "\n")
+		right := m.renderBreakdownSections(sections, contentHeight, contentWidth-summaryWidth)
+		left = lipgloss.NewStyle().Width(summaryWidth).Render(left)
+		return lipgloss.JoinHorizontal(lipgloss.Top, left, strings.Join(right, "\n"))
+	}
+
+	lines := clipLines(summary, contentHeight)
+	if rest := contentHeight - len(lines) - 1; rest > 0 {
+		lines = append(lines, "")
+		lines = append(lines, m.renderBreakdownSections(sections, rest, contentWidth)...)
+	}
+	return strings.Join(fitLinesToWidth(lines, contentWidth), "\n")
+}
+
+const gpuTypeRowFmt = "%-10s %11s %7s %7s %6s"
+
+// gpuTypeRowLine shows a GPU model's allocated/total GPUs on nodes next to the
+// GPUs running and pending jobs hold or request. GPUs and requests without a
+// model share the untyped row.
+func gpuTypeRowLine(st slurm.GPUTypeStats) string {
+	name := st.Type
+	if name == "" {
+		name = "untyped"
+	}
+	alloc := "-"
+	if st.Total > 0 {
+		alloc = uifmt.Ratio(st.Alloc, st.Total)
+	}
+	return fmt.Sprintf(gpuTypeRowFmt, truncateRunes(name, 10), alloc, fmt.Sprint(st.RunningGPU), fmt.Sprint(st.PendingGPU), fmt.Sprint(st.PendingJobs))
+}
+
+const breakdownMinWidth = 34
+
+type breakdownSection struct {
+	title  string
+	counts []slurm.NameCount
+	// total is the denominator for the share column.
+	total int
+}
+
+// renderBreakdownSections splits contentHeight between the sections. Short
+// sections take only the rows they need and hand the rest to longer ones.
+func (m Model) renderBreakdownSections(sections []breakdownSection, contentHeight, contentWidth int) []string {
+	if len(sections) == 0 || contentHeight <= 0 {
+		return nil
+	}
+	budgets := splitSectionBudgets(sections, contentHeight-(len(sections)-1))
+	nameWidth := min(32, max(8, contentWidth-16))
+
+	var lines []string
+	for i, sec := range sections {
+		if budgets[i] <= 0 {
+			continue
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, m.renderBreakdown(sec, budgets[i], nameWidth)...)
+	}
+	return fitLinesToWidth(clipLines(lines, contentHeight), contentWidth)
+}
+
+// splitSectionBudgets water-fills total rows across sections: each round gives
+// every unsatisfied section an equal share, capped at what it needs (title,
+// header and one row per entry).
+func splitSectionBudgets(sections []breakdownSection, total int) []int {
+	budgets := make([]int, len(sections))
+	need := make([]int, len(sections))
+	for i, sec := range sections {
+		need[i] = 2 + max(1, len(sec.counts))
+	}
+	for total > 0 {
+		open := 0
+		for i := range sections {
+			if budgets[i] < need[i] {
+				open++
+			}
+		}
+		if open == 0 {
+			break
+		}
+		share := max(1, total/open)
+		for i := range sections {
+			if total == 0 {
+				break
+			}
+			if budgets[i] >= need[i] {
+				continue
+			}
+			give := min(share, min(need[i]-budgets[i], total))
+			budgets[i] += give
+			total -= give
+		}
+	}
+	return budgets
+}
+
+func (m Model) renderBreakdown(sec breakdownSection, contentHeight, nameWidth int) []string {
+	rowFmt := fmt.Sprintf("%%-%ds %%7s %%6s", nameWidth)
+	rows := make([]string, 0, len(sec.counts))
+	for _, c := range sec.counts {
+		rows = append(rows, fmt.Sprintf(rowFmt, truncateRunes(c.Name, nameWidth), fmt.Sprint(c.Count), sharePct(c.Count, sec.total)))
+	}
+	header := fmt.Sprintf(rowFmt, "name", "count", "share")
+	if len(rows) == 0 {
+		rows = append(rows, m.styles.dim.Render("none"))
+	}
+	return m.renderTableWithBudget(tableSpec{panel: panelNone, title: sec.title, header: header, rows: rows}, contentHeight)
+}
+
+func sharePct(count, total int) string {
+	if total <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", float64(count)*100/float64(total))
+}
+
+const userDetailRowFmt = "%-14s %5s %5s %5s %5s  %8s %8s  %8s %9s %8s"
+
+func userDetailHeaderLine() string {
+	return fmt.Sprintf(userDetailRowFmt, "user", "rCJ", "rGJ", "pCJ", "pGJ", "heldCPU", "heldGPU", "pendCPU", "pendMem", "pendGPU")
+}
+
+func userDetailRowLine(u slurm.UserSummary) string {
+	return fmt.Sprintf(
+		userDetailRowFmt,
+		truncateRunes(u.User, 14),
+		fmt.Sprint(u.RunningCPUJobs),
+		fmt.Sprint(u.RunningGPUJobs),
+		fmt.Sprint(u.PendingCPUJobs),
+		fmt.Sprint(u.PendingGPUJobs),
+		fmt.Sprint(u.RunningCPU),
+		fmt.Sprint(u.RunningGPU),
+		fmt.Sprint(u.PendingCPU),
+		uifmt.MemMB(u.PendingMemMB),
+		fmt.Sprint(u.PendingGPU),
+	)
+}
+
+// renderUserDetail is the full-screen user table. Unlike the overview user
+// section it has room for held and pending resource totals next to the
+// job-count split, so both are shown with distinct column labels, plus a
+// held-GPU trend once history has samples.
+func (m Model) renderUserDetail(contentHeight, contentWidth int) string {
+	users := m.sortedUsers()
+	header := userDetailHeaderLine()
+	showTrend := m.hasTrend()
+	if showTrend {
+		header += "  heldGPU trend"
+	}
+	rows := make([]string, 0, len(users))
+	for _, u := range users {
+		row := userDetailRowLine(u)
+		if showTrend {
+			row += "  " + m.userHeldGPUTrend(u.User, 13)
+		}
+		rows = append(rows, row)
+	}
+	lines := m.renderTableWithBudget(tableSpec{
+		panel:   panelUsers,
+		section: slurm.SectionQueue,
+		title:   "user view",
+		suffix:  sortSuffix(m.userSort.String()),
+		header:  header,
+		rows:    rows,
+	}, contentHeight)
+	return strings.Join(fitLinesToWidth(lines, contentWidth), "\n")
+}
+
+const partitionRowFmt = "%-14s %7s %7s %5s %5s %5s  %-8s %5s %6s %11s %6s %9s %7s"
+
+// partitionRow pairs a partition's job counts with its capacity. Either side
+// may be missing: a partition without jobs still has capacity, and captures
+// made before partitions were collected have counts only.
+type partitionRow struct {
+	name   string
+	counts slurm.PartitionCount
+	part   *slurm.Partition
+}
+
+// partitionRows lists partitions in job-count order, then those with no jobs
+// in the order scontrol printed them.
+func (m Model) partitionRows() []partitionRow {
+	byName := make(map[string]*slurm.Partition, len(m.snapshot.Partitions))
+	for i := range m.snapshot.Partitions {
+		byName[m.snapshot.Partitions[i].Name] = &m.snapshot.Partitions[i]
+	}
+	rows := make([]partitionRow, 0, len(m.snapshot.Queue.ByPartition)+len(m.snapshot.Partitions))
+	listed := make(map[string]bool, len(m.snapshot.Queue.ByPartition))
+	for _, c := range m.snapshot.Queue.ByPartition {
+		listed[c.Partition] = true
+		rows = append(rows, partitionRow{name: c.Partition, counts: c, part: byName[c.Partition]})
+	}
+	for i := range m.snapshot.Partitions {
+		p := &m.snapshot.Partitions[i]
+		if !listed[p.Name] {
+			rows = append(rows, partitionRow{name: p.Name, part: p})
+		}
+	}
+	return rows
+}
+
+func partitionRowLine(r partitionRow) string {
+	name := r.name
+	state, nodes, shared, cpu, idle, gpu, maxTime := "-", "-", "-", "-", "-", "-", "-"
+	if p := r.part; p != nil {
+		if p.Default {
+			// sinfo marks the default partition the same way.
+			name += "*"
+		}
+		state = strings.ToLower(p.State)
+		nodes = fmt.Sprint(p.TotalNodes)
+		shared = fmt.Sprint(p.SharedNodes)
+		cpu = uifmt.Ratio(p.AllocCPUs, p.TotalCPUs)
+		idle = fmt.Sprint(p.IdleCPUs)
+		if p.TotalGPUs > 0 {
+			gpu = uifmt.Ratio(p.AllocGPUs, p.TotalGPUs)
+		}
+		maxTime = partitionLimit(p.MaxTime)
+	}
+	c := r.counts
+	return fmt.Sprintf(
+		partitionRowFmt,
+		truncateRunes(name, 14),
+		fmt.Sprint(c.Running),
+		fmt.Sprint(c.Pending),
+		fmt.Sprint(c.Other),
+		fmt.Sprint(c.Running+c.Pending+c.Other),
+		fmt.Sprint(c.Shared),
+		truncateRunes(state, 8),
+		nodes,
+		shared,
+		cpu,
+		idle,
+		gpu,
+		maxTime,
+	)
+}
+
+// partitionLimit renders a partition MaxTime compactly; zero is UNLIMITED.
+func partitionLimit(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "unl"
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+}
+
+// renderPartitionDetail shows job counts per partition next to the
+// partition's state, size, shared nodes, CPU and GPU allocation, idle CPUs on
+// usable nodes and MaxTime. The multi column counts jobs submitted to several
+// partitions, which count in each of them. Nodes shared between partitions
+// likewise count in each, so the TOTAL row takes job counts from the queue
+// summary and capacity from the node table instead of summing partitions.
+func (m Model) renderPartitionDetail(contentHeight, contentWidth int) string {
+	parts := m.partitionRows()
+	rows := make([]string, 0, len(parts))
+	for _, p := range parts {
+		rows = append(rows, partitionRowLine(p))
+	}
+	header := fmt.Sprintf(partitionRowFmt, "partition", "running", "pending", "other", "total", "multi", "state", "nodes", "shared", "cpu", "idle", "gpu", "maxtime")
+	q := m.snapshot.Queue
+	var multi int
+	for _, j := range m.snapshot.Jobs {
+		if len(j.PartitionNames()) > 1 {
+			multi += j.Count()
+		}
+	}
+	nodes, shared, cpu, gpu := "-", "-", "-", "-"
+	if len(m.snapshot.Partitions) > 0 {
+		totals := m.snapshot.Totals()
+		nodes = fmt.Sprint(len(m.snapshot.Nodes))
+		var n int
+		for _, node := range m.snapshot.Nodes {
+			if len(node.Partitions) > 1 {
+				n++
+			}
+		}
+		shared = fmt.Sprint(n)
+		cpu = uifmt.Ratio(totals.CPUAlloc, totals.CPUTotal)
+		if totals.GPUTotal > 0 {
+			gpu = uifmt.Ratio(totals.GPUAlloc, totals.GPUTotal)
+		}
+	}
+	total := m.styles.accent.Render(fmt.Sprintf(
+		partitionRowFmt, "TOTAL", fmt.Sprint(q.Running), fmt.Sprint(q.Pending), fmt.Sprint(q.Other), fmt.Sprint(q.Running+q.Pending+q.Other),
+		fmt.Sprint(multi), "", nodes, shared, cpu, "-", gpu, "",
+	))
+	lines := m.renderTableWithBudget(tableSpec{panel: panelPartitions, section: slurm.SectionPartitions, title: "partition view", header: header, rows: rows, footer: total}, contentHeight)
+	return strings.Join(fitLinesToWidth(lines, contentWidth), "\n")
+}
+
+const jobRowFmt = "%-14s %-10s %-11s %-12s %5s %8s %4s  %s"
+
+func jobHeaderLine() string {
+	return fmt.Sprintf(jobRowFmt, "job", "user", "state", "partition", "cpu", "mem", "gpu", "name  nodes/reason")
+}
+
+func jobRowLine(j slurm.Job) string {
+	where := j.NodeList
+	if where == "" {
+		where = j.Reason
+	}
+	name := truncateRunes(j.Name, 24)
+	if n := j.Count(); n > 1 {
+		// A task range past the expansion cap is one row for many tasks.
+		name += fmt.Sprintf(" (%d tasks)", n)
+	}
+	return fmt.Sprintf(
+		jobRowFmt,
+		truncateRunes(j.ID, 14),
+		truncateRunes(j.User, 10),
+		truncateRunes(j.State, 11),
+		truncateRunes(j.Partition, 12),
+		fmt.Sprint(j.CPUs),
+		uifmt.MemMB(j.MemMB),
+		fmt.Sprint(j.GPUs),
+		name+"  "+where,
+	)
+}
+
+// renderJobDetail formats only the rows in the window, since the queue can
+// hold hundreds of thousands of jobs.
+func (m Model) renderJobDetail(contentHeight, contentWidth int) string {
+	jobs := m.snapshot.Jobs
+	lines := m.renderTableWithBudget(tableSpec{
+		panel:   panelJobs,
+		section: slurm.SectionQueue,
+		title:   "job view",
+		header:  jobHeaderLine(),
+		count:   len(jobs),
+		row:     func(i int) string { return jobRowLine(jobs[i]) },
+	}, contentHeight)
+	return strings.Join(fitLinesToWidth(lines, contentWidth), "\n")
+}
+
+// tableSpec describes a budgeted table. suffix follows the row-window
+// metadata in the title; footer (such as a TOTAL row) is optional. section,
+// when set, labels the title if that part of the snapshot is stale. A table
+// too long to format up front sets count and row instead of rows, and only
+// the rows in the window are formatted.
+type tableSpec struct {
+	panel   panelID
+	section slurm.Section
+	title   string
+	suffix  string
+	header  string
+	rows    []string
+	count   int
+	row     func(i int) string
+	footer  string
+}
+
+// renderTableWithBudget lays out title, header, as many rows as fit and the
+// footer inside contentHeight. The title and footer are always kept; the row
+// window follows the panel's scroll offset and is reported in the title.
+func (m Model) renderTableWithBudget(t tableSpec, contentHeight int) []string {
+	if contentHeight <= 0 {
+		return nil
+	}
+	mandatory := 1
+	if t.footer != "" {
+		mandatory++
+	}
+	total, row := len(t.rows), func(i int) string { return t.rows[i] }
+	if t.row != nil {
+		total, row = t.count, t.row
+	}
+	remaining := contentHeight - mandatory
+	showHeader := remaining > 0
+	visibleRows := 0
+	if showHeader {
+		visibleRows = min(total, remaining-1)
+	}
+	offset := m.scrollOffset(t.panel, visibleRows, total)
+	lines := []string{m.sectionTitle(windowTitle(t.title, offset, visibleRows, total) + t.suffix)}
+	if t.section != "" {
+		lines[0] += m.sectionLabel(t.section)
+	}
+	if showHeader {
+		lines = append(lines, t.header)
+	}
+	for i := offset; i < offset+visibleRows; i++ {
+		lines = append(lines, m.highlightRow(t.panel, i, row(i)))
+	}
+	if t.footer != "" {
+		lines = append(lines, t.footer)
+	}
+	return clipLines(lines, contentHeight)
+}
+
+// highlightRow marks the cursor row of the focused panel.
+func (m Model) highlightRow(p panelID, index int, line string) string {
+	if row, ok := m.selectedRow(p); ok && row == index {
+		return m.styles.selected.Render(line)
+	}
+	return line
+}
+
+func (m Model) footerHint() string {
+	if m.detail != nil {
+		return "Ctrl+C to exit · esc back · j/k pgup/pgdn g/G scroll"
+	}
+	hint := "Ctrl+C to exit · 1-6/tab view"
+	if p := m.focusedPanel(); p != panelNone {
+		hint += " · j/k pgup/pgdn g/G scroll " + p.String()
+		if p == panelNodes || p == panelUsers {
+			hint += " · s/S sort"
+		}
+		if p == panelNodes || p == panelJobs {
+			hint += " · enter details"
+		}
+	}
+	if m.view == viewOverview {
+		hint += " · f focus"
+	}
+	hint += " · / filter"
+	if m.playback != nil {
+		hint += " · space pause · ,/. step · [/] ±1m {/} ±10m · -/+ speed"
+	}
+	return hint
+}
@@ -0,0 +1,298 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"slurm_monitor/internal/slurm"
+)
+
+func pressKey(t *testing.T, m Model, key string) Model {
+	t.Helper()
+	var msg tea.KeyMsg
+	switch key {
+	case "tab":
+		msg = tea.KeyMsg{Type: tea.KeyTab}
+	case "shift+tab":
+		msg = tea.KeyMsg{Type: tea.KeyShiftTab}
+	default:
+		msg = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+	}
+	next, _ := m.Update(msg)
+	return next.(Model)
+}
+
+func TestViewKeysSwitchViews(t *testing.T) {
+	m := seededModel()
+	if m.view != viewOverview {
+		t.Fatalf("expected overview by default, got %s", m.view)
+	}
+	m = pressKey(t, m, "3")
+	if m.view != viewQueue {
+		t.Fatalf("expected key 3 to select queue view, got %s", m.view)
+	}
+	m = pressKey(t, m, "tab")
+	if m.view != viewUsers {
+		t.Fatalf("expected tab to advance to users view, got %s", m.view)
+	}
+	m = pressKey(t, m, "1")
+	m = pressKey(t, m, "shift+tab")
+	if m.view != viewJobs {
+		t.Fatalf("expected shift+tab to wrap to jobs view, got %s", m.view)
+	}
+	m = pressKey(t, m, "9")
+	if m.view != viewJobs {
+		t.Fatalf("expected out-of-range number key to be ignored, got %s", m.view)
+	}
+}
+
+func TestEveryViewFitsViewport(t *testing.T) {
+	for v := viewID(0); v < viewCount; v++ {
+		t.Run(v.String(), func(t *testing.T) {
+			m := seededModel()
+			m.snapshot.Jobs = sampleJobs()
+			m.view = v
+			m.width = 90
+			m.height = 24
+			out := m.View()
+			assertViewportBounds(t, out, 90, 24)
+			lines := strings.Split(out, "\n")
+			if !strings.Contains(lines[len(lines)-1], "Ctrl+C to exit") {
+				t.Fatalf("expected exit hint on bottom row in %s view", v)
+			}
+		})
+	}
+}
+
+func TestTabBarHighlightsActiveView(t *testing.T) {
+	m := seededModel()
+	m.view = viewPartitions
+	out := m.View()
+	lines := strings.Split(out, "\n")
+	if !strings.Contains(lines[1], "5 partitions") || !strings.Contains(lines[1], "1 overview") {
+		t.Fatalf("expected tab bar on the row under the header, got: %q", lines[1])
+	}
+}
+
+// partitionCells maps each partition view row, keyed by its first cell, to
+// its cells keyed by column header.
+func partitionCells(t *testing.T, body string) map[string]map[string]string {
+	t.Helper()
+	var header []string
+	rows := make(map[string]map[string]string)
+	for _, line := range strings.Split(body, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "partition" {
+			header = fields
+			continue
+		}
+		if header == nil {
+			continue
+		}
+		cells := make(map[string]string, len(header))
+		for i, f := range fields {
+			if i < len(header) {
+				cells[header[i]] = f
+			}
+		}
+		rows[fields[0]] = cells
+	}
+	if header == nil {
+		t.Fatalf("no partition header in:\n%s", body)
+	}
+	return rows
+}
+
+func TestPartitionViewShowsTotals(t *testing.T) {
+	m := seededModel()
+	m.snapshot.Jobs = []slurm.Job{
+		{ID: "1", State: "PENDING", User: "alice", Partition: "gpu,cpu"},
+		{ID: "2", State: "RUNNING", User: "alice", Partition: "gpu"},
+	}
+	m.snapshot.Queue, m.snapshot.Users = slurm.SummarizeJobs(m.snapshot.Jobs)
+	rows := partitionCells(t, m.renderPartitionDetail(10, 120))
+
+	gpu, cpu, total := rows["gpu"], rows["cpu"], rows["TOTAL"]
+	if gpu["running"] != "1" || gpu["pending"] != "1" || gpu["total"] != "2" || gpu["multi"] != "1" {
+		t.Fatalf("unexpected gpu row %v", gpu)
+	}
+	if cpu["pending"] != "1" || cpu["total"] != "1" || cpu["multi"] != "1" {
+		t.Fatalf("unexpected cpu row %v", cpu)
+	}
+	// The job submitted to both partitions counts once in the TOTAL row.
+	if total["running"] != "1" || total["pending"] != "1" || total["total"] != "2" || total["multi"] != "1" {
+		t.Fatalf("expected TOTAL row from the queue summary, got %v", total)
+	}
+}
+
+func TestPartitionViewShowsCapacity(t *testing.T) {
+	m := seededModel()
+	m.snapshot.Queue.ByPartition = []slurm.PartitionCount{{Partition: "gpu", Running: 3, Pending: 1}}
+	m.snapshot.Partitions = []slurm.Partition{
+		{Name: "cpu", State: "UP", Default: true, TotalNodes: 4, TotalCPUs: 128, AllocCPUs: 32, IdleCPUs: 64, MaxTime: 48 * time.Hour},
+		{Name: "gpu", State: "UP", TotalNodes: 2, SharedNodes: 2, TotalCPUs: 128, AllocCPUs: 16, IdleCPUs: 112, TotalGPUs: 8, AllocGPUs: 2, MaxTime: 12 * time.Hour},
+	}
+	body := m.renderPartitionDetail(10, 120)
+	rows := partitionCells(t, body)
+
+	gpu := rows["gpu"]
+	if gpu["running"] != "3" || gpu["cpu"] != "16/128" || gpu["gpu"] != "2/8" || gpu["maxtime"] != "12h" || gpu["nodes"] != "2" || gpu["shared"] != "2" {
+		t.Fatalf("expected gpu capacity next to its job counts, got %v", gpu)
+	}
+	// cpu has no jobs but still lists its capacity, after partitions with jobs.
+	cpu := rows["cpu*"]
+	if cpu["cpu"] != "32/128" || cpu["maxtime"] != "2d" || cpu["total"] != "0" || strings.Index(body, "cpu*") < strings.Index(body, "gpu ") {
+		t.Fatalf("expected capacity-only default partition listed last, got %v", cpu)
+	}
+}
+
+func TestUserViewShowsLabelledResourceColumns(t *testing.T) {
+	m := seededModel()
+	body := m.renderUserDetail(10, 100)
+	for _, label := range []string{"rCJ", "pGJ", "heldCPU", "heldGPU", "pendMem"} {
+		if !strings.Contains(body, label) {
+			t.Fatalf("expected %q column in full-screen user view, got:\n%s", label, body)
+		}
+	}
+}
+
+func TestJobViewListsJobsAndReportsHidden(t *testing.T) {
+	m := seededModel()
+	m.snapshot.Jobs = sampleJobs()
+	body := m.renderJobDetail(4, 100)
+	if !strings.Contains(body, "job view (top 2/3, +1 hidden)") {
+		t.Fatalf("expected hidden job count in title, got:\n%s", body)
+	}
+	if !strings.Contains(body, "1001") || !strings.Contains(body, "gpu-a01") {
+		t.Fatalf("expected first job with its node list, got:\n%s", body)
+	}
+	if !strings.Contains(body, "Priority") {
+		t.Fatalf("expected pending job to show its reason, got:\n%s", body)
+	}
+}
+
+func TestJobViewShowsTaskRangeCount(t *testing.T) {
+	m := seededModel()
+	m.snapshot.Jobs = []slurm.Job{{ID: "77_[65537-90000]", ArrayJobID: "77", ArrayTaskID: "[65537-90000]", User: "alice", State: "PENDING", Partition: "gpu", Name: "sweep", CPUs: 1, Reason: "Priority", Tasks: 24464}}
+	if body := m.renderJobDetail(4, 120); !strings.Contains(body, "sweep (24464 tasks)  Priority") {
+		t.Fatalf("expected the range row to show its task count, got:\n%s", body)
+	}
+}
+
+func TestStaleSectionsAreLabelled(t *testing.T) {
+	m := seededModel()
+	m.snapshot.Jobs = sampleJobs()
+	m.snapshot.Sections = []slurm.SectionStatus{
+		{Section: slurm.SectionNodes, State: slurm.SectionFresh, UpdatedAt: m.snapshot.CollectedAt},
+		{Section: slurm.SectionQueue, State: slurm.SectionStale, UpdatedAt: m.snapshot.CollectedAt.Add(-90 * time.Second), Error: "timeout"},
+		{Section: slurm.SectionPartitions, State: slurm.SectionFailed, Error: "timeout"},
+	}
+	if body := m.renderJobDetail(6, 100); !strings.Contains(body, "stale 1m30s") {
+		t.Fatalf("expected stale label on the job view, got:\n%s", body)
+	}
+	if body := m.renderPartitionDetail(6, 120); !strings.Contains(body, "unavailable") {
+		t.Fatalf("expected failed label on the partition view, got:\n%s", body)
+	}
+	m.view = viewOverview
+	if view := m.View(); strings.Contains(view, "node summary stale") || !strings.Contains(view, "stale 1m30s") {
+		t.Fatalf("expected only the queue panels labelled in the overview, got:\n%s", view)
+	}
+}
+
+func TestFailedPendingLookupsAreLabelled(t *testing.T) {
+	m := seededModel()
+	m.width, m.height = 160, 40
+	if body := m.renderQueueDetail(20, 150); strings.Contains(body, "pending requests") {
+		t.Fatalf("did not expect a lookup label while lookups are fresh, got:\n%s", body)
+	}
+	m.snapshot.Sections = []slurm.SectionStatus{
+		{Section: slurm.SectionQueue, State: slurm.SectionFresh, UpdatedAt: m.snapshot.CollectedAt},
+		{Section: slurm.SectionPendingLookups, State: slurm.SectionFailed, Error: "look up pending jobs: timeout"},
+	}
+	if body := m.renderQueueDetail(20, 150); !strings.Contains(body, "pending requests unavailable") {
+		t.Fatalf("expected failed lookups labelled on the queue summary, got:\n%s", body)
+	}
+	m.snapshot.Sections[1] = slurm.SectionStatus{Section: slurm.SectionPendingLookups, State: slurm.SectionStale, UpdatedAt: m.snapshot.CollectedAt.Add(-time.Minute)}
+	m.view = viewOverview
+	if view := m.View(); !strings.Contains(view, "pending requests stale 1m0s") {
+		t.Fatalf("expected stale lookups labelled in the overview, got:\n%s", view)
+	}
+}
+
+func sampleJobs() []slurm.Job {
+	return []slurm.Job{
+		{ID: "1001", State: "RUNNING", User: "alice", Partition: "gpu", Name: "train", CPUs: 16, MemMB: 64000, GPUs: 4, NodeList: "gpu-a01"},
+		{ID: "1002", State: "PENDING", User: "bob", Partition: "cpu", Name: "prep", CPUs: 8, MemMB: 16000, Reason: "Priority"},
+		{ID: "1003", State: "PENDING", User: "carol", Partition: "gpu", Name: "eval", CPUs: 4, MemMB: 8000, GPUs: 1, Reason: "Resources"},
+	}
+}
+
+func TestQueueViewShowsBreakdownsInWideAndNarrowLayouts(t *testing.T) {
+	for _, width := range []int{72, 160} {
+		t.Run(fmt.Sprint(width), func(t *testing.T) {
+			m := seededModel()
+			m.view = viewQueue
+			m.width = width
+			m.height = 40
+			out := m.View()
+			assertViewportBounds(t, out, width, 40)
+			for _, want := range []string{"pending causes", "Priority", "Resources", "job states", "job names"} {
+				if !strings.Contains(out, want) {
+					t.Fatalf("expected %q in queue view at width %d, got:\n%s", want, width, out)
+				}
+			}
+		})
+	}
+}
+
+func TestQueueViewShowsGPUTypes(t *testing.T) {
+	m := seededModel()
+	m.snapshot.Nodes[0].GPUTypes = []slurm.GPUTypeUsage{{Type: "a100", Alloc: 2, Total: 4}}
+	m.snapshot.Queue.ByGPUType = []slurm.GPUTypeLoad{
+		{Type: "h100", PendingGPU: 8, PendingJobs: 2},
+		{Type: "a100", RunningGPU: 2, RunningJobs: 1},
+		{Type: "", PendingGPU: 1, PendingJobs: 1},
+	}
+	body := m.renderQueueDetail(40, 160)
+	if !strings.Contains(body, "gpu types") {
+		t.Fatalf("expected a gpu types section, got:\n%s", body)
+	}
+	var a100, h100, untyped bool
+	for _, line := range strings.Split(body, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+		switch strings.Join(fields[:5], " ") {
+		case "a100 2/4 2 0 0":
+			a100 = true
+		case "h100 - 0 8 2":
+			h100 = true
+		case "untyped - 0 1 1":
+			untyped = true
+		}
+	}
+	if !a100 || !h100 || !untyped {
+		t.Fatalf("expected capacity and demand per model (a100=%v h100=%v untyped=%v), got:\n%s", a100, h100, untyped, body)
+	}
+}
+
+func TestBreakdownShowsShareOfTotal(t *testing.T) {
+	m := seededModel()
+	lines := m.renderBreakdown(breakdownSection{
+		title:  "pending causes",
+		counts: []slurm.NameCount{{Name: "Priority", Count: 3}, {Name: "Resources", Count: 1}},
+		total:  4,
+	}, 5, 20)
+	body := strings.Join(lines, "\n")
+	if !strings.Contains(body, "75%") || !strings.Contains(body, "25%") {
+		t.Fatalf("expected share of pending per cause, got:\n%s", body)
+	}
+}
@@ -28,3 +28,42 @@ func MemMB(v int) string {
 func MemPair(allocMB, totalMB int) string {
 	return fmt.Sprintf("%s/%s", MemMB(allocMB), MemMB(totalMB))
 }
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws values as block characters scaled between lo and hi. When
+// there are more values than width they are averaged into width buckets, so
+// the newest value is always the rightmost cell. If hi <= lo, hi becomes the
+// largest value.
+func Sparkline(values []float64, lo, hi float64, width int) string {
+	if width <= 0 || len(values) == 0 {
+		return ""
+	}
+	if len(values) > width {
+		buckets := make([]float64, width)
+		for i := range buckets {
+			from := i * len(values) / width
+			to := (i + 1) * len(values) / width
+			var sum float64
+			for _, v := range values[from:to] {
+				sum += v
+			}
+			buckets[i] = sum / float64(to-from)
+		}
+		values = buckets
+	}
+	if hi <= lo {
+		for _, v := range values {
+			hi = max(hi, v)
+		}
+	}
+	out := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if hi > lo {
+			level = int((v - lo) / (hi - lo) * float64(len(sparkBlocks)-1))
+		}
+		out[i] = sparkBlocks[min(len(sparkBlocks)-1, max(0, level))]
+	}
+	return string(out)
+}
@@ -0,0 +1,15 @@
+package uifmt
+
+import "testing"
+
+func TestSparklineScalesAndBuckets(t *testing.T) {
+	if got := Sparkline([]float64{0, 50, 100}, 0, 100, 10); got != "▁▄█" {
+		t.Fatalf("unexpected sparkline %q", got)
+	}
+	if got := Sparkline([]float64{0, 0, 10, 10}, 0, 0, 2); got != "▁█" {
+		t.Fatalf("expected buckets averaged to width and auto-scaled, got %q", got)
+	}
+	if got := Sparkline(nil, 0, 100, 10); got != "" {
+		t.Fatalf("expected empty sparkline for no data, got %q", got)
+	}
+}